@@ -0,0 +1,188 @@
+package main
+
+import (
+	"SimpleToDo/config"
+	"SimpleToDo/db"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/jwtkeys"
+	"SimpleToDo/util/secretbox"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const commandsUsage = `Usage:
+  simpletodo                    start the server
+  simpletodo jwt-keys rotate    generate a new JWT signing key and retire the current one
+  simpletodo jwt-keys list      list the JWT signing keys in the keyring
+  simpletodo secrets rotate     generate a new master key and re-encrypt the stored API keys with it
+  simpletodo secrets reencrypt  re-encrypt the stored API keys with the current master key
+  simpletodo secrets list       list the master keys that encrypt stored API keys`
+
+// runCommand executes a maintenance subcommand and returns the process exit code.
+func runCommand(args []string) int {
+	if len(args) == 2 && args[0] == "jwt-keys" {
+		switch args[1] {
+		case "rotate":
+			return rotateJWTKey()
+		case "list":
+			return listJWTKeys()
+		}
+	}
+	if len(args) == 2 && args[0] == "secrets" {
+		switch args[1] {
+		case "rotate":
+			return rotateSecretsKey()
+		case "reencrypt":
+			return reencryptSecrets()
+		case "list":
+			return listSecretsKeys()
+		}
+	}
+	fmt.Println(commandsUsage)
+	return 2
+}
+
+func rotateJWTKey() int {
+	dir, err := config.JWTKeysDir()
+	if err != nil {
+		fmt.Println("❌ Error resolving keys directory:", err)
+		return 1
+	}
+	ring, err := jwtkeys.Load(dir)
+	if err != nil {
+		fmt.Println("❌ Error loading JWT keys:", err)
+		return 1
+	}
+	kid, err := ring.Rotate(config.GetAppEnv().JWTAlgorithm)
+	if err != nil {
+		fmt.Println("❌ Error rotating JWT key:", err)
+		return 1
+	}
+	fmt.Printf("🔑 New JWT signing key %s (%s) stored in %s\n", kid, config.GetAppEnv().JWTAlgorithm, dir)
+	fmt.Printf("   Previous keys stay valid for verification for %s.\n", jwtkeys.TokenTTL)
+	return 0
+}
+
+func listJWTKeys() int {
+	dir, err := config.JWTKeysDir()
+	if err != nil {
+		fmt.Println("❌ Error resolving keys directory:", err)
+		return 1
+	}
+	ring, err := jwtkeys.Load(dir)
+	if err != nil {
+		fmt.Println("❌ Error loading JWT keys:", err)
+		return 1
+	}
+	keys := ring.Keys()
+	if len(keys) == 0 {
+		fmt.Println("No JWT signing keys in", dir)
+		return 0
+	}
+	for _, k := range keys {
+		status := "active"
+		if k.RetiredAt != nil {
+			status = "retired, verifiable until " + k.RetiredAt.Add(jwtkeys.TokenTTL).Format(time.RFC3339)
+		}
+		fmt.Printf("%s  %-6s  created %s  %s\n", k.Kid, k.Alg, k.CreatedAt.Format(time.RFC3339), status)
+	}
+	return 0
+}
+
+// initSecretsKeyring loads the master keys for stored secrets, generating one on first use.
+func initSecretsKeyring() int {
+	dir, err := config.SecretsKeysDir()
+	if err != nil {
+		fmt.Println("❌ Error resolving keys directory:", err)
+		return 1
+	}
+	env := config.GetAppEnv()
+	if err := secretbox.Init(dir, env.SecretsMasterKey, env.SecretsPreviousMasterKeys); err != nil {
+		fmt.Println("❌ Invalid secrets master key:", err)
+		return 1
+	}
+	return 0
+}
+
+func rotateSecretsKey() int {
+	if code := initSecretsKeyring(); code != 0 {
+		return code
+	}
+	kid, err := secretbox.Default().Rotate()
+	if errors.Is(err, secretbox.ErrEnvMasterKey) {
+		fmt.Println("❌", err)
+		fmt.Println("   Then run `simpletodo secrets reencrypt`.")
+		return 1
+	}
+	if err != nil {
+		fmt.Println("❌ Error rotating master key:", err)
+		return 1
+	}
+	fmt.Printf("🔑 New master key %s stored in %s\n", kid, secretbox.Default().Dir())
+	return reencryptStoredSecrets()
+}
+
+func reencryptSecrets() int {
+	if code := initSecretsKeyring(); code != 0 {
+		return code
+	}
+	return reencryptStoredSecrets()
+}
+
+// reencryptStoredSecrets seals every stored secret with the active master key and then drops the
+// stored keys nothing is encrypted with anymore.
+func reencryptStoredSecrets() int {
+	errDb, DB := db.InitDB()
+	if errDb != nil {
+		fmt.Println("❌ Error initializing database:", errDb)
+		return 1
+	}
+	n, err := repository.NewAIServerRepository(DB).ReEncryptAll()
+	if err != nil {
+		fmt.Println("❌ Error re-encrypting API keys:", err)
+		fmt.Println("   The previous master keys were kept, no data was lost.")
+		return 1
+	}
+	fmt.Printf("🔒 Re-encrypted %d API key(s)\n", n)
+
+	removed, err := secretbox.Default().Prune()
+	if err != nil {
+		fmt.Println("❌ Error removing old master keys:", err)
+		return 1
+	}
+	if removed > 0 {
+		fmt.Printf("   Removed %d old master key(s).\n", removed)
+	}
+	if len(config.GetAppEnv().SecretsPreviousMasterKeys) > 0 {
+		fmt.Println("   SECRETS_PREVIOUS_MASTER_KEYS is no longer needed and can be removed.")
+	}
+	return 0
+}
+
+func listSecretsKeys() int {
+	if code := initSecretsKeyring(); code != 0 {
+		return code
+	}
+	active, err := secretbox.Default().ActiveKid()
+	if err != nil {
+		fmt.Println("❌ Error loading master keys:", err)
+		return 1
+	}
+	for _, k := range secretbox.Default().Keys() {
+		source := "keyring"
+		if k.FromEnv {
+			source = "environment"
+		}
+		status := "decrypt only"
+		if k.Kid == active {
+			status = "active"
+		}
+		created := ""
+		if !k.CreatedAt.IsZero() {
+			created = "created " + k.CreatedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%s  %-11s  %-12s  %s\n", k.Kid, source, status, created)
+	}
+	return 0
+}
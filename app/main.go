@@ -6,14 +6,18 @@ import (
 	"SimpleToDo/db"
 	"SimpleToDo/docs"
 	_ "SimpleToDo/docs"
+	"SimpleToDo/repository"
 	"SimpleToDo/router"
+	"SimpleToDo/service"
 	"SimpleToDo/util"
+	"SimpleToDo/util/jwtkeys"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
 	_ "github.com/swaggo/echo-swagger"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -21,8 +25,23 @@ import (
 	"runtime"
 	"strings"
 	"time"
+	// timezone preferences must resolve on hosts without a zoneinfo database
+	_ "time/tzdata"
 )
 
+// ipExtractor trusts X-Forwarded-For only when the request comes through one of the trusted proxies, so
+// that clients cannot choose the IP that rate limits, lockouts and the audit log see.
+func ipExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, ipRange := range trustedProxies {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
 func applyMiddlewares(e *echo.Echo, showLogs *bool, corsOrigins *[]string, debug *bool) {
 
 	if !*showLogs {
@@ -61,8 +80,9 @@ func applyMiddlewares(e *echo.Echo, showLogs *bool, corsOrigins *[]string, debug
 	e.Use(middleware.StaticWithConfig(middleware.StaticConfig{
 		Skipper: func(c echo.Context) bool {
 			path := c.Request().URL.Path
-			// Jump over API and Swagger paths to avoid serving static files for them.
-			return strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/swagger")
+			// Jump over API, Swagger and well-known paths to avoid serving static files for them.
+			return strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/swagger") ||
+				strings.HasPrefix(path, "/.well-known")
 		},
 		// Root directory from where the static content is served.
 		Root: "/",
@@ -119,6 +139,8 @@ func openBrowser(url string) {
 // @description Provide your JWT as: Bearer <token>
 func main() {
 
+	firstRun := !config.EnvFileExists()
+
 	if err := config.EnsureEnvInteractive(); err != nil {
 		fmt.Println("❌ Invalid config:", err)
 		os.Exit(1)
@@ -129,12 +151,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	if firstRun {
+		if code := rotateJWTKey(); code != 0 {
+			os.Exit(code)
+		}
+	}
+
+	keysDir, err := config.JWTKeysDir()
+	if err != nil {
+		fmt.Println("❌ Error resolving keys directory:", err)
+		os.Exit(1)
+	}
+	if err := jwtkeys.Init(keysDir); err != nil {
+		fmt.Println("❌ Invalid JWT keys:", err)
+		fmt.Println("   Run `simpletodo jwt-keys rotate` to generate a signing key.")
+		os.Exit(1)
+	}
+
+	if code := initSecretsKeyring(); code != 0 {
+		os.Exit(code)
+	}
+
 	e := echo.New()
 
 	e.HideBanner = true
 	util.PrintBanner()
 
 	env := config.GetAppEnv()
+	e.IPExtractor = ipExtractor(env.TrustedProxies)
 	applyMiddlewares(e, &env.ShowLogs, &env.CorsOrigin, &env.Debug)
 
 	if u, err := url.Parse(env.BaseURL); err == nil && u.Scheme != "" && u.Host != "" {
@@ -151,9 +199,28 @@ func main() {
 		return
 	}
 
+	// Encrypt API keys stored in plaintext or under a previous master key
+	if n, err := repository.NewAIServerRepository(DB).ReEncryptAll(); err != nil {
+		fmt.Println("❌ Error encrypting stored API keys:", err)
+		os.Exit(1)
+	} else if n > 0 {
+		fmt.Printf("🔒 Encrypted %d stored API key(s) with the current master key\n", n)
+	}
+
+	// Convert avatars stored before uploads were resized
+	if n, err := service.NewAvatarService(repository.NewAvatarRepository(DB)).MigrateLegacyImages(); err != nil {
+		fmt.Println("❌ Error converting stored avatars:", err)
+		os.Exit(1)
+	} else if n > 0 {
+		fmt.Printf("🖼️  Converted %d stored avatar(s)\n", n)
+	}
+
 	// Initialize routes
 	router.InitRouters(e, DB)
 
+	// Start periodic maintenance jobs
+	router.StartBackgroundJobs(DB)
+
 	// Serve Swagger UI at /swagger/index.html
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
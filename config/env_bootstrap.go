@@ -2,8 +2,6 @@ package config
 
 import (
 	"bufio"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/joho/godotenv"
@@ -40,7 +38,7 @@ const (
 )
 
 type AppEnv struct {
-	JWTSecret     string
+	JWTAlgorithm  string
 	Scheme        string
 	Host          string
 	Port          int
@@ -68,12 +66,38 @@ type AppEnv struct {
 	DbSSL         bool
 	Timezone      string
 	Debug         bool
+	Password      PasswordPolicy
+	// AccountDeletionGrace is how long a self-deleted account can still be restored before it is purged.
+	AccountDeletionGrace time.Duration
+	// SecretsMasterKey, when set, replaces the generated master key that encrypts stored AI API keys.
+	// SecretsPreviousMasterKeys still decrypt values written before it was changed.
+	SecretsMasterKey          string
+	SecretsPreviousMasterKeys []string
+	// AIJobWorkers is how many background AI jobs run at once, AIJobUserConcurrency how many of them
+	// may belong to the same user. Finished jobs are kept for AIJobRetention.
+	AIJobWorkers         int
+	AIJobUserConcurrency int
+	AIJobRetention       time.Duration
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header names the client. Without any,
+	// the client is the address of the connection.
+	TrustedProxies []*net.IPNet
+}
+
+// PasswordPolicy holds the rules enforced whenever a user chooses a password. They are tuned through
+// the optional PASSWORD_* variables and BREACHED_PASSWORDS_FILE.
+type PasswordPolicy struct {
+	MinLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	History          int
+	BreachedListPath string
 }
 
 var (
 	reHostName = regexp.MustCompile(`^[a-zA-Z0-9.-]+$`)
 	reUser     = regexp.MustCompile(`^.{1,}$`)
-	reJWT      = regexp.MustCompile(`^.{16,}$`)
 	rePhone    = regexp.MustCompile(`^[0-9+\-().\s]{6,}$`)
 	reUsername = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,}$`)
 )
@@ -180,9 +204,10 @@ func validateNonEmpty(v string) error {
 	return nil
 }
 
-func validateJWT(v string) error {
-	if !reJWT.MatchString(v) {
-		return errors.New("JWT_SECRET must be at least 16 characters")
+func validateJWTAlg(v string) error {
+	x := strings.TrimSpace(v)
+	if x != "EdDSA" && x != "RS256" {
+		return errors.New("JWT_ALG must be EdDSA or RS256")
 	}
 	return nil
 }
@@ -258,6 +283,31 @@ func splitCSV(s string) []string {
 	return out
 }
 
+// parseIPRanges parses a comma separated list of IP addresses and CIDR ranges.
+func parseIPRanges(s string) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, p := range splitCSV(s) {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR range", p)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", p)
+		}
+		ranges = append(ranges, ipNet)
+	}
+	return ranges, nil
+}
+
 func normalizeCSV(s string) string {
 	return strings.Join(splitCSV(s), ",")
 }
@@ -269,14 +319,6 @@ func fallback(s, def string) string {
 	return s
 }
 
-func mustGenerateJWTSecret() string {
-	buf := make([]byte, 32)
-	if _, err := rand.Read(buf); err != nil {
-		return "ChangeMePlease_UseAStrongSecret123!"
-	}
-	return base64.RawURLEncoding.EncodeToString(buf)
-}
-
 func atoiDefault(s string, def int) (int, error) {
 	if strings.TrimSpace(s) == "" {
 		return def, nil
@@ -301,6 +343,29 @@ func AppDir() (string, error) {
 	return filepath.Join(home, "SimpleToDo"), nil
 }
 
+func JWTKeysDir() (string, error) {
+	dir, err := AppDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keys", "jwt"), nil
+}
+
+// SecretsKeysDir is where the generated master keys for encrypting stored secrets are kept.
+func SecretsKeysDir() (string, error) {
+	dir, err := AppDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keys", "secrets"), nil
+}
+
+// EnvFileExists reports whether the .env file has already been created in the app directory.
+func EnvFileExists() bool {
+	p, err := envPath()
+	return err == nil && fileExists(p)
+}
+
 func GetDbClient() (string, error) {
 	env := GetAppEnv()
 	if env.DbClient != "" {
@@ -319,12 +384,7 @@ func EnsureEnvInteractive() error {
 	}
 
 	fields := []envField{
-		{Key: "JWT_SECRET", Prompt: "JWT Secret (>=16 chars)", Default: "", Secret: true, Validate: func(s string) error {
-			if strings.TrimSpace(s) == "" {
-				return nil
-			}
-			return validateJWT(s)
-		}},
+		{Key: "JWT_ALG", Prompt: "JWT signing algorithm (EdDSA|RS256)", Default: "EdDSA", Validate: validateJWTAlg},
 		{Key: "SCHEME", Prompt: "Scheme (http|https)", Default: "http", Validate: validateScheme, Normalize: strings.ToLower},
 		{Key: "HOST", Prompt: "Host (e.g., localhost or 127.0.0.1)", Default: "localhost", Validate: validateHost},
 		{Key: "PORT", Prompt: "Port", Default: "8000", Validate: validatePort},
@@ -420,13 +480,9 @@ func EnsureEnvInteractive() error {
 			continue
 		}
 
-		// 2) If not interactive (e.g., container), use defaults (and generate JWT if empty)
+		// 2) If not interactive (e.g., container), use defaults
 		if !interactive {
 			candidate := f.Default
-			if f.Key == "JWT_SECRET" && candidate == "" {
-				candidate = mustGenerateJWTSecret()
-				fmt.Println("(generated secure JWT secret)")
-			}
 			if f.Normalize != nil {
 				candidate = f.Normalize(candidate)
 			}
@@ -443,9 +499,7 @@ func EnsureEnvInteractive() error {
 		reader := bufio.NewReader(os.Stdin)
 		for {
 			defNote := ""
-			if f.Secret && f.Key == "JWT_SECRET" && f.Default == "" {
-				defNote = "[default: auto-generate secure key]"
-			} else if f.Default != "" {
+			if f.Default != "" {
 				defNote = fmt.Sprintf("[default: %s]", f.Default)
 			} else if f.Optional {
 				defNote = "[optional, can be empty]"
@@ -455,12 +509,7 @@ func EnsureEnvInteractive() error {
 			line, _ := reader.ReadString('\n')
 			input := strings.TrimSpace(line)
 			if input == "" {
-				if f.Key == "JWT_SECRET" && f.Default == "" {
-					input = mustGenerateJWTSecret()
-					fmt.Println("(generated secure JWT secret)")
-				} else {
-					input = f.Default
-				}
+				input = f.Default
 			}
 			if f.Normalize != nil {
 				input = f.Normalize(input)
@@ -532,15 +581,43 @@ func LoadEnvFromAppDir() error {
 		base = fmt.Sprintf("%s://%s:%d", scheme, host, port)
 	}
 
-	jwt := os.Getenv("JWT_SECRET")
-	if strings.TrimSpace(jwt) == "" {
-		jwt = mustGenerateJWTSecret()
-	}
-
 	dbPort, err := atoiDefault(os.Getenv("DB_PORT"), 5432)
 
+	passwordMinLength, err := atoiDefault(os.Getenv("PASSWORD_MIN_LENGTH"), 8)
+	if err != nil || passwordMinLength < 1 || passwordMinLength > 72 {
+		return errors.New("invalid PASSWORD_MIN_LENGTH: must be in 1-72")
+	}
+	passwordHistory, err := atoiDefault(os.Getenv("PASSWORD_HISTORY"), 5)
+	if err != nil || passwordHistory < 0 {
+		return errors.New("invalid PASSWORD_HISTORY: must be 0 or greater")
+	}
+	deletionGraceDays, err := atoiDefault(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"), 14)
+	if err != nil || deletionGraceDays < 0 {
+		return errors.New("invalid ACCOUNT_DELETION_GRACE_DAYS: must be 0 or greater")
+	}
+	aiJobWorkers, err := atoiDefault(os.Getenv("AI_JOB_WORKERS"), 4)
+	if err != nil || aiJobWorkers < 1 {
+		return errors.New("invalid AI_JOB_WORKERS: must be 1 or greater")
+	}
+	aiJobUserConcurrency, err := atoiDefault(os.Getenv("AI_JOB_USER_CONCURRENCY"), 1)
+	if err != nil || aiJobUserConcurrency < 1 {
+		return errors.New("invalid AI_JOB_USER_CONCURRENCY: must be 1 or greater")
+	}
+	aiJobRetentionDays, err := atoiDefault(os.Getenv("AI_JOB_RETENTION_DAYS"), 7)
+	if err != nil || aiJobRetentionDays < 1 {
+		return errors.New("invalid AI_JOB_RETENTION_DAYS: must be 1 or greater")
+	}
+	trustedProxies, err := parseIPRanges(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	breachedList := strings.TrimSpace(os.Getenv("BREACHED_PASSWORDS_FILE"))
+	if breachedList != "" && !fileExists(breachedList) {
+		return fmt.Errorf("invalid BREACHED_PASSWORDS_FILE: %s does not exist", breachedList)
+	}
+
 	Env = AppEnv{
-		JWTSecret:     jwt,
+		JWTAlgorithm:  fallback(os.Getenv("JWT_ALG"), "EdDSA"),
 		Scheme:        scheme,
 		Host:          host,
 		Port:          port,
@@ -568,6 +645,22 @@ func LoadEnvFromAppDir() error {
 		DbSSL:         parseBoolWithDefault(os.Getenv("DB_SSL"), false),
 		Timezone:      fallback(os.Getenv("TIMEZONE"), "UTC"),
 		Debug:         fallback(os.Getenv("DEBUG"), "false") == "true",
+		Password: PasswordPolicy{
+			MinLength:        passwordMinLength,
+			RequireUpper:     parseBoolWithDefault(os.Getenv("PASSWORD_REQUIRE_UPPER"), true),
+			RequireLower:     parseBoolWithDefault(os.Getenv("PASSWORD_REQUIRE_LOWER"), true),
+			RequireDigit:     parseBoolWithDefault(os.Getenv("PASSWORD_REQUIRE_DIGIT"), true),
+			RequireSymbol:    parseBoolWithDefault(os.Getenv("PASSWORD_REQUIRE_SYMBOL"), false),
+			History:          passwordHistory,
+			BreachedListPath: breachedList,
+		},
+		AccountDeletionGrace:      time.Duration(deletionGraceDays) * 24 * time.Hour,
+		SecretsMasterKey:          strings.TrimSpace(os.Getenv("SECRETS_MASTER_KEY")),
+		SecretsPreviousMasterKeys: splitCSV(os.Getenv("SECRETS_PREVIOUS_MASTER_KEYS")),
+		AIJobWorkers:              aiJobWorkers,
+		AIJobUserConcurrency:      aiJobUserConcurrency,
+		AIJobRetention:            time.Duration(aiJobRetentionDays) * 24 * time.Hour,
+		TrustedProxies:            trustedProxies,
 	}
 	return nil
 }
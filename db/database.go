@@ -61,20 +61,56 @@ func InitDB() (error, *gorm.DB) {
 	}
 
 	if err = DB.AutoMigrate(
+		&models.Permission{},
 		&models.Role{},
 		&models.Status{},
 		&models.User{},
 		&models.Project{},
 		&models.Task{},
+		&models.ProjectMember{},
+		&models.ProjectSummary{},
 		&models.PasswordResetToken{},
 		&models.EmailVerificationToken{},
+		&models.MagicLinkToken{},
+		&models.EmailChangeToken{},
+		&models.PasswordHistory{},
+		&models.Setting{},
+		&models.Invitation{},
+		&models.ImpersonationSession{},
+		&models.SecurityEvent{},
+		&models.UserAvatar{},
+		&models.UserPreferences{},
 		&models.Prompt{},
 		&models.AIServerSettings{},
+		&models.Job{},
+		&models.AIUsage{},
+		&models.AIQuota{},
+		&models.AIModelPrice{},
+		&models.AuthThrottle{},
 	); err != nil {
 		return err, nil
 	}
 
+	if err = migrateImpersonationAdmins(DB); err != nil {
+		return err, nil
+	}
+
 	Seed(DB)
 
 	return nil, DB
 }
+
+// migrateImpersonationAdmins drops the foreign key impersonation sessions used to have on their admin and
+// copies the admin's email into them, so that purging an admin's account keeps the sessions they ran.
+func migrateImpersonationAdmins(db *gorm.DB) error {
+	const constraint = "fk_impersonation_sessions_admin"
+	if !db.Migrator().HasConstraint(&models.ImpersonationSession{}, constraint) {
+		return nil
+	}
+	if err := db.Exec("UPDATE impersonation_sessions SET admin_email = " +
+		"(SELECT email FROM users WHERE users.id = impersonation_sessions.admin_id) " +
+		"WHERE admin_email = ''").Error; err != nil {
+		return err
+	}
+	return db.Migrator().DropConstraint(&models.ImpersonationSession{}, constraint)
+}
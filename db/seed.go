@@ -35,16 +35,46 @@ func Seed(db *gorm.DB) {
 			}
 		}
 
+		permissions := []models.Permission{
+			{ID: 1, Name: models.PermissionUsersManage, Description: "Manage user accounts"},
+			{ID: 2, Name: models.PermissionRolesManage, Description: "Manage roles and their permissions"},
+			{ID: 3, Name: models.PermissionPromptsManage, Description: "Manage AI system prompts"},
+			{ID: 4, Name: models.PermissionProjectsReadAll, Description: "Read projects of every user"},
+			{ID: 5, Name: models.PermissionSettingsManage, Description: "Manage application settings such as registration"},
+			{ID: 6, Name: models.PermissionAuditRead, Description: "Read the security audit log"},
+			{ID: 7, Name: models.PermissionAIUsageManage, Description: "Read the AI usage of every user and set AI quotas and prices"},
+		}
+		for _, p := range permissions {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&p).Error; err != nil {
+				return err
+			}
+		}
+
 		roles := []models.Role{
 			{ID: 1, Name: "Admin", Value: "admin"},
 			{ID: 2, Name: "USER", Value: "user"},
 		}
 		for _, r := range roles {
-			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&r).Error; err != nil {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Permissions").Create(&r).Error; err != nil {
 				return err
 			}
 		}
 
+		// The admin role always holds every permission, including the ones added in later versions.
+		if err := tx.Model(&models.Role{ID: 1}).Association("Permissions").Append(permissions); err != nil {
+			return err
+		}
+
+		// Seeded rows use explicit IDs, so PostgreSQL sequences must be moved past them
+		// before roles can be created through the API.
+		if tx.Dialector.Name() == "postgres" {
+			for _, table := range []string{"roles", "permissions"} {
+				if err := tx.Exec("SELECT setval(pg_get_serial_sequence(?, 'id'), (SELECT MAX(id) FROM "+table+"))", table).Error; err != nil {
+					return err
+				}
+			}
+		}
+
 		imageBytes, err := embedfs.ImageFS.ReadFile("config/static/root_image.png")
 		if err != nil {
 			return err
@@ -1,7 +1,14 @@
 package request
 
 type UpdateAISettingsRequest struct {
-	BaseUrl string `json:"baseUrl" validate:"required,url"`
-	APIKey  string `json:"apiKey" validate:"required"`
-	Model   string `json:"model" validate:"required"`
+	// Provider defaults to openai, which also covers servers compatible with the OpenAI API
+	Provider string `json:"provider" validate:"omitempty,oneof=openai anthropic ollama azure" example:"openai"`
+	// BaseUrl may be left empty to use the provider's public endpoint; Azure needs the resource endpoint
+	BaseUrl string `json:"baseUrl" validate:"omitempty,url" example:"https://api.openai.com/v1"`
+	// APIKey may be left empty, or set to the masked key, to keep the stored one. Ollama needs none
+	APIKey string `json:"apiKey"`
+	// Model is the model name or, for Azure, the deployment name
+	Model string `json:"model" validate:"required" example:"gpt-4o"`
+	// APIVersion is the Azure OpenAI API version, e.g. 2024-10-21
+	APIVersion string `json:"apiVersion" validate:"omitempty,max=20" example:"2024-10-21"`
 }
@@ -0,0 +1,22 @@
+package request
+
+type AIUsageFilter struct {
+	// From and To are UTC days, both included; the default is the last 30 days.
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02" example:"2025-01-01"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02" example:"2025-01-31"`
+	// UserId narrows the admin report to one user.
+	UserId uint `query:"userId" example:"2"`
+}
+
+type AIQuotaRequestDto struct {
+	// MonthlyTokens caps the input and output tokens of a calendar month; 0 means no limit.
+	MonthlyTokens int64 `json:"monthlyTokens" validate:"min=0" example:"1000000"`
+	// MonthlyRequests caps the AI calls of a calendar month; 0 means no limit.
+	MonthlyRequests int64 `json:"monthlyRequests" validate:"min=0" example:"500"`
+}
+
+type AIModelPriceRequestDto struct {
+	// InputPerMillion and OutputPerMillion are the price of a million tokens.
+	InputPerMillion  float64 `json:"inputPerMillion" validate:"min=0" example:"2.5"`
+	OutputPerMillion float64 `json:"outputPerMillion" validate:"min=0" example:"10"`
+}
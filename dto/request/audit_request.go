@@ -0,0 +1,11 @@
+package request
+
+type SecurityEventFilter struct {
+	Type     string `query:"type" validate:"omitempty,max=64" example:"auth.login"`
+	Outcome  string `query:"outcome" validate:"omitempty,oneof=success failure" example:"failure"`
+	ActorId  uint   `query:"actorId" example:"1"`
+	TargetId uint   `query:"targetId" example:"2"`
+	IP       string `query:"ip" validate:"omitempty,ip" example:"203.0.113.7"`
+	From     string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To       string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-12-31T23:59:59Z"`
+}
@@ -5,7 +5,7 @@ import "time"
 type RegisterRequest struct {
 	Username  string    `json:"username" validate:"required,min=2,max=100" example:"jdoe"`
 	Email     string    `json:"email" validate:"required,email" example:"jdoe@example.com"`
-	Password  string    `json:"password" validate:"required,min=6,max=50" example:"P@ssw0rd!"`
+	Password  string    `json:"password" validate:"required,max=72" example:"P@ssw0rd!"`
 	Phone     string    `json:"phone" validate:"min=4,max=15" example:"+34123456789"`
 	FirstName string    `json:"firstName" validate:"required,min=2,max=100" example:"John"`
 	LastName  string    `json:"lastName" validate:"required,min=2,max=100" example:"Doe"`
@@ -13,6 +13,8 @@ type RegisterRequest struct {
 	Gender    string    `json:"gender,omitempty" validate:"required,oneof=male female" example:"male"`
 	BirthDate time.Time `json:"birthDate" example:"1995-01-15T00:00:00Z"`
 	Address   string    `json:"address" example:"First Avenue 5, Madrid"`
+	// InviteToken is required when registration is invitation-only and lets invited users bypass the other modes.
+	InviteToken string `json:"inviteToken,omitempty" validate:"omitempty,min=64" example:"6a1fbd97e8...<rest_of_token>"`
 }
 
 type LoginRequest struct {
@@ -26,5 +28,9 @@ type ForgotPasswordRequest struct {
 
 type ResetPasswordRequest struct {
 	Token       string `json:"token" validate:"required,min=64" example:"6a1fbd97e8...<rest_of_token>"`
-	NewPassword string `json:"newPassword" validate:"required,min=6,max=50" example:"NewP@ss123"`
+	NewPassword string `json:"newPassword" validate:"required,max=72" example:"NewP@ss123"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" validate:"required,min=64" example:"6a1fbd97e8...<rest_of_token>"`
 }
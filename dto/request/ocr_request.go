@@ -2,4 +2,11 @@ package request
 
 type AnalyzeImageRequest struct {
 	ImageBase64 string `json:"imageBase64" validate:"required"`
+	// PromptId picks the prompt to analyze the image with; without it the user's default prompt is used,
+	// then the default prompt.
+	PromptId *uint `json:"promptId" validate:"omitempty,min=1" example:"1"`
+	// Preview returns the extracted tasks without saving them; create them afterwards with POST /tasks/batch/{projectId}.
+	Preview bool `json:"preview" example:"false"`
+	// Async queues the analysis as a job and answers 202 at once; follow it with GET /jobs/{id}.
+	Async bool `json:"async" example:"false"`
 }
@@ -0,0 +1,14 @@
+package request
+
+// UpdatePreferencesRequest changes the given preferences; fields left empty keep their value.
+type UpdatePreferencesRequest struct {
+	Timezone   string `json:"timezone" validate:"omitempty,timezone" example:"Europe/Madrid"`
+	Locale     string `json:"locale" validate:"omitempty,bcp47_language_tag" example:"en"`
+	WeekStart  string `json:"weekStart" validate:"omitempty,oneof=monday sunday saturday" example:"monday"`
+	DateFormat string `json:"dateFormat" validate:"omitempty,oneof=YYYY-MM-DD DD/MM/YYYY MM/DD/YYYY DD.MM.YYYY" example:"DD/MM/YYYY"`
+	// DefaultProjectId of 0 clears the default project.
+	DefaultProjectId *int   `json:"defaultProjectId" validate:"omitempty,min=0" example:"1"`
+	DefaultTaskSort  string `json:"defaultTaskSort" validate:"omitempty,oneof=oldest newest due_date title" example:"due_date"`
+	// DefaultPromptId of 0 clears the default vision prompt.
+	DefaultPromptId *int `json:"defaultPromptId" validate:"omitempty,min=0" example:"1"`
+}
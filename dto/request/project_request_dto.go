@@ -9,3 +9,11 @@ type UpdateProjectRequestDto struct {
 	Name        string `json:"name" validate:"required,min=5,max=100" example:"Project Alpha"`
 	Description string `json:"description" validate:"required,min=20,max=300" example:"This project aims to develop a new software solution for managing tasks efficiently."`
 }
+
+// ProjectSummaryRequestDto asks for an AI status report of a project.
+type ProjectSummaryRequestDto struct {
+	// Email sends the report to the requesting user.
+	Email bool `json:"email" example:"true"`
+	// UseCache returns the last report instead of generating one, unless the tasks changed since.
+	UseCache bool `json:"useCache" example:"false"`
+}
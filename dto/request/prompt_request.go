@@ -4,10 +4,13 @@ type CreatePromptRequest struct {
 	Title        string `json:"title" validate:"required,max=150"`
 	Description  string `json:"description"`
 	SystemPrompt string `json:"systemPrompt" validate:"required"`
+	// Purpose is vision, the default, or breakdown.
+	Purpose string `json:"purpose" validate:"omitempty,oneof=vision breakdown"`
 }
 
 type UpdatePromptRequest struct {
 	Title        string `json:"title" validate:"max=150"`
 	Description  string `json:"description"`
 	SystemPrompt string `json:"systemPrompt"`
+	Purpose      string `json:"purpose" validate:"omitempty,oneof=vision breakdown"`
 }
@@ -0,0 +1,11 @@
+package request
+
+type UpdateRegistrationSettingsRequest struct {
+	Mode           string   `json:"mode" validate:"required,oneof=open closed domains invite" example:"domains"`
+	AllowedDomains []string `json:"allowedDomains" validate:"omitempty,dive,fqdn" example:"example.com"`
+}
+
+type CreateInvitationRequest struct {
+	Email     string `json:"email" validate:"required,email" example:"new.member@example.com"`
+	ProjectId *uint  `json:"projectId,omitempty" validate:"omitempty,min=1" example:"1"`
+}
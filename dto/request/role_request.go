@@ -0,0 +1,11 @@
+package request
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50" example:"Prompt Editor"`
+	Value       string   `json:"value" validate:"required,min=2,max=50" example:"prompt_editor"`
+	Permissions []string `json:"permissions" example:"prompts:manage"`
+}
+
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required" example:"prompts:manage"`
+}
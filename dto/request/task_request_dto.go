@@ -10,10 +10,34 @@ type TaskRequestDto struct {
 type CreateTaskRequestDto struct {
 	Title       string `json:"title" validate:"required,min=5,max=100" example:"Task 1"`
 	Description string `json:"description" validate:"required,min=10,max=300" example:"This is the first task in the project."`
+	DueDate     string `json:"dueDate" validate:"omitempty,datetime=2006-01-02" example:"2025-01-31"`
+	Priority    string `json:"priority" validate:"omitempty,oneof=low medium high" example:"high"`
 }
 
 type UpdateTaskRequestDto struct {
 	Title       string `json:"title" validate:"required,min=5,max=100" example:"Task 1"`
 	Description string `json:"description" validate:"required,min=10,max=300" example:"This is the first task in the project."`
 	Status      string `json:"status" validate:"required,oneof=pending ongoing completed blocked cancelled" example:"pending"`
+	// DueDate left empty removes the due date.
+	DueDate string `json:"dueDate" validate:"omitempty,datetime=2006-01-02" example:"2025-01-31"`
+	// Priority left empty removes the priority.
+	Priority string `json:"priority" validate:"omitempty,oneof=low medium high" example:"high"`
+}
+
+// CreateTasksRequestDto creates several tasks at once, e.g. the ones previewed from an image.
+type CreateTasksRequestDto struct {
+	Tasks []CreateTaskRequestDto `json:"tasks" validate:"required,min=1,max=50,dive"`
+}
+
+// TaskBreakdownRequestDto asks for the steps of a task. Without a prompt the default breakdown prompt is used.
+type TaskBreakdownRequestDto struct {
+	PromptId *uint `json:"promptId" validate:"omitempty,min=1" example:"1"`
+}
+
+// QuickAddRequestDto creates a task from a sentence such as "call supplier about invoice next Tuesday high priority #finance".
+type QuickAddRequestDto struct {
+	Text string `json:"text" validate:"required,max=500" example:"call supplier about invoice next Tuesday high priority #finance"`
+	// ProjectId is used when the text has no #tag naming one of the user's projects; without it the
+	// user's default project is used.
+	ProjectId *uint `json:"projectId" validate:"omitempty,min=1" example:"1"`
 }
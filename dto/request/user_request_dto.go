@@ -3,9 +3,21 @@ package request
 type UpdateUserRequest struct {
 	FirstName string `json:"firstName" validate:"omitempty,min=2,max=50" example:"John"`
 	LastName  string `json:"lastName" validate:"omitempty,min=2,max=50" example:"Doe"`
-	Email     string `json:"email" validate:"omitempty,email" example:"john.doe@example.com"`
 	Phone     string `json:"phone" validate:"omitempty,min=4,max=15" example:"+123456789"`
-	Image     []byte `json:"image" validate:"omitempty"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"newEmail" validate:"required,email" example:"john.new@example.com"`
+	CurrentPassword string `json:"currentPassword" validate:"required" example:"P@ssw0rd!"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required" example:"P@ssw0rd!"`
+	NewPassword     string `json:"newPassword" validate:"required,max=72" example:"NewP@ss123"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required,min=64" example:"6a1fbd97e8...<rest_of_token>"`
 }
 
 type User struct {
@@ -23,3 +35,12 @@ type User struct {
 	Address   string `json:"address" example:"123 Main St, New York, USA"`
 	Role      string `json:"role" example:"USER"`
 }
+
+type ImpersonateUserRequest struct {
+	Reason  string `json:"reason" validate:"required,min=5,max=500" example:"Investigating ticket #123"`
+	Minutes int    `json:"minutes,omitempty" validate:"omitempty,min=1,max=60" example:"30"`
+}
+
+type DeleteAccountRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required" example:"P@ssw0rd!"`
+}
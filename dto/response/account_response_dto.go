@@ -0,0 +1,18 @@
+package response
+
+import "time"
+
+type AccountExportProjectsDto struct {
+	Owned  []ProjectResponseDto `json:"owned"`
+	Shared []ProjectResponseDto `json:"shared"`
+}
+
+// AccountExportPromptDto names a prompt the user ran. The system prompt itself is not the user's data.
+type AccountExportPromptDto struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type AccountDeletionResponseDto struct {
+	DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+}
@@ -1,7 +1,9 @@
 package response
 
 type AISettingsResponseDto struct {
-	BaseUrl string `json:"baseUrl"`
-	APIKey  string `json:"apiKey"`
-	Model   string `json:"model"`
+	Provider   string `json:"provider"`
+	BaseUrl    string `json:"baseUrl"`
+	APIKey     string `json:"apiKey"`
+	Model      string `json:"model"`
+	APIVersion string `json:"apiVersion,omitempty"`
 }
@@ -0,0 +1,68 @@
+package response
+
+import "time"
+
+// AIUsageRowDto sums the AI calls of a user with a model on a day.
+type AIUsageRowDto struct {
+	Day string `json:"day" example:"2025-01-31"`
+	// UserId is 0 for the calls of purged accounts.
+	UserId       uint   `json:"userId"`
+	Model        string `json:"model" example:"gpt-4o"`
+	Requests     int64  `json:"requests"`
+	Failures     int64  `json:"failures"`
+	InputTokens  int64  `json:"inputTokens"`
+	OutputTokens int64  `json:"outputTokens"`
+	AvgLatencyMs int64  `json:"avgLatencyMs"`
+	// Cost is estimated from the price of the model, when one is set.
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+type AIUsageTotalsDto struct {
+	Requests     int64 `json:"requests"`
+	Failures     int64 `json:"failures"`
+	InputTokens  int64 `json:"inputTokens"`
+	OutputTokens int64 `json:"outputTokens"`
+	// Cost sums the rows whose model has a price.
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+type AIUsageReportDto struct {
+	From   string           `json:"from"`
+	To     string           `json:"to"`
+	Rows   []AIUsageRowDto  `json:"rows"`
+	Totals AIUsageTotalsDto `json:"totals"`
+	// Quota is only in the report of the user's own usage.
+	Quota *AIQuotaStatusDto `json:"quota,omitempty"`
+}
+
+// AIQuotaStatusDto is the quota of a user and how much of it this month used. Limits of 0 mean no limit.
+type AIQuotaStatusDto struct {
+	MonthlyTokens   int64     `json:"monthlyTokens"`
+	MonthlyRequests int64     `json:"monthlyRequests"`
+	UsedTokens      int64     `json:"usedTokens"`
+	UsedRequests    int64     `json:"usedRequests"`
+	ResetsAt        time.Time `json:"resetsAt"`
+}
+
+type AIQuotaDto struct {
+	UserId          uint  `json:"userId,omitempty"`
+	MonthlyTokens   int64 `json:"monthlyTokens"`
+	MonthlyRequests int64 `json:"monthlyRequests"`
+}
+
+type AIQuotasResponseDto struct {
+	// Default applies to the users without a quota of their own.
+	Default AIQuotaDto   `json:"default"`
+	Users   []AIQuotaDto `json:"users"`
+}
+
+type AIModelPriceDto struct {
+	Model            string    `json:"model" example:"gpt-4o"`
+	InputPerMillion  float64   `json:"inputPerMillion" example:"2.5"`
+	OutputPerMillion float64   `json:"outputPerMillion" example:"10"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+type AIModelPricesResponseDto struct {
+	Prices []AIModelPriceDto `json:"prices"`
+}
@@ -0,0 +1,18 @@
+package response
+
+import "time"
+
+type SecurityEventResponseDto struct {
+	Id             uint      `json:"id"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Type           string    `json:"type"`
+	Outcome        string    `json:"outcome"`
+	ActorId        uint      `json:"actorId,omitempty"`
+	ActorEmail     string    `json:"actorEmail,omitempty"`
+	ImpersonatorId uint      `json:"impersonatorId,omitempty"`
+	TargetId       uint      `json:"targetId,omitempty"`
+	TargetEmail    string    `json:"targetEmail,omitempty"`
+	IP             string    `json:"ip,omitempty"`
+	UserAgent      string    `json:"userAgent,omitempty"`
+	Detail         string    `json:"detail,omitempty"`
+}
@@ -0,0 +1,11 @@
+package response
+
+type PasswordPolicyResponseDto struct {
+	MinLength     int  `json:"minLength" example:"8"`
+	RequireUpper  bool `json:"requireUpper" example:"true"`
+	RequireLower  bool `json:"requireLower" example:"true"`
+	RequireDigit  bool `json:"requireDigit" example:"true"`
+	RequireSymbol bool `json:"requireSymbol" example:"false"`
+	History       int  `json:"history" example:"5"`
+	BreachedCheck bool `json:"breachedCheck" example:"false"`
+}
@@ -0,0 +1,20 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobResponseDto is the state of a background AI job.
+type JobResponseDto struct {
+	Id     uint   `json:"id"`
+	Kind   string `json:"kind" example:"vision.analyze"`
+	Status string `json:"status" example:"queued"`
+	// Result is what the synchronous endpoint would have returned, once the job succeeded.
+	Result json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	// Error tells why the job failed.
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+}
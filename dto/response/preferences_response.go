@@ -0,0 +1,11 @@
+package response
+
+type PreferencesResponseDto struct {
+	Timezone         string `json:"timezone"`
+	Locale           string `json:"locale"`
+	WeekStart        string `json:"weekStart"`
+	DateFormat       string `json:"dateFormat"`
+	DefaultProjectId *uint  `json:"defaultProjectId"`
+	DefaultTaskSort  string `json:"defaultTaskSort"`
+	DefaultPromptId  *uint  `json:"defaultPromptId"`
+}
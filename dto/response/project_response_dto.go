@@ -10,3 +10,16 @@ type ProjectResponseDto struct {
 	CreatedAt   time.Time                   `json:"createdAt"`
 	UpdatedAt   time.Time                   `json:"updatedAt"`
 }
+
+// ProjectSummaryResponseDto is an AI status report of a project.
+type ProjectSummaryResponseDto struct {
+	ProjectId   uint      `json:"projectId"`
+	Summary     string    `json:"summary"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Cached is true when the report was generated by an earlier request.
+	Cached bool `json:"cached"`
+	// Stale is true when tasks of the project changed after the report was generated.
+	Stale bool `json:"stale"`
+	// Emailed is true when the report is being sent to the user.
+	Emailed bool `json:"emailed"`
+}
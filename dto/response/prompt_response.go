@@ -7,6 +7,16 @@ type PromptResponse struct {
 	Title        string    `json:"title" validate:"required,max=150"`
 	Description  string    `json:"description"`
 	SystemPrompt string    `json:"systemPrompt" validate:"required"`
+	Purpose      string    `json:"purpose"`
+	IsDefault    bool      `json:"isDefault"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 }
+
+// PromptSummaryResponse lists a prompt to users choosing one for vision analysis, without its system prompt.
+type PromptSummaryResponse struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	IsDefault   bool   `json:"isDefault"`
+}
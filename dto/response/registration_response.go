@@ -0,0 +1,25 @@
+package response
+
+import "time"
+
+type RegistrationSettingsResponseDto struct {
+	Mode           string   `json:"mode" example:"domains"`
+	AllowedDomains []string `json:"allowedDomains" example:"example.com"`
+}
+
+type InvitationResponseDto struct {
+	Id          uint       `json:"id" example:"1"`
+	Email       string     `json:"email" example:"new.member@example.com"`
+	ProjectId   *uint      `json:"projectId,omitempty" example:"1"`
+	ProjectName string     `json:"projectName,omitempty" example:"Website"`
+	InvitedBy   string     `json:"invitedBy" example:"admin@example.com"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	Used        bool       `json:"used"`
+	AcceptedAt  *time.Time `json:"acceptedAt,omitempty"`
+}
+
+type InvitationPreviewResponseDto struct {
+	Email       string    `json:"email" example:"new.member@example.com"`
+	ProjectName string    `json:"projectName,omitempty" example:"Website"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
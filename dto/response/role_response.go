@@ -0,0 +1,14 @@
+package response
+
+type PermissionResponseDto struct {
+	Id          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RoleResponseDto struct {
+	Id          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Value       string   `json:"value"`
+	Permissions []string `json:"permissions"`
+}
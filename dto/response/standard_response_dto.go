@@ -26,7 +26,11 @@ type StandardResponseError struct {
 }
 
 func WriteJSONResponse(c echo.Context, statusCode int, message string, data any, isError bool) error {
+	return c.JSON(statusCode, NewStandardResponse(statusCode, message, data, isError))
+}
 
+// NewStandardResponse builds the body WriteJSONResponse sends, for responses written some other way.
+func NewStandardResponse(statusCode int, message string, data any, isError bool) any {
 	var response any
 	if isError {
 		response = StandardResponseError{
@@ -57,5 +61,5 @@ func WriteJSONResponse(c echo.Context, statusCode int, message string, data any,
 
 	}
 
-	return c.JSON(statusCode, response)
+	return response
 }
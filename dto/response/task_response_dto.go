@@ -12,10 +12,23 @@ type TaskResponseDto struct {
 	StatusId    int       `json:"statusId" form:"statusId"`
 	UserId      int       `json:"userId" form:"userId"`
 	ProjectId   int       `json:"projectId" form:"projectId"`
+	DueDate     *string   `json:"dueDate,omitempty" form:"dueDate"`
+	Priority    string    `json:"priority,omitempty" form:"priority"`
 	CreatedAt   time.Time `json:"createdAt" form:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt" form:"updatedAt"`
 }
 
+// TasksResponseDto lists tasks created together, without pagination.
+type TasksResponseDto struct {
+	Tasks []TaskResponseDto `json:"tasks"`
+}
+
+// QuickAddResponseDto is the task created by quick-add and whether the AI or the rule-based parser read the text.
+type QuickAddResponseDto struct {
+	Task   TaskResponseDto `json:"task"`
+	Parser string          `json:"parser" example:"ai"`
+}
+
 type TaskResponseForProjectDto struct {
 	Id          int    `json:"id"`
 	Title       string `json:"title" form:"title" validate:"required"`
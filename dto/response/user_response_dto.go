@@ -12,7 +12,28 @@ type UserResponseDto struct {
 	Phone     string    `json:"phone,omitempty"`
 	Username  string    `json:"username,omitempty"`
 	BirthDate time.Time `json:"birthDate"`
-	Image     []byte    `json:"image,omitempty"`
+	AvatarUrl string    `json:"avatarUrl,omitempty"`
 	Address   string    `json:"address"`
 	Role      string    `json:"role"`
+	Verified  bool      `json:"verified"`
+	Suspended bool      `json:"suspended"`
+
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty"`
+}
+
+type ImpersonationSessionResponseDto struct {
+	Id         uint       `json:"id"`
+	AdminId    uint       `json:"adminId"`
+	AdminEmail string     `json:"adminEmail"`
+	UserId     uint       `json:"userId"`
+	UserEmail  string     `json:"userEmail"`
+	Reason     string     `json:"reason"`
+	IP         string     `json:"ip"`
+	StartedAt  time.Time  `json:"startedAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	EndedAt    *time.Time `json:"endedAt,omitempty"`
+}
+
+type AvatarResponseDto struct {
+	AvatarUrl string `json:"avatarUrl"`
 }
@@ -12,7 +12,7 @@ var (
 	//go:embed config/static/root_image.png
 	ImageFS embed.FS
 
-	//go:embed config/static/templates/*.html
+	//go:embed config/static/templates/i18n
 	TemplatesFS embed.FS
 
 	//go:embed frontend/dist/*
@@ -18,6 +18,8 @@ import ResetPasswordPage from "./pages/ResetPasswordPage.tsx";
 import {AdminRoute} from "./utils/AdminRoute.tsx";
 import {useBootstrapAuth} from './hooks/useBootstrapAuth';
 import PromptsPage from "./pages/PromptsPage.tsx";
+import MagicLoginPage from "./pages/MagicLoginPage.tsx";
+import ConfirmEmailChangePage from "./pages/ConfirmEmailChangePage.tsx";
 
 function App() {
 
@@ -58,6 +60,12 @@ function App() {
                                 <ResetPasswordPage/>
                             </div>
                         }/>
+                        <Route path="/magic-login" element={
+                            <div>
+                                <AlertManager/>
+                                <MagicLoginPage/>
+                            </div>
+                        }/>
                     </Route>
                     <Route element={<PrivateRoute/>}>
                         <Route element={<DrawerNavigation/>}>
@@ -71,6 +79,12 @@ function App() {
                             </Route>
                         </Route>
                     </Route>
+                    <Route path="/confirm-email-change" element={
+                        <div>
+                            <AlertManager/>
+                            <ConfirmEmailChangePage/>
+                        </div>
+                    }/>
                     <Route path="*" element={<NotFoundPage />} />
                 </Routes>
             </main>
@@ -15,6 +15,8 @@ export const useBootstrapAuth = () => {
             '/pending-email-verification',
             '/forgot-password',
             '/reseting-password',
+            '/magic-login',
+            '/confirm-email-change',
         ]
 
         if (publicAuthPaths.includes(location.pathname)) {
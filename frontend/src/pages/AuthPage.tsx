@@ -1,11 +1,11 @@
 import React, {FormEvent, useEffect, useState} from 'react'
 import {useTranslation} from 'react-i18next'
 import {useAlert} from '../hooks/useAlert'
-import {useNavigate} from 'react-router-dom'
-import {CurrentUserMe, GenderType, RegisterDto} from "../schemas/auth.ts";
+import {useNavigate, useSearchParams} from 'react-router-dom'
+import {CurrentUserMe, GenderType, InvitationPreview, RegisterDto} from "../schemas/auth.ts";
 import {ApiResponse, ThemeColor} from "../schemas/globals.ts";
 import "react-day-picker/style.css";
-import {getCurrentUser, login, register} from "../services/AuthService.ts";
+import {getCurrentUser, login, previewInvitation, register} from "../services/AuthService.ts";
 import {
     CalendarIcon,
     EnvelopeIcon,
@@ -23,8 +23,11 @@ const AuthPage: React.FC = () => {
     const alert = useAlert()
     const navigate = useNavigate()
     const {setAuth} = useAuthStore()
+    const [searchParams] = useSearchParams()
+    // Invitation emails link here with ?invite=<token>, which must be sent along with the registration
+    const inviteToken = searchParams.get('invite') || ''
 
-    const [isLogin, setIsLogin] = useState(true)
+    const [isLogin, setIsLogin] = useState(!inviteToken)
     const [email, setEmail] = useState('')
     const [password, setPassword] = useState('')
     // Only for register
@@ -53,6 +56,19 @@ const AuthPage: React.FC = () => {
         })()
     }, [])
 
+    useEffect(() => {
+        if (!inviteToken) return
+        // Pre-fill the invited address, the invitation is only accepted for it
+        (async () => {
+            const res = await previewInvitation(inviteToken)
+            if (res.ok && res.result) {
+                setEmail((res.result as InvitationPreview).email)
+            } else {
+                alert(t('auth.invitationInvalid'), 'alert-error')
+            }
+        })()
+    }, [inviteToken])
+
 
     const toggleMode = () => setIsLogin(!isLogin)
 
@@ -113,7 +129,8 @@ const AuthPage: React.FC = () => {
                 gender: gender,
                 age: age,
                 address: '',
-                birthDate: date ? date.toISOString() : ''
+                birthDate: date ? date.toISOString() : '',
+                inviteToken: inviteToken || undefined
             }
             const response = await register(userToRegister)
             if (response.ok && (response.statusCode === 201 || response.statusCode === 200)) {
@@ -143,6 +160,10 @@ const AuthPage: React.FC = () => {
                     {isLogin ? t('auth.loginTitle') : t('auth.registerTitle')}
                 </h2>
 
+                {!isLogin && inviteToken && (
+                    <p className="text-sm text-center opacity-70">{t('auth.invited')}</p>
+                )}
+
                 <form className="space-y-2 flex flex-col items-center" onSubmit={handleSubmit}>
                     {!isLogin && (
                         <>
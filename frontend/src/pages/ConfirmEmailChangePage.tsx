@@ -0,0 +1,87 @@
+import React, {useEffect, useRef, useState} from 'react';
+import {useNavigate, useSearchParams} from 'react-router-dom';
+import {confirmEmailChange} from "../services/AuthService";
+import {useTranslation} from "react-i18next";
+import {ApiResponse, ThemeColor} from "../schemas/globals.ts";
+import {useAlert} from "../hooks/useAlert.ts";
+import useAuthStore from "../store/authStore";
+
+const ConfirmEmailChangePage: React.FC = () => {
+    const { t } = useTranslation();
+    const [status, setStatus] = useState<'loading' | 'success' | 'error'>('loading');
+    const [message, setMessage] = useState('');
+    const [searchParams] = useSearchParams();
+    const navigate = useNavigate();
+    const { clearAuth } = useAuthStore();
+    // the token can only be used once, so it must not be sent again when the effect runs twice
+    const sent = useRef(false);
+    const isDarkMode = window.matchMedia('(prefers-color-scheme: dark)').matches
+    const theme = localStorage.getItem('theme') as ThemeColor || (isDarkMode ? ThemeColor.DARK : ThemeColor.LIGHT)
+    const alert = useAlert()
+
+    const handlerErrors = (response: ApiResponse<any>) => {
+        const errors = response.errors instanceof Array ? response.errors : [response.errors as string]
+        const messageFormatted = errors[0] ? errors[0].slice(0, 1).toUpperCase() + errors[0].slice(1) : t('confirmEmailChange.failed')
+        alert(messageFormatted, 'alert-error')
+        setMessage(messageFormatted);
+    }
+
+    useEffect(() => {
+        if (sent.current) return;
+        sent.current = true;
+
+        const token = searchParams.get('token');
+        if (!token) {
+            setStatus('error');
+            setMessage(t('verifyEmail.tokenMissing'));
+            alert(t('verifyEmail.tokenMissing'), 'alert-error');
+            return;
+        }
+
+        const run = async () => {
+            const response = await confirmEmailChange(token);
+            if (response.ok) {
+                // the change signs out every session of the account
+                clearAuth();
+                setStatus('success');
+                setMessage(t('confirmEmailChange.success'));
+                alert(t('confirmEmailChange.success'), 'alert-success');
+            } else {
+                setStatus('error');
+                handlerErrors(response);
+            }
+        }
+        run().then();
+    }, [searchParams, t]);
+
+    return (
+        <div className="flex flex-col items-center justify-center min-h-screen bg-base-200 p-6" data-theme={theme}>
+            <div className="card w-full max-w-md bg-base-100 shadow-xl p-6 text-center">
+                {status === 'loading' && (
+                    <>
+                        <span className="loading loading-spinner loading-lg text-primary mb-4" />
+                        <h2 className="text-lg font-bold">{t('confirmEmailChange.confirming')}</h2>
+                    </>
+                )}
+
+                {status !== 'loading' && (
+                    <>
+                        <div className={`${status === 'success' ? 'text-success' : 'text-error'} text-6xl mb-4`}>
+                            {status === 'success' ? '✅' : '❌'}
+                        </div>
+                        <h2 className="text-xl font-bold mb-2">{message}</h2>
+                        {status === 'success' && <p className="text-sm opacity-70">{t('confirmEmailChange.signInAgain')}</p>}
+                        <button
+                            className="btn btn-primary mt-4"
+                            onClick={() => navigate('/auth')}
+                        >
+                            {t('verifyEmail.goLogin')}
+                        </button>
+                    </>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default ConfirmEmailChangePage;
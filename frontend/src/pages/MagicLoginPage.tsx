@@ -0,0 +1,87 @@
+import React, {useEffect, useRef, useState} from 'react';
+import {useNavigate, useSearchParams} from 'react-router-dom';
+import {getCurrentUser, verifyMagicLink} from "../services/AuthService";
+import {useTranslation} from "react-i18next";
+import {ApiResponse, ThemeColor} from "../schemas/globals.ts";
+import {CurrentUserMe} from "../schemas/auth.ts";
+import {useAlert} from "../hooks/useAlert.ts";
+import useAuthStore from "../store/authStore";
+
+const MagicLoginPage: React.FC = () => {
+    const { t } = useTranslation();
+    const [status, setStatus] = useState<'loading' | 'error'>('loading');
+    const [message, setMessage] = useState('');
+    const [searchParams] = useSearchParams();
+    const navigate = useNavigate();
+    const { setAuth } = useAuthStore();
+    // the token can only be used once, so it must not be sent again when the effect runs twice
+    const sent = useRef(false);
+    const isDarkMode = window.matchMedia('(prefers-color-scheme: dark)').matches
+    const theme = localStorage.getItem('theme') as ThemeColor || (isDarkMode ? ThemeColor.DARK : ThemeColor.LIGHT)
+    const alert = useAlert()
+
+    const handlerErrors = (response: ApiResponse<any>) => {
+        const errors = response.errors instanceof Array ? response.errors : [response.errors as string]
+        const messageFormatted = errors[0] ? errors[0].slice(0, 1).toUpperCase() + errors[0].slice(1) : t('magicLogin.failed')
+        alert(messageFormatted, 'alert-error')
+        setMessage(messageFormatted);
+    }
+
+    useEffect(() => {
+        if (sent.current) return;
+        sent.current = true;
+
+        const token = searchParams.get('token');
+        if (!token) {
+            setStatus('error');
+            setMessage(t('verifyEmail.tokenMissing'));
+            alert(t('verifyEmail.tokenMissing'), 'alert-error');
+            return;
+        }
+
+        const run = async () => {
+            const response = await verifyMagicLink(token);
+            if (!response.ok) {
+                setStatus('error');
+                handlerErrors(response);
+                return;
+            }
+            const me = await getCurrentUser();
+            if (me.ok && me.result) {
+                const { id, email, role } = me.result as CurrentUserMe
+                setAuth({ id, email, role })
+            }
+            navigate('/', { replace: true });
+        }
+        run().then();
+    }, [searchParams, t]);
+
+    return (
+        <div className="flex flex-col items-center justify-center min-h-screen bg-base-200 p-6" data-theme={theme}>
+            <div className="card w-full max-w-md bg-base-100 shadow-xl p-6 text-center">
+                {status === 'loading' && (
+                    <>
+                        <span className="loading loading-spinner loading-lg text-primary mb-4" />
+                        <h2 className="text-lg font-bold">{t('magicLogin.signingIn')}</h2>
+                    </>
+                )}
+
+                {status === 'error' && (
+                    <>
+                        <div className="text-error text-6xl mb-4">❌</div>
+                        <h2 className="text-xl font-bold mb-2">{message}</h2>
+                        <p className="text-sm opacity-70">{t('magicLogin.requestNew')}</p>
+                        <button
+                            className="btn btn-primary mt-4"
+                            onClick={() => navigate('/auth')}
+                        >
+                            {t('verifyEmail.goLogin')}
+                        </button>
+                    </>
+                )}
+            </div>
+        </div>
+    );
+};
+
+export default MagicLoginPage;
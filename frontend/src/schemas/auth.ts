@@ -13,6 +13,12 @@ export interface CurrentUserMe {
     role: number
 }
 
+export interface InvitationPreview {
+    email: string
+    projectName?: string
+    expiresAt: string
+}
+
 export type GenderType = "male" | "female"
 
 export interface RegisterDto {
@@ -26,6 +32,7 @@ export interface RegisterDto {
     gender: GenderType | string
     birthDate?: string
     address?: string
+    inviteToken?: string
 }
 
 export interface User {
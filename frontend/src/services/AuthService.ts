@@ -2,7 +2,7 @@ import {apiClient} from './apiClient'
 import {AxiosResponse} from 'axios'
 import {ApiResponse} from '../schemas/globals.ts'
 import {handleApiError, handleApiResponse} from '../utils/apiUtils.ts'
-import {CurrentUserMe, LoginDto, RegisterDto, User} from '../schemas/auth.ts'
+import {CurrentUserMe, InvitationPreview, LoginDto, RegisterDto, User} from '../schemas/auth.ts'
 
 export async function login(data: LoginDto): Promise<ApiResponse<null>> {
     try {
@@ -82,3 +82,32 @@ export async function getCurrentUser(): Promise<ApiResponse<CurrentUserMe>> {
         return handleApiError<CurrentUserMe>(error as AxiosResponse<ApiResponse<CurrentUserMe>>)
     }
 }
+
+export async function verifyMagicLink(token: string): Promise<ApiResponse<null>> {
+    try {
+        const res: AxiosResponse<ApiResponse<null>> = await apiClient.post('/auth/magic-link/verify', {token})
+        return handleApiResponse<null>(res)
+    } catch (error) {
+        console.error('Error logging in with magic link:', error)
+        return handleApiError<null>(error as AxiosResponse<ApiResponse<null>>)
+    }
+}
+
+export async function confirmEmailChange(token: string): Promise<ApiResponse<null>> {
+    try {
+        const res: AxiosResponse<ApiResponse<null>> = await apiClient.post('/auth/confirm-email-change', {token})
+        return handleApiResponse<null>(res)
+    } catch (error) {
+        console.error('Error confirming email change:', error)
+        return handleApiError<null>(error as AxiosResponse<ApiResponse<null>>)
+    }
+}
+
+export async function previewInvitation(token: string): Promise<ApiResponse<InvitationPreview>> {
+    try {
+        const res: AxiosResponse<ApiResponse<InvitationPreview>> = await apiClient.get('/auth/invitation', {params: {token}})
+        return handleApiResponse<InvitationPreview>(res)
+    } catch (error) {
+        return handleApiError<InvitationPreview>(error as AxiosResponse<ApiResponse<InvitationPreview>>)
+    }
+}
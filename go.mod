@@ -13,6 +13,7 @@ require (
 	github.com/swaggo/echo-swagger v1.4.1
 	github.com/swaggo/swag v1.16.6
 	golang.org/x/crypto v0.45.0
+	golang.org/x/image v0.33.0
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/gorm v1.30.1
 )
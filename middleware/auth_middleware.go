@@ -1,8 +1,9 @@
 package middleware
 
 import (
-	"SimpleToDo/config"
 	"SimpleToDo/dto/response"
+	"SimpleToDo/service"
+	"SimpleToDo/util/jwtkeys"
 	"net/http"
 	"strings"
 
@@ -12,6 +13,13 @@ import (
 
 const AuthCookieName = "auth_token"
 
+var sessionService *service.SessionService
+
+// UseSessionService enables the per-request check that rejects tokens revoked after they were issued.
+func UseSessionService(s *service.SessionService) {
+	sessionService = s
+}
+
 func JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		var tokenStr string
@@ -35,13 +43,9 @@ func JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return response.WriteJSONResponse(c, http.StatusUnauthorized, "Missing auth token", "", true)
 		}
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			secret := config.GetAppEnv().JWTSecret
-			if secret == "" {
-				secret = "supersecretkey"
-			}
-			return []byte(secret), nil
-		})
+		token, err := jwt.Parse(tokenStr, jwtkeys.Default().Keyfunc,
+			jwt.WithValidMethods([]string{jwtkeys.AlgRS256, jwtkeys.AlgEdDSA}),
+			jwt.WithExpirationRequired())
 
 		if err != nil || !token.Valid {
 			return response.WriteJSONResponse(c, http.StatusUnauthorized, "Invalid or expired token", "", true)
@@ -55,16 +59,56 @@ func JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		c.Set("user_id", claims["user_id"])
 		c.Set("user_role", claims["role"])
 		c.Set("user_email", claims["email"])
+
+		if sessionService != nil {
+			userId, _ := claims["user_id"].(float64)
+			issuedAt, err := claims.GetIssuedAt()
+			if err != nil || issuedAt == nil {
+				return response.WriteJSONResponse(c, http.StatusUnauthorized, "Invalid claims", "", true)
+			}
+			impersonationId, _ := claims["impersonation_id"].(float64)
+			user, err := sessionService.ValidateSession(uint(userId), issuedAt.Time, uint(impersonationId))
+			if err != nil {
+				return response.WriteJSONResponse(c, http.StatusUnauthorized, "Invalid or expired token", err.Error(), true)
+			}
+			// the role and email may have changed since the token was issued
+			c.Set("user_role", float64(user.RoleId))
+			c.Set("user_email", user.Email)
+		}
+		if impersonator, ok := claims["impersonator"]; ok {
+			c.Set("impersonator_id", impersonator)
+			c.Set("impersonation_id", claims["impersonation_id"])
+		}
 		return next(c)
 	}
 }
 
-func AdminOnlyMIddleware(next echo.HandlerFunc) echo.HandlerFunc {
+// NoImpersonationMiddleware blocks account-sensitive actions for administrators acting as another user.
+func NoImpersonationMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		role, ok := c.Get("user_role").(float64)
-		if !ok || role != 1 {
-			return response.WriteJSONResponse(c, http.StatusForbidden, "Admin access required", nil, true)
+		if c.Get("impersonator_id") != nil {
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Not allowed while impersonating", "", true)
 		}
 		return next(c)
 	}
 }
+
+// PermissionMiddleware lets the request through only when the caller's role grants the named permission.
+func PermissionMiddleware(roleService *service.RoleService, permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, ok := c.Get("user_role").(float64)
+			if !ok {
+				return response.WriteJSONResponse(c, http.StatusForbidden, "Permission required", permission, true)
+			}
+			allowed, err := roleService.HasPermission(uint(role), permission)
+			if err != nil {
+				return response.WriteJSONResponse(c, http.StatusInternalServerError, "Permission check failed", err.Error(), true)
+			}
+			if !allowed {
+				return response.WriteJSONResponse(c, http.StatusForbidden, "Permission required", permission, true)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/service"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimitMiddleware allows at most limit requests per client IP and window for the given action.
+func RateLimitMiddleware(throttleService *service.ThrottleService, action string, limit int, window time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := throttleService.Hit(service.IPActionSubject(action, c.RealIP()), limit, window)
+			if err != nil {
+				var throttled *service.ThrottledError
+				if errors.As(err, &throttled) {
+					return WriteTooManyRequests(c, throttled)
+				}
+				return response.WriteJSONResponse(c, http.StatusInternalServerError, "Rate limit check failed", err.Error(), true)
+			}
+			return next(c)
+		}
+	}
+}
+
+func WriteTooManyRequests(c echo.Context, throttled *service.ThrottledError) error {
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(seconds))
+	return response.WriteJSONResponse(c, http.StatusTooManyRequests, "Too many requests", throttled.Error(), true)
+}
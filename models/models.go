@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"time"
@@ -12,10 +13,27 @@ type Status struct {
 	Value string
 }
 
+const (
+	PermissionUsersManage     = "users:manage"
+	PermissionRolesManage     = "roles:manage"
+	PermissionPromptsManage   = "prompts:manage"
+	PermissionProjectsReadAll = "projects:read_all"
+	PermissionSettingsManage  = "settings:manage"
+	PermissionAuditRead       = "audit:read"
+	PermissionAIUsageManage   = "ai_usage:manage"
+)
+
+type Permission struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"unique;not null"`
+	Description string
+}
+
 type Role struct {
-	ID    uint   `gorm:"primarykey"`
-	Name  string `gorm:"unique;not null"`
-	Value string
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"unique;not null"`
+	Value       string
+	Permissions []Permission `gorm:"many2many:role_permissions"`
 }
 
 type Task struct {
@@ -28,6 +46,10 @@ type Task struct {
 	ProjectId   uint
 	User        User    `gorm:"foreignKey:UserId"`
 	Project     Project `gorm:"foreignKey:ProjectId"`
+	// DueDate is a calendar day, stored as midnight UTC; whether it is today depends on the user's timezone.
+	DueDate *time.Time `gorm:"type:date;index"`
+	// Priority is low, medium, high or empty when the task has none.
+	Priority string `gorm:"size:10"`
 }
 
 type Project struct {
@@ -43,6 +65,24 @@ type Project struct {
 
 type Tasks []Task
 
+// ProjectSummary is the last AI status report generated for a project.
+type ProjectSummary struct {
+	ID          uint   `gorm:"primaryKey"`
+	ProjectId   uint   `gorm:"uniqueIndex;not null"`
+	Content     string `gorm:"type:text;not null"`
+	GeneratedBy uint   `gorm:"index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ProjectMember grants a user other than the owner access to a project.
+type ProjectMember struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	ProjectId uint `gorm:"not null;uniqueIndex:idx_project_member"`
+	UserId    uint `gorm:"not null;uniqueIndex:idx_project_member"`
+}
+
 type User struct {
 	gorm.Model `json:"gorm.Model"`
 	FirstName  string    `json:"firstName,omitempty"`
@@ -59,17 +99,136 @@ type User struct {
 	Address    string    `json:"address,omitempty"`
 	Role       Role      `gorm:"foreignKey:RoleId" json:"role"`
 	Verified   bool      `gorm:"default:false"`
+	// TokensValidAfter revokes every JWT issued before it, e.g. after a password change.
+	TokensValidAfter *time.Time `json:"-"`
+	Suspended        bool       `gorm:"not null;default:false"`
+	SuspendedAt      *time.Time
+	// DeletionScheduledAt is set when the user deletes their account; the account and everything it
+	// owns are purged once it has passed.
+	DeletionScheduledAt *time.Time `gorm:"index"`
+	// AvatarVersion changes with every new avatar and is part of its URL, so clients can cache it for good.
+	AvatarVersion string `gorm:"not null;default:''"`
+}
+
+// UserPreferences holds the display settings of a user. Users without a row use the column defaults.
+type UserPreferences struct {
+	ID               uint   `gorm:"primaryKey"`
+	UserID           uint   `gorm:"not null;uniqueIndex"`
+	Timezone         string `gorm:"not null;default:'UTC'"`
+	Locale           string `gorm:"not null;default:'en'"`
+	WeekStart        string `gorm:"not null;default:'monday'"`
+	DateFormat       string `gorm:"not null;default:'YYYY-MM-DD'"`
+	DefaultProjectId *uint
+	DefaultTaskSort  string `gorm:"not null;default:'oldest'"`
+	DefaultPromptId  *uint
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// UserAvatar is one square rendition of a user's avatar, re-encoded without the uploaded file's metadata.
+type UserAvatar struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_user_avatar_size"`
+	Size        int    `gorm:"not null;uniqueIndex:idx_user_avatar_size"`
+	ContentType string `gorm:"not null"`
+	Data        []byte `gorm:"type:bytea;not null"`
+	ETag        string `gorm:"not null"`
+	CreatedAt   time.Time
 }
 
 type AIServerSettings struct {
-	ID        uint   `gorm:"primaryKey"`
-	BaseUrl   string `gorm:"not null"`
-	APIKey    string `gorm:"not null"`
-	Model     string `gorm:"not null;default:'gpt-4o'"`
-	UserID    uint   `gorm:"not null;index"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID uint `gorm:"primaryKey"`
+	// Provider is one of aiprovider.Providers.
+	Provider string `gorm:"not null;default:'openai'"`
+	// BaseUrl may be empty to use the provider's public endpoint, except for Azure.
+	BaseUrl string `gorm:"not null"`
+	APIKey  string `gorm:"not null"`
+	Model   string `gorm:"not null;default:'gpt-4o'"`
+	// APIVersion is only used by Azure OpenAI.
+	APIVersion string
+	UserID     uint `gorm:"not null;index"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+// AI features whose calls are metered in AIUsage.
+const (
+	AIFeatureVision    = "vision"
+	AIFeatureQuickAdd  = "quick_add"
+	AIFeatureBreakdown = "breakdown"
+	AIFeatureSummary   = "summary"
+)
+
+// AIUsage records one call to an AI provider. The UserID of the calls of a purged account is 0.
+type AIUsage struct {
+	ID       uint   `gorm:"primaryKey"`
+	UserID   uint   `gorm:"not null;index"`
+	Feature  string `gorm:"size:30;not null"`
+	Provider string `gorm:"size:20;not null"`
+	Model    string `gorm:"size:100;not null"`
+	// PromptID is the prompt the call was made with, if it came from the prompts table.
+	PromptID     *uint
+	InputTokens  int `gorm:"not null;default:0"`
+	OutputTokens int `gorm:"not null;default:0"`
+	LatencyMs    int64
+	Success      bool   `gorm:"not null"`
+	Error        string `gorm:"size:500"`
+	// Day is the UTC date of the call, YYYY-MM-DD, so that reports can group by it on every database.
+	Day       string    `gorm:"size:10;not null;index"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// AIQuota limits the AI calls of a user per calendar month (UTC). Zero means no limit. Users without
+// one get the default quota of the settings.
+type AIQuota struct {
+	ID              uint  `gorm:"primaryKey"`
+	UserID          uint  `gorm:"not null;uniqueIndex"`
+	MonthlyTokens   int64 `gorm:"not null;default:0"`
+	MonthlyRequests int64 `gorm:"not null;default:0"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// AIModelPrice is what a model costs, used to estimate the cost of usage reports.
+type AIModelPrice struct {
+	Model string `gorm:"primaryKey;size:100"`
+	// InputPerMillion and OutputPerMillion are the price of a million tokens, in the currency the
+	// admins chose.
+	InputPerMillion  float64 `gorm:"not null;default:0"`
+	OutputPerMillion float64 `gorm:"not null;default:0"`
+	UpdatedAt        time.Time
+}
+
+// Statuses of a Job.
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// JobKindVisionAnalyze is a job analyzing an image with /vision/analyze.
+const JobKindVisionAnalyze = "vision.analyze"
+
+// Job is a unit of AI work run in the background by the job runner.
+type Job struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"not null;index"`
+	Kind   string `gorm:"size:50;not null"`
+	Status string `gorm:"size:20;not null;default:queued;index"`
+	// Payload is the JSON input of the job; it is cleared once the job finished.
+	Payload string `gorm:"type:text"`
+	// Result is the JSON output of a succeeded job, Error the reason a job failed.
+	Result string `gorm:"type:text"`
+	Error  string `gorm:"type:text"`
+	// Checkpoint is what the handler recorded of the changes it made, so that a job rerun after an
+	// interruption does not make them again.
+	Checkpoint string `gorm:"type:text"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time `gorm:"index"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 type EmailVerificationToken struct {
@@ -94,14 +253,172 @@ type PasswordResetToken struct {
 	Used      bool           `gorm:"not null;default:false"`
 }
 
+type MagicLinkToken struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	UserID    uint           `gorm:"not null;index"`
+	TokenHash string         `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time      `gorm:"not null"`
+	Used      bool           `gorm:"not null;default:false"`
+}
+
+type EmailChangeToken struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	UserID    uint           `gorm:"not null;index"`
+	NewEmail  string         `gorm:"not null"`
+	TokenHash string         `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time      `gorm:"not null"`
+	Used      bool           `gorm:"not null;default:false"`
+}
+
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"not null;index"`
+	PasswordHash string    `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+const (
+	RegistrationModeOpen    = "open"
+	RegistrationModeClosed  = "closed"
+	RegistrationModeDomains = "domains"
+	RegistrationModeInvite  = "invite"
+)
+
+// Setting is a runtime configuration value that admins can change without restarting the server.
+type Setting struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	Value     string `gorm:"type:text"`
+	UpdatedAt time.Time
+}
+
+type Invitation struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Email       string         `gorm:"not null;index"`
+	TokenHash   string         `gorm:"size:64;not null;uniqueIndex"`
+	ProjectId   *uint
+	Project     *Project  `gorm:"foreignKey:ProjectId"`
+	InvitedById uint      `gorm:"not null;index"`
+	InvitedBy   User      `gorm:"foreignKey:InvitedById"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	Used        bool      `gorm:"not null;default:false"`
+	AcceptedAt  *time.Time
+}
+
+// ImpersonationSession records every time an administrator signs in as another user for support.
+type ImpersonationSession struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	// AdminId has no foreign key and AdminEmail is copied, so the record outlives the admin's account.
+	AdminId    uint   `gorm:"not null;index"`
+	AdminEmail string `gorm:"not null;default:''"`
+	UserId     uint   `gorm:"not null;index"`
+	User       User   `gorm:"foreignKey:UserId"`
+	Reason     string `gorm:"type:text;not null"`
+	IP         string
+	ExpiresAt  time.Time `gorm:"not null"`
+	EndedAt    *time.Time
+}
+
+const (
+	SecurityEventLogin                  = "auth.login"
+	SecurityEventRegister               = "auth.register"
+	SecurityEventEmailVerified          = "auth.email_verified"
+	SecurityEventPasswordResetRequested = "auth.password_reset_requested"
+	SecurityEventPasswordReset          = "auth.password_reset"
+	SecurityEventPasswordChanged        = "auth.password_changed"
+	SecurityEventEmailChangeRequested   = "auth.email_change_requested"
+	SecurityEventEmailChanged           = "auth.email_changed"
+	SecurityEventUserDeleted            = "user.deleted"
+	SecurityEventUserUnlocked           = "user.unlocked"
+	SecurityEventUserSuspended          = "user.suspended"
+	SecurityEventUserReactivated        = "user.reactivated"
+	SecurityEventUserVerified           = "user.verified"
+	SecurityEventRoleAssigned           = "user.role_changed"
+	SecurityEventImpersonationStarted   = "user.impersonation_started"
+	SecurityEventImpersonationEnded     = "user.impersonation_ended"
+	SecurityEventAISettingsUpdated      = "ai_settings.updated"
+	SecurityEventAccountExported        = "account.exported"
+	SecurityEventDeletionRequested      = "account.deletion_requested"
+	SecurityEventDeletionCancelled      = "account.deletion_cancelled"
+	SecurityEventAccountPurged          = "account.purged"
+	SecurityEventRoleCreated            = "role.created"
+	SecurityEventRolePermissionsChanged = "role.permissions_changed"
+	SecurityEventRegistrationUpdated    = "settings.registration_updated"
+	SecurityEventAIQuotaUpdated         = "settings.ai_quota_updated"
+	SecurityEventAIPriceUpdated         = "settings.ai_price_updated"
+	SecurityEventInvitationSent         = "invitation.sent"
+	SecurityEventInvitationRevoked      = "invitation.revoked"
+
+	SecurityOutcomeSuccess = "success"
+	SecurityOutcomeFailure = "failure"
+)
+
+var errSecurityEventsAppendOnly = errors.New("security events are append-only")
+
+// SecurityEvent is an entry of the append-only security audit log. Actor and target are kept by id and
+// email without foreign keys so the trail survives the deletion of the accounts it mentions.
+type SecurityEvent struct {
+	ID             uint      `gorm:"primaryKey"`
+	CreatedAt      time.Time `gorm:"index"`
+	EventType      string    `gorm:"size:64;not null;index"`
+	Outcome        string    `gorm:"size:16;not null;index"`
+	ActorId        uint      `gorm:"index"`
+	ActorEmail     string
+	ImpersonatorId uint
+	TargetId       uint `gorm:"index"`
+	TargetEmail    string
+	IP             string `gorm:"size:64"`
+	UserAgent      string `gorm:"type:text"`
+	Detail         string `gorm:"type:text"`
+}
+
+func (e *SecurityEvent) BeforeUpdate(*gorm.DB) error {
+	return errSecurityEventsAppendOnly
+}
+
+func (e *SecurityEvent) BeforeDelete(*gorm.DB) error {
+	return errSecurityEventsAppendOnly
+}
+
+// What a prompt is used for.
+const (
+	PromptPurposeVision    = "vision"
+	PromptPurposeBreakdown = "breakdown"
+)
+
 type Prompt struct {
-	ID           uint           `gorm:"primaryKey"`
-	Title        string         `gorm:"uniqueIndex;not null;size:150"`
-	Description  string         `gorm:"type:text;not null"`
-	SystemPrompt string         `gorm:"type:text;not null"`
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID           uint   `gorm:"primaryKey"`
+	Title        string `gorm:"uniqueIndex;not null;size:150"`
+	Description  string `gorm:"type:text;not null"`
+	SystemPrompt string `gorm:"type:text;not null"`
+	// Purpose is PromptPurposeVision or PromptPurposeBreakdown.
+	Purpose string `gorm:"size:20;not null;default:vision;index"`
+	// IsDefault marks the prompt used for its purpose when neither the request nor the user picks one.
+	IsDefault bool           `gorm:"not null;default:false"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type AuthThrottle struct {
+	ID            uint   `gorm:"primaryKey"`
+	Subject       string `gorm:"size:255;not null;uniqueIndex"`
+	Hits          int    `gorm:"not null;default:0"`
+	WindowStart   time.Time
+	Failures      int `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 func (u *User) BeforeCreate(*gorm.DB) (err error) {
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AccountRepository gathers everything a user owns, for data exports and for the final purge of
+// deleted accounts.
+type AccountRepository struct {
+	Db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) *AccountRepository {
+	return &AccountRepository{Db: db}
+}
+
+func (r *AccountRepository) FindOwnedProjects(userId uint) ([]models.Project, error) {
+	var projects []models.Project
+	err := r.Db.Where("user_id = ?", userId).Order("id").Find(&projects).Error
+	return projects, err
+}
+
+// FindMemberProjects returns projects shared with the user by someone else.
+func (r *AccountRepository) FindMemberProjects(userId uint) ([]models.Project, error) {
+	var projects []models.Project
+	err := r.Db.Where("id IN (?)", r.Db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userId)).
+		Order("id").Find(&projects).Error
+	return projects, err
+}
+
+func (r *AccountRepository) FindTasks(userId uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.Db.Preload("Status").Where("user_id = ?", userId).Order("id").Find(&tasks).Error
+	return tasks, err
+}
+
+// FindUsedPrompts returns the prompts the user's recorded AI calls were made with.
+func (r *AccountRepository) FindUsedPrompts(userId uint) ([]models.Prompt, error) {
+	var prompts []models.Prompt
+	err := r.Db.Where("id IN (?)", r.Db.Model(&models.AIUsage{}).Select("prompt_id").
+		Where("user_id = ? AND prompt_id IS NOT NULL", userId)).
+		Order("id").Find(&prompts).Error
+	return prompts, err
+}
+
+// FindAvatar returns the largest rendition of the user's avatar.
+func (r *AccountRepository) FindAvatar(userId uint) (*models.UserAvatar, error) {
+	var avatar models.UserAvatar
+	err := r.Db.Where("user_id = ?", userId).Order("size desc").First(&avatar).Error
+	if err != nil {
+		return nil, err
+	}
+	return &avatar, nil
+}
+
+func (r *AccountRepository) ScheduleDeletion(userId uint, at *time.Time) error {
+	return r.Db.Model(&models.User{}).Where("id = ?", userId).Update("deletion_scheduled_at", at).Error
+}
+
+func (r *AccountRepository) FindDueForDeletion(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.Db.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).Find(&users).Error
+	return users, err
+}
+
+// Purge hard-deletes the user together with their projects (including tasks other members added to
+// them), their tasks, AI settings, project summaries, AI jobs, AI quota, tokens and the impersonation
+// sessions run on their account. Their AI usage is kept for the reports and costs, anonymised to user 0.
+// Security events are append-only and stay, as do the impersonation sessions the user ran as an
+// administrator.
+func (r *AccountRepository) Purge(userId uint) error {
+	return r.Db.Transaction(func(tx *gorm.DB) error {
+		ownedProjects := tx.Model(&models.Project{}).Select("id").Where("user_id = ?", userId)
+
+		if err := tx.Unscoped().Where("user_id = ? OR project_id IN (?)", userId, ownedProjects).
+			Delete(&models.Task{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR project_id IN (?)", userId, ownedProjects).
+			Delete(&models.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("project_id IN (?)", ownedProjects).
+			Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id IN (?) OR generated_by = ?", ownedProjects, userId).
+			Delete(&models.ProjectSummary{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UserPreferences{}).Where("default_project_id IN (?)", ownedProjects).
+			Update("default_project_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&models.Project{}).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			&models.AIServerSettings{},
+			&models.EmailVerificationToken{},
+			&models.PasswordResetToken{},
+			&models.MagicLinkToken{},
+			&models.EmailChangeToken{},
+			&models.UserAvatar{},
+			&models.UserPreferences{},
+			&models.Job{},
+			&models.AIQuota{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", userId).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("invited_by_id = ?", userId).Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&models.ImpersonationSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AIUsage{}).Where("user_id = ?", userId).Update("user_id", 0).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&models.User{}, userId).Error
+	})
+}
@@ -2,10 +2,14 @@ package repository
 
 import (
 	"SimpleToDo/models"
+	"SimpleToDo/util/secretbox"
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
 )
 
+// AIServerRepository stores the API key encrypted with the secretbox master key. Callers always see
+// the plaintext key.
 type AIServerRepository struct {
 	Db *gorm.DB
 }
@@ -20,22 +24,92 @@ func (r *AIServerRepository) FindByUserID(userID uint) (*models.AIServerSettings
 	if err != nil {
 		return nil, err
 	}
+	if settings.APIKey, err = openAPIKey(&settings); err != nil {
+		return nil, err
+	}
 	return &settings, nil
 }
 
 func (r *AIServerRepository) Save(settings *models.AIServerSettings) error {
+	sealed, err := sealAPIKey(settings.UserID, settings.APIKey)
+	if err != nil {
+		return err
+	}
+
 	var existing models.AIServerSettings
-	err := r.Db.Where("user_id = ?", settings.UserID).First(&existing).Error
+	err = r.Db.Where("user_id = ?", settings.UserID).First(&existing).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return r.Db.Create(settings).Error
+			created := *settings
+			created.APIKey = sealed
+			if err := r.Db.Create(&created).Error; err != nil {
+				return err
+			}
+			settings.ID = created.ID
+			settings.CreatedAt = created.CreatedAt
+			settings.UpdatedAt = created.UpdatedAt
+			return nil
 		}
 		return err
 	}
 
+	existing.Provider = settings.Provider
 	existing.BaseUrl = settings.BaseUrl
-	existing.APIKey = settings.APIKey
+	existing.APIKey = sealed
 	existing.Model = settings.Model
+	existing.APIVersion = settings.APIVersion
 	return r.Db.Save(&existing).Error
 }
+
+// ReEncryptAll seals every stored API key with the active master key, including keys saved in
+// plaintext before encryption was introduced, and returns how many rows were rewritten.
+func (r *AIServerRepository) ReEncryptAll() (int, error) {
+	activeKid, err := secretbox.Default().ActiveKid()
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	var rows []models.AIServerSettings
+	err = r.Db.Unscoped().FindInBatches(&rows, 200, func(tx *gorm.DB, batch int) error {
+		for _, settings := range rows {
+			if secretbox.SealedWith(settings.APIKey) == activeKid {
+				continue
+			}
+			plaintext, err := openAPIKey(&settings)
+			if err != nil {
+				return fmt.Errorf("AI settings %d: %w", settings.ID, err)
+			}
+			sealed, err := sealAPIKey(settings.UserID, plaintext)
+			if err != nil {
+				return err
+			}
+			// UpdateColumn leaves updated_at alone, this is not a change made by the user
+			if err := r.Db.Unscoped().Model(&models.AIServerSettings{}).Where("id = ?", settings.ID).
+				UpdateColumn("api_key", sealed).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	}).Error
+	return updated, err
+}
+
+// apiKeyContext binds a sealed key to its owner, so it cannot be copied to another user's row.
+func apiKeyContext(userID uint) string {
+	return fmt.Sprintf("ai_server_settings.api_key:%d", userID)
+}
+
+func sealAPIKey(userID uint, apiKey string) (string, error) {
+	return secretbox.Default().Seal(apiKey, apiKeyContext(userID))
+}
+
+func openAPIKey(settings *models.AIServerSettings) (string, error) {
+	if !secretbox.IsSealed(settings.APIKey) {
+		// saved before encryption was introduced; sealed on the next start
+		return settings.APIKey, nil
+	}
+	return secretbox.Default().Open(settings.APIKey, apiKeyContext(settings.UserID))
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type AIUsageRepository struct {
+	Db *gorm.DB
+}
+
+func NewAIUsageRepository(db *gorm.DB) *AIUsageRepository {
+	return &AIUsageRepository{Db: db}
+}
+
+// AIUsageRow sums the AI calls of a user with a model on a day.
+type AIUsageRow struct {
+	Day          string
+	UserID       uint
+	Model        string
+	Requests     int64
+	Failures     int64
+	InputTokens  int64
+	OutputTokens int64
+	LatencyMs    int64
+}
+
+// Reserve records the call in usage if check accepts the tokens and calls the user has used since the
+// given time. The user row is locked until the call is recorded, so that concurrent calls of a user are
+// checked one after the other.
+func (r *AIUsageRepository) Reserve(usage *models.AIUsage, since time.Time, check func(tokens, requests int64) error) error {
+	return r.Db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, usage.UserID).Error; err != nil {
+			return err
+		}
+		tokens, requests, err := usageSince(tx, usage.UserID, since)
+		if err != nil {
+			return err
+		}
+		if err := check(tokens, requests); err != nil {
+			return err
+		}
+		return tx.Create(usage).Error
+	})
+}
+
+// Complete records the outcome of a call reserved with Reserve.
+func (r *AIUsageRepository) Complete(usage *models.AIUsage) error {
+	return r.Db.Model(usage).Select("input_tokens", "output_tokens", "latency_ms", "success", "error").Updates(usage).Error
+}
+
+// Report sums the calls from day from to day to, both included, by day, user and model. A userId of 0
+// reports every user.
+func (r *AIUsageRepository) Report(userId uint, from, to string) ([]AIUsageRow, error) {
+	query := r.Db.Model(&models.AIUsage{}).
+		Select("day, user_id, model, COUNT(*) AS requests, SUM(CASE WHEN success THEN 0 ELSE 1 END) AS failures, "+
+			"SUM(input_tokens) AS input_tokens, SUM(output_tokens) AS output_tokens, SUM(latency_ms) AS latency_ms").
+		Where("day BETWEEN ? AND ?", from, to)
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	var rows []AIUsageRow
+	err := query.Group("day, user_id, model").Order("day, user_id, model").Scan(&rows).Error
+	return rows, err
+}
+
+// UsageSince returns the tokens and calls the user has used since the given time.
+func (r *AIUsageRepository) UsageSince(userId uint, since time.Time) (tokens, requests int64, err error) {
+	return usageSince(r.Db, userId, since)
+}
+
+func usageSince(db *gorm.DB, userId uint, since time.Time) (tokens, requests int64, err error) {
+	var totals struct {
+		Tokens   int64
+		Requests int64
+	}
+	err = db.Model(&models.AIUsage{}).
+		Select("COALESCE(SUM(input_tokens + output_tokens), 0) AS tokens, COUNT(*) AS requests").
+		Where("user_id = ? AND created_at >= ?", userId, since).Scan(&totals).Error
+	return totals.Tokens, totals.Requests, err
+}
+
+// FindQuota returns the quota of the user, or gorm.ErrRecordNotFound when they have none of their own.
+func (r *AIUsageRepository) FindQuota(userId uint) (*models.AIQuota, error) {
+	var quota models.AIQuota
+	if err := r.Db.Where("user_id = ?", userId).First(&quota).Error; err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+func (r *AIUsageRepository) FindQuotas() ([]models.AIQuota, error) {
+	var quotas []models.AIQuota
+	err := r.Db.Order("user_id").Find(&quotas).Error
+	return quotas, err
+}
+
+// SaveQuota creates or replaces the quota of the user.
+func (r *AIUsageRepository) SaveQuota(quota *models.AIQuota) error {
+	return r.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"monthly_tokens", "monthly_requests", "updated_at"}),
+	}).Create(quota).Error
+}
+
+func (r *AIUsageRepository) DeleteQuota(userId uint) (int64, error) {
+	result := r.Db.Where("user_id = ?", userId).Delete(&models.AIQuota{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *AIUsageRepository) FindPrices() ([]models.AIModelPrice, error) {
+	var prices []models.AIModelPrice
+	err := r.Db.Order("model").Find(&prices).Error
+	return prices, err
+}
+
+// SavePrice creates or replaces the price of the model.
+func (r *AIUsageRepository) SavePrice(price *models.AIModelPrice) error {
+	return r.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{"input_per_million", "output_per_million", "updated_at"}),
+	}).Create(price).Error
+}
+
+func (r *AIUsageRepository) DeletePrice(model string) (int64, error) {
+	result := r.Db.Where("model = ?", model).Delete(&models.AIModelPrice{})
+	return result.RowsAffected, result.Error
+}
@@ -4,12 +4,15 @@ import (
 	"SimpleToDo/models"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var ErrEmailTaken = errors.New("email already in use")
+
 type AuthRepository struct {
 	Db *gorm.DB
 }
@@ -81,6 +84,11 @@ func (r *AuthRepository) UpdateUserPassword(userID uint, newPlain string) error
 	return r.Db.Model(&models.User{}).Where("id = ?", userID).Update("password", string(hashed)).Error
 }
 
+// RevokeUserTokens invalidates every JWT of the user issued before at.
+func (r *AuthRepository) RevokeUserTokens(userID uint, at time.Time) error {
+	return r.Db.Model(&models.User{}).Where("id = ?", userID).Update("tokens_valid_after", at).Error
+}
+
 func (r *AuthRepository) CreateEmailVerificationToken(userID uint, tokenPlain string, ttl time.Duration) (*models.EmailVerificationToken, error) {
 	hash, _ := getPasswordHash(tokenPlain)
 
@@ -115,6 +123,114 @@ func (r *AuthRepository) MarkEmailVerificationTokenUsed(id uint) error {
 	return r.Db.Model(&models.EmailVerificationToken{}).Where("id = ?", id).Update("used", true).Error
 }
 
+func (r *AuthRepository) CreateMagicLinkToken(userID uint, tokenPlain string, ttl time.Duration) (*models.MagicLinkToken, error) {
+	hash, _ := getPasswordHash(tokenPlain)
+
+	// invalidate previous active links for user
+	_ = r.Db.Model(&models.MagicLinkToken{}).
+		Where("user_id = ? AND used = ? AND expires_at > ?", userID, false, time.Now()).
+		Update("used", true).Error
+
+	item := &models.MagicLinkToken{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+		Used:      false,
+	}
+	if err := r.Db.Create(item).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ConsumeMagicLinkToken marks the token as used and returns it. The conditional update makes sure that
+// two concurrent requests with the same link cannot both succeed.
+func (r *AuthRepository) ConsumeMagicLinkToken(tokenPlain string) (*models.MagicLinkToken, error) {
+	hash, _ := getPasswordHash(tokenPlain)
+
+	var t models.MagicLinkToken
+	if err := r.Db.Where("token_hash = ?", hash).First(&t).Error; err != nil {
+		return nil, err
+	}
+	res := r.Db.Model(&models.MagicLinkToken{}).
+		Where("id = ? AND used = ? AND expires_at > ?", t.ID, false, time.Now()).
+		Update("used", true)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &t, nil
+}
+
+func (r *AuthRepository) CreateEmailChangeToken(userID uint, newEmail, tokenPlain string, ttl time.Duration) (*models.EmailChangeToken, error) {
+	hash, _ := getPasswordHash(tokenPlain)
+
+	// only the latest requested address can be confirmed
+	_ = r.Db.Model(&models.EmailChangeToken{}).
+		Where("user_id = ? AND used = ? AND expires_at > ?", userID, false, time.Now()).
+		Update("used", true).Error
+
+	item := &models.EmailChangeToken{
+		UserID:    userID,
+		NewEmail:  newEmail,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+		Used:      false,
+	}
+	if err := r.Db.Create(item).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ConsumeEmailChangeToken marks the token as used, switches the user's email and revokes their tokens in
+// one transaction.
+func (r *AuthRepository) ConsumeEmailChangeToken(tokenPlain string) (*models.EmailChangeToken, error) {
+	hash, _ := getPasswordHash(tokenPlain)
+
+	var t models.EmailChangeToken
+	err := r.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", hash).First(&t).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&models.EmailChangeToken{}).
+			Where("id = ? AND used = ? AND expires_at > ?", t.ID, false, time.Now()).
+			Update("used", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("LOWER(email) = LOWER(?) AND id <> ?", t.NewEmail, t.UserID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrEmailTaken
+		}
+		// tokens carry the email, so the ones issued for the old address are revoked
+		return tx.Model(&models.User{}).Where("id = ?", t.UserID).Updates(map[string]interface{}{
+			"email":              t.NewEmail,
+			"tokens_valid_after": time.Now().Truncate(time.Second),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *AuthRepository) FindById(id uint) *models.User {
+	var user models.User
+	if err := r.Db.First(&user, id).Error; err != nil {
+		return nil
+	}
+	return &user
+}
+
 func (r *AuthRepository) MarkUserVerified(userID uint) error {
 	return r.Db.Model(&models.User{}).Where("id = ?", userID).Update("verified", true).Error
 }
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"gorm.io/gorm"
+)
+
+type AvatarRepository struct {
+	Db *gorm.DB
+}
+
+func NewAvatarRepository(db *gorm.DB) *AvatarRepository {
+	return &AvatarRepository{Db: db}
+}
+
+func (r *AvatarRepository) Find(userId uint, size int) (*models.UserAvatar, error) {
+	var avatar models.UserAvatar
+	err := r.Db.Where("user_id = ? AND size = ?", userId, size).First(&avatar).Error
+	if err != nil {
+		return nil, err
+	}
+	return &avatar, nil
+}
+
+func (r *AvatarRepository) FindVersion(userId uint) (string, error) {
+	var user models.User
+	err := r.Db.Select("id", "avatar_version").First(&user, userId).Error
+	return user.AvatarVersion, err
+}
+
+// Replace swaps every rendition of the user's avatar for avatars and records the new version. The
+// legacy image column is cleared along the way.
+func (r *AvatarRepository) Replace(userId uint, avatars []models.UserAvatar, version string) error {
+	return r.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&models.UserAvatar{}).Error; err != nil {
+			return err
+		}
+		if len(avatars) > 0 {
+			if err := tx.Create(&avatars).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userId).
+			Updates(map[string]interface{}{"avatar_version": version, "image": nil}).Error
+	})
+}
+
+func (r *AvatarRepository) Delete(userId uint) error {
+	return r.Replace(userId, nil, "")
+}
+
+// FindLegacyImageOwners returns the ids of users whose avatar is still stored in the image column.
+func (r *AvatarRepository) FindLegacyImageOwners() ([]uint, error) {
+	var ids []uint
+	err := r.Db.Model(&models.User{}).Where("image IS NOT NULL AND avatar_version = ''").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *AvatarRepository) FindLegacyImage(userId uint) ([]byte, error) {
+	var user models.User
+	err := r.Db.Select("id", "image").First(&user, userId).Error
+	return user.Image, err
+}
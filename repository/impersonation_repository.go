@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImpersonationRepository struct {
+	Db *gorm.DB
+}
+
+func NewImpersonationRepository(db *gorm.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{Db: db}
+}
+
+func (r *ImpersonationRepository) Save(session *models.ImpersonationSession) error {
+	return r.Db.Create(session).Error
+}
+
+func (r *ImpersonationRepository) FindById(id uint) (*models.ImpersonationSession, error) {
+	var session models.ImpersonationSession
+	if err := r.Db.First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *ImpersonationRepository) FindAll(pagination response.Pagination) (*response.Pagination, error) {
+	if r.Db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+	var sessions []*models.ImpersonationSession
+	result := r.Db.Scopes(Paginate(&models.ImpersonationSession{}, &pagination, r.Db)).
+		Preload("User").Find(&sessions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	pagination.Items = sessions
+	return &pagination, nil
+}
+
+func (r *ImpersonationRepository) End(id uint) error {
+	return r.Db.Model(&models.ImpersonationSession{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Update("ended_at", time.Now()).Error
+}
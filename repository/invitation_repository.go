@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InvitationRepository struct {
+	Db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{Db: db}
+}
+
+func (r *InvitationRepository) Create(invitation *models.Invitation, tokenPlain string) error {
+	hash, _ := getPasswordHash(tokenPlain)
+
+	// a new invitation replaces any pending one for the same address
+	_ = r.Db.Model(&models.Invitation{}).
+		Where("LOWER(email) = LOWER(?) AND used = ? AND expires_at > ?", invitation.Email, false, time.Now()).
+		Update("used", true).Error
+
+	invitation.TokenHash = hash
+	return r.Db.Create(invitation).Error
+}
+
+// FindPendingByToken returns the invitation only while it can still be accepted.
+func (r *InvitationRepository) FindPendingByToken(tokenPlain string) (*models.Invitation, error) {
+	hash, _ := getPasswordHash(tokenPlain)
+
+	var invitation models.Invitation
+	err := r.Db.Preload("Project").
+		Where("token_hash = ? AND used = ? AND expires_at > ?", hash, false, time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *InvitationRepository) FindById(id uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.Db.First(&invitation, id).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *InvitationRepository) FindAll(pagination response.Pagination) (*response.Pagination, error) {
+	if r.Db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+	var invitations []*models.Invitation
+	result := r.Db.Scopes(Paginate(&models.Invitation{}, &pagination, r.Db)).
+		Preload("Project").Preload("InvitedBy").Find(&invitations)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	pagination.Items = invitations
+	return &pagination, nil
+}
+
+// MarkAccepted consumes the invitation. It fails when another registration already used it.
+func (r *InvitationRepository) MarkAccepted(id uint) error {
+	now := time.Now()
+	res := r.Db.Model(&models.Invitation{}).
+		Where("id = ? AND used = ?", id, false).
+		Updates(map[string]interface{}{"used": true, "accepted_at": &now})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *InvitationRepository) Revoke(id uint) error {
+	return r.Db.Model(&models.Invitation{}).Where("id = ?", id).Update("used", true).Error
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type JobRepository struct {
+	Db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{Db: db}
+}
+
+func (r *JobRepository) Create(job *models.Job) error {
+	return r.Db.Create(job).Error
+}
+
+// FindByIdForUser returns the job if it belongs to the user, or gorm.ErrRecordNotFound.
+func (r *JobRepository) FindByIdForUser(id, userId uint) (*models.Job, error) {
+	var job models.Job
+	if err := r.Db.Where("id = ? AND user_id = ?", id, userId).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimNext marks the oldest queued job of a user running fewer than perUser jobs as running and returns
+// it, or nil when there is none.
+func (r *JobRepository) ClaimNext(perUser int) (*models.Job, error) {
+	busyUsers := r.Db.Model(&models.Job{}).Select("user_id").Where("status = ?", models.JobStatusRunning).
+		Group("user_id").Having("COUNT(*) >= ?", perUser)
+
+	// Find rather than First, as an empty queue is the usual case and not worth logging
+	var jobs []models.Job
+	err := r.Db.Where("status = ? AND user_id NOT IN (?)", models.JobStatusQueued, busyUsers).Order("id").Limit(1).Find(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	job := jobs[0]
+
+	now := time.Now()
+	result := r.Db.Model(&models.Job{}).Where("id = ? AND status = ?", job.ID, models.JobStatusQueued).
+		Updates(map[string]interface{}{"status": models.JobStatusRunning, "started_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// claimed by another runner in the meantime
+		return nil, nil
+	}
+	job.Status = models.JobStatusRunning
+	job.StartedAt = &now
+	return &job, nil
+}
+
+// Finish records the outcome of a running job and drops its payload, which may hold a large image, and
+// its checkpoint.
+func (r *JobRepository) Finish(id uint, status, result, errorMessage string) error {
+	return r.Db.Model(&models.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":      status,
+		"result":      result,
+		"error":       errorMessage,
+		"payload":     "",
+		"checkpoint":  "",
+		"finished_at": time.Now(),
+	}).Error
+}
+
+// RequeueRunning puts the jobs started before the given time and still running back in the queue. As a
+// job is cancelled once it ran for its timeout, these were interrupted by a restart.
+func (r *JobRepository) RequeueRunning(startedBefore time.Time) (int64, error) {
+	result := r.Db.Model(&models.Job{}).Where("status = ? AND started_at < ?", models.JobStatusRunning, startedBefore).
+		Updates(map[string]interface{}{"status": models.JobStatusQueued, "started_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteFinishedBefore removes the jobs that finished before the given time.
+func (r *JobRepository) DeleteFinishedBefore(before time.Time) (int64, error) {
+	result := r.Db.Where("finished_at < ?", before).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
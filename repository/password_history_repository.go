@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository struct {
+	Db *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{Db: db}
+}
+
+func (r *PasswordHistoryRepository) FindLatest(userID uint, limit int) ([]models.PasswordHistory, error) {
+	var items []models.PasswordHistory
+	err := r.Db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+// Add stores the hash and removes everything older than the latest keep entries.
+func (r *PasswordHistoryRepository) Add(userID uint, passwordHash string, keep int) error {
+	return r.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+			return err
+		}
+		var keepIds []uint
+		if err := tx.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).
+			Order("created_at DESC, id DESC").Limit(keep).Pluck("id", &keepIds).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id NOT IN ?", userID, keepIds).Delete(&models.PasswordHistory{}).Error
+	})
+}
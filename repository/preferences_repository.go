@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"errors"
+	"gorm.io/gorm"
+)
+
+type PreferencesRepository struct {
+	Db *gorm.DB
+}
+
+func NewPreferencesRepository(db *gorm.DB) *PreferencesRepository {
+	return &PreferencesRepository{Db: db}
+}
+
+// FindByUserID returns the user's preferences, or the defaults when they never saved any.
+func (r *PreferencesRepository) FindByUserID(userID uint) (*models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	err := r.Db.Where("user_id = ?", userID).First(&prefs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.UserPreferences{
+			UserID:          userID,
+			Timezone:        "UTC",
+			Locale:          "en",
+			WeekStart:       "monday",
+			DateFormat:      "YYYY-MM-DD",
+			DefaultTaskSort: "oldest",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (r *PreferencesRepository) Save(prefs *models.UserPreferences) error {
+	var existing models.UserPreferences
+	err := r.Db.Where("user_id = ?", prefs.UserID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.Db.Create(prefs).Error
+	}
+	if err != nil {
+		return err
+	}
+	prefs.ID = existing.ID
+	prefs.CreatedAt = existing.CreatedAt
+	return r.Db.Save(prefs).Error
+}
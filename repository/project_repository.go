@@ -5,6 +5,7 @@ import (
 	"SimpleToDo/models"
 	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProjectRepository struct {
@@ -63,6 +64,22 @@ func (p *ProjectRepository) Delete(id int) error {
 		return result.Error
 	}
 
+	result = p.Db.Where("project_id = ?", id).Delete(&models.ProjectMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = p.Db.Where("project_id = ?", id).Delete(&models.ProjectSummary{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	result = p.Db.Model(&models.UserPreferences{}).Where("default_project_id = ?", id).
+		Update("default_project_id", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+
 	result = p.Db.Delete(&models.Project{}, id)
 	if result.Error != nil {
 		return result.Error
@@ -89,11 +106,12 @@ func (p *ProjectRepository) FindAllByUserId(pagination response.Pagination, user
 	}
 	var projects []*models.Project
 
-	condition1 := response.NewCondition("user_id", response.Equal, userId, response.Empty)
-	conditions := []response.Condition{*condition1}
+	// owned projects plus the ones the user was added to as a member
+	memberOf := p.Db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userId)
+	scoped := p.Db.Where("user_id = ? OR id IN (?)", userId, memberOf)
 
-	result := p.Db.Where(condition1.ToQueryStringWithValue()).
-		Scopes(PaginateWithConditions(&models.Project{}, conditions, &pagination, p.Db)).
+	result := scoped.
+		Scopes(Paginate(&models.Project{}, &pagination, scoped.Session(&gorm.Session{}))).
 		Preload("Tasks").
 		Find(&projects)
 
@@ -106,6 +124,17 @@ func (p *ProjectRepository) FindAllByUserId(pagination response.Pagination, user
 	return &pagination, nil
 }
 
+// FindByNameForUser returns the project with the given name, ignoring case, among the ones the user owns
+// or is a member of, preferring owned ones.
+func (p *ProjectRepository) FindByNameForUser(userId int, name string) (models.Project, error) {
+	var project models.Project
+	memberOf := p.Db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userId)
+	err := p.Db.Where("(user_id = ? OR id IN (?)) AND LOWER(name) = LOWER(?)", userId, memberOf, name).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "user_id = ? DESC, id", Vars: []any{userId}}}).
+		First(&project).Error
+	return project, err
+}
+
 func (p *ProjectRepository) FindAll(pagination response.Pagination) (*response.Pagination, error) {
 	if p.Db == nil {
 		return nil, errors.New("database connection is nil")
@@ -119,3 +148,18 @@ func (p *ProjectRepository) FindAll(pagination response.Pagination) (*response.P
 
 	return &pagination, nil
 }
+
+// CanUse reports whether the user owns the project or is a member of it.
+func (p *ProjectRepository) CanUse(userId uint, projectId uint) (bool, error) {
+	var count int64
+	memberOf := p.Db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userId)
+	err := p.Db.Model(&models.Project{}).
+		Where("id = ? AND (user_id = ? OR id IN (?))", projectId, userId, memberOf).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (p *ProjectRepository) AddMember(projectId uint, userId uint) error {
+	return p.Db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ProjectMember{ProjectId: projectId, UserId: userId}).Error
+}
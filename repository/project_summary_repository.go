@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ProjectSummaryRepository struct {
+	Db *gorm.DB
+}
+
+func NewProjectSummaryRepository(db *gorm.DB) *ProjectSummaryRepository {
+	return &ProjectSummaryRepository{Db: db}
+}
+
+// FindByProjectId returns the cached summary of the project, or gorm.ErrRecordNotFound.
+func (r *ProjectSummaryRepository) FindByProjectId(projectId uint) (*models.ProjectSummary, error) {
+	var summary models.ProjectSummary
+	if err := r.Db.Where("project_id = ?", projectId).First(&summary).Error; err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Save replaces the cached summary of the project.
+func (r *ProjectSummaryRepository) Save(summary *models.ProjectSummary) error {
+	return r.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "generated_by", "updated_at"}),
+	}).Create(summary).Error
+}
+
+// LastTaskChange returns when a task of the project was last created, updated or deleted, or nil when
+// the project never had tasks.
+func (r *ProjectSummaryRepository) LastTaskChange(projectId uint) (*time.Time, error) {
+	var task models.Task
+	err := r.Db.Unscoped().Where("project_id = ?", projectId).
+		Order("COALESCE(deleted_at, updated_at) DESC").First(&task).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	last := task.UpdatedAt
+	if task.DeletedAt.Valid && task.DeletedAt.Time.After(last) {
+		last = task.DeletedAt.Time
+	}
+	return &last, nil
+}
@@ -35,12 +35,22 @@ func (r *PromptRepository) Delete(id uint) error {
 		return result.Error
 	}
 
-	result = r.Db.Delete(&models.Prompt{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
+	return r.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.Prompt{}, id).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.UserPreferences{}).Where("default_prompt_id = ?", id).
+			Update("default_prompt_id", nil).Error
+	})
+}
 
-	return nil
+// FindByIdAndPurpose returns the prompt if it has the given purpose, or gorm.ErrRecordNotFound.
+func (r *PromptRepository) FindByIdAndPurpose(id uint, purpose string) (*models.Prompt, error) {
+	var p models.Prompt
+	if err := r.Db.Where("purpose = ?", purpose).First(&p, id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
 }
 
 func (r *PromptRepository) FindById(id uint) (*models.Prompt, error) {
@@ -51,15 +61,46 @@ func (r *PromptRepository) FindById(id uint) (*models.Prompt, error) {
 	return &p, nil
 }
 
-func (r *PromptRepository) FindAll(pagination response.Pagination) (*response.Pagination, error) {
+// FindAll lists the prompts with the given purpose, or all of them when purpose is empty.
+func (r *PromptRepository) FindAll(pagination response.Pagination, purpose string) (*response.Pagination, error) {
 	if r.Db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 	var prompts []*models.Prompt
-	result := r.Db.Scopes(Paginate(&models.Prompt{}, &pagination, r.Db)).Find(&prompts)
+	scoped := r.Db
+	if purpose != "" {
+		scoped = r.Db.Where("purpose = ?", purpose)
+	}
+	result := scoped.Scopes(Paginate(&models.Prompt{}, &pagination, scoped.Session(&gorm.Session{}))).Find(&prompts)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	pagination.Items = prompts
 	return &pagination, nil
 }
+
+// SetDefault marks the prompt as the default for its purpose and unmarks any other prompt with that
+// purpose, or returns gorm.ErrRecordNotFound.
+func (r *PromptRepository) SetDefault(id uint) error {
+	return r.Db.Transaction(func(tx *gorm.DB) error {
+		var prompt models.Prompt
+		if err := tx.First(&prompt, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Prompt{}).Where("is_default AND purpose = ? AND id <> ?", prompt.Purpose, id).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Prompt{}).Where("id = ?", id).Update("is_default", true).Error
+	})
+}
+
+// FindDefault returns the prompt with the purpose marked as default or, when none is, the oldest prompt
+// with the purpose, so that adding prompts never changes which one is used.
+func (r *PromptRepository) FindDefault(purpose string) (*models.Prompt, error) {
+	var p models.Prompt
+	if err := r.Db.Where("purpose = ?", purpose).Order("is_default desc, id asc").First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
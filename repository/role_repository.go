@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"errors"
+	"gorm.io/gorm"
+)
+
+type RoleRepository struct {
+	Db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{Db: db}
+}
+
+func (r *RoleRepository) Save(role *models.Role) error {
+	return r.Db.Create(role).Error
+}
+
+func (r *RoleRepository) FindById(id uint) (*models.Role, error) {
+	var role models.Role
+	if err := r.Db.Preload("Permissions").First(&role, id).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) FindAll(pagination response.Pagination) (*response.Pagination, error) {
+	if r.Db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+	var roles []*models.Role
+	result := r.Db.Scopes(Paginate(&models.Role{}, &pagination, r.Db)).Preload("Permissions").Find(&roles)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	pagination.Items = roles
+	return &pagination, nil
+}
+
+func (r *RoleRepository) FindAllPermissions(pagination response.Pagination) (*response.Pagination, error) {
+	if r.Db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+	var permissions []*models.Permission
+	result := r.Db.Scopes(Paginate(&models.Permission{}, &pagination, r.Db)).Find(&permissions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	pagination.Items = permissions
+	return &pagination, nil
+}
+
+func (r *RoleRepository) FindPermissionsByNames(names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	if err := r.Db.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *RoleRepository) ReplacePermissions(role *models.Role, permissions []models.Permission) error {
+	return r.Db.Model(role).Association("Permissions").Replace(permissions)
+}
+
+func (r *RoleRepository) FindPermissionNamesByRoleId(roleId uint) ([]string, error) {
+	var names []string
+	err := r.Db.Model(&models.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ?", roleId).
+		Order("permissions.name asc").
+		Pluck("permissions.name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *RoleRepository) HasPermission(roleId uint, permission string) (bool, error) {
+	var count int64
+	err := r.Db.Model(&models.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ? AND permissions.name = ?", roleId, permission).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// SecurityEventRepository only appends and reads; events are never updated or deleted.
+type SecurityEventRepository struct {
+	Db *gorm.DB
+}
+
+func NewSecurityEventRepository(db *gorm.DB) *SecurityEventRepository {
+	return &SecurityEventRepository{Db: db}
+}
+
+func (r *SecurityEventRepository) Save(event *models.SecurityEvent) error {
+	return r.Db.Create(event).Error
+}
+
+func (r *SecurityEventRepository) FindAll(conditions []response.Condition, pagination response.Pagination) (*response.Pagination, error) {
+	if r.Db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+	var events []*models.SecurityEvent
+	query := r.Db
+	if len(conditions) > 0 {
+		queryStr, args := response.ToQueryStringMany(conditions)
+		query = query.Where(queryStr, args...)
+	}
+	result := query.Scopes(PaginateWithConditions(&models.SecurityEvent{}, conditions, &pagination, r.Db)).Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	pagination.Items = events
+	return &pagination, nil
+}
+
+// FindEach calls fn with consecutive batches of the matching events, newest first.
+func (r *SecurityEventRepository) FindEach(conditions []response.Condition, fn func([]*models.SecurityEvent) error) error {
+	var batch []*models.SecurityEvent
+	query := r.Db.Order("id desc")
+	if len(conditions) > 0 {
+		queryStr, args := response.ToQueryStringMany(conditions)
+		query = query.Where(queryStr, args...)
+	}
+	return query.FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
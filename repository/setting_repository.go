@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SettingRepository struct {
+	Db *gorm.DB
+}
+
+func NewSettingRepository(db *gorm.DB) *SettingRepository {
+	return &SettingRepository{Db: db}
+}
+
+// Get returns the stored value for name, or def when it has never been set.
+func (r *SettingRepository) Get(name, def string) (string, error) {
+	var setting models.Setting
+	if err := r.Db.Where("name = ?", name).First(&setting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return def, nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+func (r *SettingRepository) Set(name, value string) error {
+	return r.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&models.Setting{Name: name, Value: value}).Error
+}
@@ -3,9 +3,11 @@ package repository
 import (
 	"SimpleToDo/dto/response"
 	"SimpleToDo/models"
+	"encoding/json"
 	"errors"
 	"gorm.io/gorm"
 	"strings"
+	"time"
 )
 
 type TaskRepository struct {
@@ -32,12 +34,51 @@ func (t *TaskRepository) Save(taskToCreate models.Task) (task models.Task, err e
 	return taskToCreate, nil
 }
 
+// SaveAll creates the tasks in the project in a single transaction.
+func (t *TaskRepository) SaveAll(tasksToCreate []models.Task, projectId uint) ([]models.Task, error) {
+	return t.SaveAllForJob(tasksToCreate, projectId, 0)
+}
+
+// SaveAllForJob is SaveAll for a job, unless jobId is 0. The ids of the tasks are recorded as the
+// checkpoint of the job in the same transaction, so that a rerun of the job can tell they exist.
+func (t *TaskRepository) SaveAllForJob(tasksToCreate []models.Task, projectId, jobId uint) ([]models.Task, error) {
+	if t.Db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+	err := t.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Project{}).Where("id = ?", projectId).First(&models.Project{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&tasksToCreate).Error; err != nil {
+			return err
+		}
+		if jobId == 0 {
+			return nil
+		}
+		ids := make([]uint, 0, len(tasksToCreate))
+		for _, task := range tasksToCreate {
+			ids = append(ids, task.ID)
+		}
+		checkpoint, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Job{}).Where("id = ?", jobId).Update("checkpoint", string(checkpoint)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasksToCreate, nil
+}
+
 func (t *TaskRepository) Update(taskToUpdate models.Task, id int) (models.Task, error) {
 	if t.Db == nil {
 		return models.Task{}, errors.New("database connection is nil")
 	}
 
-	result := t.Db.Model(&models.Task{}).Where("id = ?", id).Updates(taskToUpdate)
+	// the due date and priority are listed so that empty ones clear them
+	result := t.Db.Model(&models.Task{}).Where("id = ?", id).
+		Select("title", "description", "status_id", "due_date", "priority").Updates(taskToUpdate)
 
 	if result.Error != nil {
 		return models.Task{}, result.Error
@@ -87,12 +128,53 @@ func (t *TaskRepository) FindById(id int) (task models.Task, err error) {
 	return taskToReturn, nil
 }
 
-func (t *TaskRepository) FindAll(pagination response.Pagination, userId int) (*response.Pagination, error) {
+// FindByIds returns the tasks with their status, in the order of the ids. Deleted tasks are left out.
+func (t *TaskRepository) FindByIds(ids []uint) ([]models.Task, error) {
+	var found []models.Task
+	if err := t.Db.Preload("Status").Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byId := make(map[uint]models.Task, len(found))
+	for _, task := range found {
+		byId[task.ID] = task
+	}
+	tasks := make([]models.Task, 0, len(found))
+	for _, id := range ids {
+		if task, ok := byId[id]; ok {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// FindByProject returns every task of the project with its status, most recently changed first, and
+// the tasks deleted from it since the given time.
+func (t *TaskRepository) FindByProject(projectId uint, deletedSince time.Time) (tasks []models.Task, deleted []models.Task, err error) {
+	if err = t.Db.Preload("Status").Where("project_id = ?", projectId).Order("updated_at DESC").Find(&tasks).Error; err != nil {
+		return nil, nil, err
+	}
+	err = t.Db.Unscoped().Where("project_id = ? AND deleted_at >= ?", projectId, deletedSince).
+		Order("deleted_at DESC").Find(&deleted).Error
+	return tasks, deleted, err
+}
+
+func (t *TaskRepository) FindAll(pagination response.Pagination, userId int, dueConditions []response.Condition) (*response.Pagination, error) {
 	if t.Db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 	var tasks []*models.Task
-	result := t.Db.Where("user_id = ?", userId).Scopes(Paginate(&models.Task{}, &pagination, t.Db)).Preload("Status").Find(&tasks)
+	conditions := append([]response.Condition{{
+		Column:   "user_id",
+		Operator: response.Equal,
+		Value:    userId,
+		Modifier: response.And,
+	}}, dueConditions...)
+	queryStr, args := response.ToQueryStringMany(conditions)
+
+	result := t.Db.Where(queryStr, args...).
+		Scopes(PaginateWithConditions(&models.Task{}, conditions, &pagination, t.Db)).
+		Preload("Status").
+		Find(&tasks)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -101,12 +183,12 @@ func (t *TaskRepository) FindAll(pagination response.Pagination, userId int) (*r
 	return &pagination, nil
 }
 
-func (t *TaskRepository) FindAllByProjectId(pagination response.Pagination, projectId int, userId int, taskTitle string, statusId int) (*response.Pagination, error) {
+func (t *TaskRepository) FindAllByProjectId(pagination response.Pagination, projectId int, userId int, taskTitle string, statusId int, dueConditions []response.Condition) (*response.Pagination, error) {
 	if t.Db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 	var tasks []*models.Task
-	conditions := getConditionsByParams(taskTitle, statusId, projectId, userId)
+	conditions := append(getConditionsByParams(taskTitle, statusId, projectId, userId), dueConditions...)
 	queryStr, args := response.ToQueryStringMany(conditions)
 
 	result := t.Db.Where(queryStr, args...).
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"SimpleToDo/models"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ThrottleRepository struct {
+	Db *gorm.DB
+}
+
+func NewThrottleRepository(db *gorm.DB) *ThrottleRepository {
+	return &ThrottleRepository{Db: db}
+}
+
+// FindOrInit returns the throttle row for the given subject, or an unsaved zero row when none exists yet.
+func (r *ThrottleRepository) FindOrInit(subject string) (*models.AuthThrottle, error) {
+	var t models.AuthThrottle
+	err := r.Db.Where("subject = ?", subject).First(&t).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.AuthThrottle{Subject: subject}, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Hit counts one request for the subject in a single statement, so that concurrent requests are all
+// counted. The window restarts at now when it began at or before expired. It returns the row after the hit.
+func (r *ThrottleRepository) Hit(subject string, now, expired time.Time) (*models.AuthThrottle, error) {
+	var t models.AuthThrottle
+	err := r.Db.Raw(`INSERT INTO auth_throttles (subject, hits, window_start, failures, last_failure_at, locked_until, created_at, updated_at)
+VALUES (?, 1, ?, 0, ?, ?, ?, ?)
+ON CONFLICT (subject) DO UPDATE SET
+	hits = CASE WHEN auth_throttles.window_start <= ? THEN 1 ELSE auth_throttles.hits + 1 END,
+	window_start = CASE WHEN auth_throttles.window_start <= ? THEN ? ELSE auth_throttles.window_start END,
+	updated_at = ?
+RETURNING *`, subject, now, time.Time{}, time.Time{}, now, now, expired, expired, now, now).Scan(&t).Error
+	return &t, err
+}
+
+// RegisterFailure counts one failure for the subject in a single statement, starting over when the last
+// one was at or before expired. It returns the row after the failure.
+func (r *ThrottleRepository) RegisterFailure(subject string, now, expired time.Time) (*models.AuthThrottle, error) {
+	var t models.AuthThrottle
+	err := r.Db.Raw(`INSERT INTO auth_throttles (subject, hits, window_start, failures, last_failure_at, locked_until, created_at, updated_at)
+VALUES (?, 0, ?, 1, ?, ?, ?, ?)
+ON CONFLICT (subject) DO UPDATE SET
+	failures = CASE WHEN auth_throttles.last_failure_at <= ? THEN 1 ELSE auth_throttles.failures + 1 END,
+	last_failure_at = ?,
+	updated_at = ?
+RETURNING *`, subject, time.Time{}, now, time.Time{}, now, now, expired, now, now).Scan(&t).Error
+	return &t, err
+}
+
+// LockUntil locks the subject until the given time, unless it already is for longer.
+func (r *ThrottleRepository) LockUntil(subject string, until time.Time) error {
+	return r.Db.Model(&models.AuthThrottle{}).
+		Where("subject = ? AND locked_until < ?", subject, until).
+		Update("locked_until", until).Error
+}
+
+func (r *ThrottleRepository) DeleteBySubject(subject string) error {
+	return r.Db.Where("subject = ?", subject).Delete(&models.AuthThrottle{}).Error
+}
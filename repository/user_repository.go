@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"time"
 )
 
 type UserRepository struct {
@@ -51,3 +52,39 @@ func (r *UserRepository) Update(user *models.User) (*models.User, error) {
 func (r *UserRepository) Delete(id uint) error {
 	return r.Db.Delete(&models.User{}, id).Error
 }
+
+func (r *UserRepository) UpdateRole(id uint, roleId uint) error {
+	return r.Db.Model(&models.User{}).Where("id = ?", id).Update("role_id", roleId).Error
+}
+
+func (r *UserRepository) ExistsByEmail(email string) (bool, error) {
+	var count int64
+	if err := r.Db.Model(&models.User{}).Where("LOWER(email) = LOWER(?)", email).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindSessionState loads only the columns needed to decide whether the user's tokens are still accepted.
+func (r *UserRepository) FindSessionState(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.Db.Select("id", "email", "role_id", "tokens_valid_after", "suspended").First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) SetSuspended(id uint, suspended bool) error {
+	updates := map[string]interface{}{"suspended": suspended, "suspended_at": nil}
+	if suspended {
+		now := time.Now()
+		updates["suspended_at"] = &now
+		// tokens issued before the suspension stay revoked after reactivation
+		updates["tokens_valid_after"] = now.Truncate(time.Second)
+	}
+	return r.Db.Model(&models.User{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *UserRepository) MarkVerified(id uint) error {
+	return r.Db.Model(&models.User{}).Where("id = ?", id).Update("verified", true).Error
+}
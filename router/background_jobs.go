@@ -0,0 +1,41 @@
+package router
+
+import (
+	"SimpleToDo/config"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"SimpleToDo/util/mapper"
+	"gorm.io/gorm"
+	"time"
+)
+
+// StartBackgroundJobs launches the periodic maintenance tasks that run alongside the HTTP server.
+func StartBackgroundJobs(db *gorm.DB) {
+	throttleService := service.NewThrottleService(repository.NewThrottleRepository(db))
+	passwordPolicyService := service.NewPasswordPolicyService(repository.NewPasswordHistoryRepository(db))
+	auditService := service.NewAuditService(repository.NewSecurityEventRepository(db))
+	preferencesService := service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db))
+	authService := service.NewAuthService(repository.NewAuthRepository(db), throttleService, passwordPolicyService, auditService,
+		preferencesService)
+	accountService := service.NewAccountService(repository.NewAccountRepository(db), repository.NewUserRepository(db),
+		repository.NewAIServerRepository(db), repository.NewRoleRepository(db), authService,
+		mapper.NewUserMapperImpl(), mapper.NewProjectMapperImpl(), mapper.NewTaskMapperImpl())
+
+	// purge accounts whose deletion grace period is over
+	go accountService.RunDeletionPurger(time.Hour)
+
+	taskService := service.NewTaskService(repository.NewTaskRepository(db), repository.NewStatusRepository(db),
+		repository.NewProjectRepository(db), nil, preferencesService)
+	jobRepo := repository.NewJobRepository(db)
+	visionService := service.NewVisionService(repository.NewAIServerRepository(db), repository.NewPromptRepository(db),
+		repository.NewProjectRepository(db), taskService, preferencesService, service.NewJobService(jobRepo),
+		service.NewAIUsageService(repository.NewAIUsageRepository(db), repository.NewSettingRepository(db), repository.NewUserRepository(db)))
+
+	// run the AI jobs queued by async requests
+	env := config.GetAppEnv()
+	jobRunner := service.NewJobRunner(jobRepo, env.AIJobWorkers, env.AIJobUserConcurrency, env.AIJobRetention)
+	jobRunner.Handle(models.JobKindVisionAnalyze, visionService.RunAnalyzeJob)
+	go jobRunner.Run(time.Second)
+}
@@ -1,18 +1,33 @@
 package router
 
 import (
+	"SimpleToDo/middleware"
+	"SimpleToDo/repository"
 	"SimpleToDo/router/v1"
+	"SimpleToDo/service"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 )
 
 func InitRouters(e *echo.Echo, db *gorm.DB) {
+	WellKnownRouters(e)
+
+	middleware.UseSessionService(service.NewSessionService(repository.NewUserRepository(db), repository.NewImpersonationRepository(db)))
+
 	apiV1 := e.Group("/api/v1")
 
 	v1.AuthRouters(db, apiV1)
 	v1.UserRouters(db, apiV1)
+	v1.AccountRouters(db, apiV1)
+	v1.AvatarRouters(db, apiV1)
+	v1.PreferencesRouters(db, apiV1)
+	v1.RoleRouters(db, apiV1)
+	v1.RegistrationRouters(db, apiV1)
+	v1.AuditRouters(db, apiV1)
 	v1.TaskRouters(db, apiV1)
 	v1.ProjectRoutes(db, apiV1)
 	v1.PromptRouters(db, apiV1)
 	v1.VisionRouters(db, apiV1)
+	v1.JobRouters(db, apiV1)
+	v1.AIUsageRouters(db, apiV1)
 }
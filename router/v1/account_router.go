@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"SimpleToDo/util/mapper"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+type AccountController struct {
+	AccountService *service.AccountService
+}
+
+func NewAccountController(accountService *service.AccountService) *AccountController {
+	return &AccountController{AccountService: accountService}
+}
+
+func (ac *AccountController) exportData(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+
+	var archive bytes.Buffer
+	if err := ac.AccountService.Export(uint(id), &archive, requestInfo(c)); err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error exporting data", err.Error(), true)
+	}
+
+	filename := fmt.Sprintf("simpletodo-export-%s.zip", time.Now().UTC().Format("20060102"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+func (ac *AccountController) requestDeletion(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+
+	var body request.DeleteAccountRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	deleteAt, err := ac.AccountService.RequestDeletion(uint(id), body.CurrentPassword, requestInfo(c))
+	if err != nil {
+		var throttled *service.ThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			return writeThrottleError(c, err)
+		case errors.Is(err, service.ErrAccountDeletionNotAllowed):
+			return response.WriteJSONResponse(c, http.StatusConflict, "Account deletion failed", err.Error(), true)
+		case err.Error() == "invalid credentials":
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Account deletion failed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Account deletion failed", err.Error(), true)
+	}
+
+	// every session, including this one, was signed out
+	clearAuthCookie(c)
+
+	data := response.AccountDeletionResponseDto{DeletionScheduledAt: *deleteAt}
+	return response.WriteJSONResponse(c, http.StatusAccepted, "Account scheduled for deletion", data, false)
+}
+
+func (ac *AccountController) cancelDeletion(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+	if err := ac.AccountService.CancelDeletion(uint(id), requestInfo(c)); err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error cancelling account deletion", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Account deletion cancelled", nil, false)
+}
+
+func AccountRouters(db *gorm.DB, v1 *echo.Group) {
+	userRepository := repository.NewUserRepository(db)
+	throttleService := service.NewThrottleService(repository.NewThrottleRepository(db))
+	passwordPolicyService := service.NewPasswordPolicyService(repository.NewPasswordHistoryRepository(db))
+	auditService := service.NewAuditService(repository.NewSecurityEventRepository(db))
+	preferencesService := service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db))
+	authService := service.NewAuthService(repository.NewAuthRepository(db), throttleService, passwordPolicyService, auditService,
+		preferencesService)
+	accountService := service.NewAccountService(repository.NewAccountRepository(db), userRepository,
+		repository.NewAIServerRepository(db), repository.NewRoleRepository(db), authService,
+		mapper.NewUserMapperImpl(), mapper.NewProjectMapperImpl(), mapper.NewTaskMapperImpl())
+	accountController := NewAccountController(accountService)
+
+	accountGroup := v1.Group("/profile")
+	accountGroup.Use(middleware.JWTMiddleware)
+	accountGroup.Use(middleware.NoImpersonationMiddleware)
+
+	// @Summary Export my data
+	// @Description Download a ZIP with the profile, projects, tasks, the prompts used by the user and AI settings (API key redacted) as JSON
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Produce application/zip
+	// @Success 200 {file} file
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Router /profile/export [get]
+	accountGroup.GET("/export", accountController.exportData,
+		middleware.RateLimitMiddleware(throttleService, "export", 5, time.Hour))
+
+	// @Summary Delete my account
+	// @Description Schedule the account and everything it owns for permanent deletion after the grace period (ACCOUNT_DELETION_GRACE_DAYS). All sessions are signed out
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.DeleteAccountRequest true "Current password"
+	// @Success 202 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 409 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Router /profile/deletion [post]
+	accountGroup.POST("/deletion", accountController.requestDeletion,
+		middleware.RateLimitMiddleware(throttleService, "delete-account", 5, 15*time.Minute))
+
+	// @Summary Cancel account deletion
+	// @Description Keep an account that is scheduled for deletion
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /profile/deletion [delete]
+	accountGroup.DELETE("/deletion", accountController.cancelDeletion)
+}
@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/util/aiprovider"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+// aiEventStream relays an AI reply to clients that ask for it with Accept: text/event-stream, as
+// Server-Sent Events:
+//
+//	delta  {"text": "..."}    a piece of the reply
+//	retry  {"reason": "..."}  the reply was rejected and is written again; drop the text received so far
+//	done   the response the request would have had without streaming
+//	error  the error response, once events were sent
+//
+// Errors before the first event are ordinary JSON responses. A client that goes away cancels the request
+// context, which stops the call to the provider.
+type aiEventStream struct {
+	c       echo.Context
+	enabled bool
+	started bool
+}
+
+func newAIEventStream(c echo.Context) *aiEventStream {
+	return &aiEventStream{c: c, enabled: strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/event-stream")}
+}
+
+// Stream returns the stream to pass to the service, or nil when the client did not ask for events.
+func (s *aiEventStream) Stream() *aiprovider.Stream {
+	if !s.enabled {
+		return nil
+	}
+	return &aiprovider.Stream{
+		Delta: func(text string) error {
+			return s.send("delta", map[string]string{"text": text})
+		},
+		Retry: func(reason error) error {
+			return s.send("retry", map[string]string{"reason": reason.Error()})
+		},
+	}
+}
+
+// Respond ends the request as response.WriteJSONResponse would, as the done or error event when
+// streaming.
+func (s *aiEventStream) Respond(statusCode int, message string, data any, isError bool) error {
+	if !s.enabled || (isError && !s.started) {
+		return response.WriteJSONResponse(s.c, statusCode, message, data, isError)
+	}
+	event := "done"
+	if isError {
+		event = "error"
+	}
+	if err := s.send(event, response.NewStandardResponse(statusCode, message, data, isError)); err != nil {
+		// the client is gone, there is no one left to tell
+		fmt.Printf("Error sending %s event: %v\n", event, err)
+	}
+	return nil
+}
+
+func (s *aiEventStream) send(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	res := s.c.Response()
+	if !s.started {
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set(echo.HeaderCacheControl, "no-cache")
+		res.Header().Set(echo.HeaderConnection, "keep-alive")
+		// keep reverse proxies such as nginx from buffering the events
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
@@ -0,0 +1,340 @@
+package v1
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type AIUsageController struct {
+	AIUsageService *service.AIUsageService
+	AuditService   *service.AuditService
+}
+
+func NewAIUsageController(aiUsageService *service.AIUsageService, auditService *service.AuditService) *AIUsageController {
+	return &AIUsageController{AIUsageService: aiUsageService, AuditService: auditService}
+}
+
+func (uc *AIUsageController) writeReport(c echo.Context, userId uint) error {
+	var filter request.AIUsageFilter
+	if err := c.Bind(&filter); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(filter); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	report, err := uc.AIUsageService.GetReport(userId, filter)
+	if errors.Is(err, service.ErrAIUsageRange) {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching AI usage", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "AI usage fetched successfully", report, false)
+}
+
+func (uc *AIUsageController) getMyUsage(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+	return uc.writeReport(c, uint(userId))
+}
+
+func (uc *AIUsageController) getUsage(c echo.Context) error {
+	return uc.writeReport(c, 0)
+}
+
+func (uc *AIUsageController) getQuotas(c echo.Context) error {
+	quotas, err := uc.AIUsageService.GetQuotas()
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching AI quotas", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "AI quotas fetched successfully", quotas, false)
+}
+
+func (uc *AIUsageController) setDefaultQuota(c echo.Context) error {
+	var body request.AIQuotaRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	quota, err := uc.AIUsageService.SetDefaultQuota(body)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error updating AI quota", err.Error(), true)
+	}
+	uc.AuditService.Record(requestInfo(c), models.SecurityEventAIQuotaUpdated, models.SecurityOutcomeSuccess, nil,
+		fmt.Sprintf("default: %d tokens, %d requests", quota.MonthlyTokens, quota.MonthlyRequests))
+	return response.WriteJSONResponse(c, http.StatusOK, "AI quota updated successfully", quota, false)
+}
+
+func (uc *AIUsageController) setUserQuota(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil || userId < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid User ID", true)
+	}
+	var body request.AIQuotaRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	quota, err := uc.AIUsageService.SetUserQuota(uint(userId), body)
+	if errors.Is(err, service.ErrUserNotFound) {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error updating AI quota", err.Error(), true)
+	}
+	target, _ := uc.AIUsageService.UserRepository.FindByID(uint(userId))
+	uc.AuditService.Record(requestInfo(c), models.SecurityEventAIQuotaUpdated, models.SecurityOutcomeSuccess, target,
+		fmt.Sprintf("%d tokens, %d requests", quota.MonthlyTokens, quota.MonthlyRequests))
+	return response.WriteJSONResponse(c, http.StatusOK, "AI quota updated successfully", quota, false)
+}
+
+func (uc *AIUsageController) deleteUserQuota(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil || userId < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid User ID", true)
+	}
+
+	err = uc.AIUsageService.DeleteUserQuota(uint(userId))
+	if errors.Is(err, service.ErrAIQuotaNotFound) {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "AI quota not found", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error deleting AI quota", err.Error(), true)
+	}
+	target, _ := uc.AIUsageService.UserRepository.FindByID(uint(userId))
+	uc.AuditService.Record(requestInfo(c), models.SecurityEventAIQuotaUpdated, models.SecurityOutcomeSuccess, target, "default")
+	return response.WriteJSONResponse(c, http.StatusOK, "AI quota deleted successfully", nil, false)
+}
+
+func (uc *AIUsageController) getPrices(c echo.Context) error {
+	prices, err := uc.AIUsageService.GetPrices()
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching AI model prices", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "AI model prices fetched successfully", prices, false)
+}
+
+// modelParam returns the model of the path. Models such as org/name are sent with the slash escaped.
+func modelParam(c echo.Context) (string, bool) {
+	model, err := url.PathUnescape(c.Param("model"))
+	model = strings.TrimSpace(model)
+	if err != nil || model == "" || len(model) > 100 {
+		return "", false
+	}
+	return model, true
+}
+
+func (uc *AIUsageController) setPrice(c echo.Context) error {
+	model, ok := modelParam(c)
+	if !ok {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid model", true)
+	}
+	var body request.AIModelPriceRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	price, err := uc.AIUsageService.SetPrice(model, body)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error updating AI model price", err.Error(), true)
+	}
+	uc.AuditService.Record(requestInfo(c), models.SecurityEventAIPriceUpdated, models.SecurityOutcomeSuccess, nil,
+		fmt.Sprintf("%s: %g input, %g output per million tokens", price.Model, price.InputPerMillion, price.OutputPerMillion))
+	return response.WriteJSONResponse(c, http.StatusOK, "AI model price updated successfully", price, false)
+}
+
+func (uc *AIUsageController) deletePrice(c echo.Context) error {
+	model, ok := modelParam(c)
+	if !ok {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid model", true)
+	}
+
+	err := uc.AIUsageService.DeletePrice(model)
+	if errors.Is(err, service.ErrAIPriceNotFound) {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "AI model price not found", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error deleting AI model price", err.Error(), true)
+	}
+	uc.AuditService.Record(requestInfo(c), models.SecurityEventAIPriceUpdated, models.SecurityOutcomeSuccess, nil, model+": deleted")
+	return response.WriteJSONResponse(c, http.StatusOK, "AI model price deleted successfully", nil, false)
+}
+
+func AIUsageRouters(db *gorm.DB, v1 *echo.Group) {
+	roleService := service.NewRoleService(repository.NewRoleRepository(db), repository.NewUserRepository(db))
+	aiUsageService := service.NewAIUsageService(repository.NewAIUsageRepository(db), repository.NewSettingRepository(db),
+		repository.NewUserRepository(db))
+	aiUsageController := NewAIUsageController(aiUsageService, service.NewAuditService(repository.NewSecurityEventRepository(db)))
+
+	profileGroup := v1.Group("/profile")
+	profileGroup.Use(middleware.JWTMiddleware)
+
+	// @Summary Get my AI usage
+	// @Description Sum the AI calls of the current user by day and model: requests, failures, tokens, average latency and, for models with a price, the estimated cost. Includes the user's monthly quota and how much of it is used; calls beyond the quota are refused with 429 until resetsAt
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Produce json
+	// @Param from query string false "First day, YYYY-MM-DD (UTC); defaults to 30 days before to"
+	// @Param to query string false "Last day, YYYY-MM-DD (UTC); defaults to today"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Router /profile/ai-usage [get]
+	profileGroup.GET("/ai-usage", aiUsageController.getMyUsage)
+
+	aiUsageGroup := v1.Group("/ai-usage")
+	aiUsageGroup.Use(middleware.JWTMiddleware)
+	aiUsageGroup.Use(middleware.PermissionMiddleware(roleService, models.PermissionAIUsageManage))
+
+	// @Summary Get AI usage
+	// @Description Sum the AI calls of every user, or of userId, by day, user and model, with the estimated cost of the models with a price (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Produce json
+	// @Param from query string false "First day, YYYY-MM-DD (UTC); defaults to 30 days before to"
+	// @Param to query string false "Last day, YYYY-MM-DD (UTC); defaults to today"
+	// @Param userId query int false "Only this user"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /ai-usage [get]
+	aiUsageGroup.GET("", aiUsageController.getUsage)
+
+	// @Summary List AI quotas
+	// @Description List the default monthly AI quota and the users with a quota of their own (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Produce json
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /ai-usage/quotas [get]
+	aiUsageGroup.GET("/quotas", aiUsageController.getQuotas)
+
+	// @Summary Set the default AI quota
+	// @Description Set the monthly tokens and requests of the users without a quota of their own; 0 means no limit (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.AIQuotaRequestDto true "Quota"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /ai-usage/quotas/default [put]
+	aiUsageGroup.PUT("/quotas/default", aiUsageController.setDefaultQuota)
+
+	// @Summary Set the AI quota of a user
+	// @Description Give the user monthly tokens and requests of their own instead of the default quota; 0 means no limit (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param userId path int true "User ID"
+	// @Param payload body request.AIQuotaRequestDto true "Quota"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /ai-usage/quotas/{userId} [put]
+	aiUsageGroup.PUT("/quotas/:userId", aiUsageController.setUserQuota)
+
+	// @Summary Delete the AI quota of a user
+	// @Description Remove the user's own quota, so that the default quota applies to them again (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Produce json
+	// @Param userId path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /ai-usage/quotas/{userId} [delete]
+	aiUsageGroup.DELETE("/quotas/:userId", aiUsageController.deleteUserQuota)
+
+	// @Summary List AI model prices
+	// @Description List the prices the cost of AI usage is estimated with (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Produce json
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /ai-usage/prices [get]
+	aiUsageGroup.GET("/prices", aiUsageController.getPrices)
+
+	// @Summary Set the price of an AI model
+	// @Description Set the price of a million input and output tokens of the model, as named in the AI settings; escape slashes in the name as %2F (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param model path string true "Model"
+	// @Param payload body request.AIModelPriceRequestDto true "Price"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /ai-usage/prices/{model} [put]
+	aiUsageGroup.PUT("/prices/:model", aiUsageController.setPrice)
+
+	// @Summary Delete the price of an AI model
+	// @Description Stop estimating the cost of the model (requires ai_usage:manage)
+	// @Tags AI Usage
+	// @Security BearerAuth
+	// @Produce json
+	// @Param model path string true "Model"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /ai-usage/prices/{model} [delete]
+	aiUsageGroup.DELETE("/prices/:model", aiUsageController.deletePrice)
+}
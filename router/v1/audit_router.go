@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+type AuditController struct {
+	AuditService *service.AuditService
+}
+
+func NewAuditController(auditService *service.AuditService) *AuditController {
+	return &AuditController{AuditService: auditService}
+}
+
+func bindSecurityEventFilter(c echo.Context) (request.SecurityEventFilter, []string, error) {
+	var filter request.SecurityEventFilter
+	if err := c.Bind(&filter); err != nil {
+		return filter, nil, err
+	}
+	validate := validator.New()
+	if err := validate.Struct(filter); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return filter, errorsString, nil
+	}
+	return filter, nil, nil
+}
+
+func (ac *AuditController) getSecurityEvents(c echo.Context) error {
+	pagination, err := validatePagination(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
+	}
+	filter, invalid, err := bindSecurityEventFilter(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	if invalid != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", invalid, true)
+	}
+
+	events, err := ac.AuditService.GetAll(filter, pagination)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching security events", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Security events fetched successfully", events, false)
+}
+
+func (ac *AuditController) exportSecurityEvents(c echo.Context) error {
+	filter, invalid, err := bindSecurityEventFilter(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	if invalid != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", invalid, true)
+	}
+
+	filename := fmt.Sprintf("security-events-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// the log can be large, so rows are streamed; an error past this point can only cut the file short
+	if err := ac.AuditService.ExportCSV(filter, c.Response()); err != nil {
+		fmt.Printf("Error exporting security events: %v\n", err)
+	}
+	return nil
+}
+
+func AuditRouters(db *gorm.DB, v1 *echo.Group) {
+	roleService := service.NewRoleService(repository.NewRoleRepository(db), repository.NewUserRepository(db))
+	auditController := NewAuditController(service.NewAuditService(repository.NewSecurityEventRepository(db)))
+
+	auditGroup := v1.Group("/audit")
+	auditGroup.Use(middleware.JWTMiddleware)
+	auditGroup.Use(middleware.PermissionMiddleware(roleService, models.PermissionAuditRead))
+
+	// @Summary Get security events
+	// @Description Query the append-only security log of logins, password and email changes, verification, role, AI setting and other admin changes (requires audit:read)
+	// @Tags Audit
+	// @Security BearerAuth
+	// @Produce json
+	// @Param page query int false "Page number"
+	// @Param limit query int false "Items per page"
+	// @Param sort query string false "Sort by id: asc or desc"
+	// @Param type query string false "Event type, e.g. auth.login"
+	// @Param outcome query string false "success or failure"
+	// @Param actorId query int false "User that performed the action"
+	// @Param targetId query int false "User the action applied to"
+	// @Param ip query string false "Client IP"
+	// @Param from query string false "Start of the time range (RFC 3339)"
+	// @Param to query string false "End of the time range (RFC 3339)"
+	// @Success 200 {object} response.StandardResponseOkPaginated
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /audit/events [get]
+	auditGroup.GET("/events", auditController.getSecurityEvents)
+
+	// @Summary Export security events
+	// @Description Download every security event matching the filters as CSV, newest first. Values that start like a spreadsheet formula are prefixed with a quote (requires audit:read)
+	// @Tags Audit
+	// @Security BearerAuth
+	// @Produce text/csv
+	// @Param type query string false "Event type, e.g. auth.login"
+	// @Param outcome query string false "success or failure"
+	// @Param actorId query int false "User that performed the action"
+	// @Param targetId query int false "User the action applied to"
+	// @Param ip query string false "Client IP"
+	// @Param from query string false "Start of the time range (RFC 3339)"
+	// @Param to query string false "End of the time range (RFC 3339)"
+	// @Success 200 {file} file
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /audit/events/export [get]
+	auditGroup.GET("/events/export", auditController.exportSecurityEvents)
+}
@@ -7,6 +7,8 @@ import (
 	"SimpleToDo/models"
 	"SimpleToDo/repository"
 	"SimpleToDo/service"
+	"SimpleToDo/util/jwtkeys"
+	"errors"
 	"net/http"
 	"time"
 
@@ -16,11 +18,20 @@ import (
 )
 
 type AuthController struct {
-	AuthService *service.AuthService
+	AuthService         *service.AuthService
+	ThrottleService     *service.ThrottleService
+	RoleService         *service.RoleService
+	RegistrationService *service.RegistrationService
 }
 
-func NewAuthController(authService *service.AuthService) *AuthController {
-	return &AuthController{AuthService: authService}
+func NewAuthController(authService *service.AuthService, throttleService *service.ThrottleService,
+	roleService *service.RoleService, registrationService *service.RegistrationService) *AuthController {
+	return &AuthController{
+		AuthService:         authService,
+		ThrottleService:     throttleService,
+		RoleService:         roleService,
+		RegistrationService: registrationService,
+	}
 }
 
 func (authController *AuthController) login(c echo.Context) error {
@@ -38,11 +49,29 @@ func (authController *AuthController) login(c echo.Context) error {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
 	}
 
-	token, err := authController.AuthService.LoginUser(body.Email, body.Password)
+	token, err := authController.AuthService.LoginUser(body.Email, body.Password, requestInfo(c))
 	if err != nil {
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			return writeThrottleError(c, err)
+		}
+		if errors.Is(err, service.ErrAccountSuspended) {
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Login failed", err.Error(), true)
+		}
 		return response.WriteJSONResponse(c, http.StatusUnauthorized, "Login failed", err.Error(), true)
 	}
 
+	setAuthCookie(c, token)
+
+	data := map[string]interface{}{
+		"token": token,
+	}
+
+	return response.WriteJSONResponse(c, http.StatusOK, "Login success", data, false)
+}
+
+// setAuthCookie stores the token in the HTTP-only session cookie for the web client.
+func setAuthCookie(c echo.Context, token string) {
 	appName := c.Request().Header.Get("Application-Name")
 	if appName == "SimpleTodoWeb" {
 		cookie := &http.Cookie{
@@ -52,10 +81,73 @@ func (authController *AuthController) login(c echo.Context) error {
 			HttpOnly: true,
 			Secure:   false,
 			SameSite: http.SameSiteLaxMode,
-			Expires:  time.Now().Add(72 * time.Hour),
+			Expires:  time.Now().Add(jwtkeys.TokenTTL),
 		}
 		c.SetCookie(cookie)
 	}
+}
+
+// requestInfo describes the caller of the current request for the security audit log.
+func requestInfo(c echo.Context) service.RequestInfo {
+	info := service.RequestInfo{
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+	if id, ok := c.Get("user_id").(float64); ok {
+		info.ActorId = uint(id)
+	}
+	if email, ok := c.Get("user_email").(string); ok {
+		info.ActorEmail = email
+	}
+	if impersonator, ok := c.Get("impersonator_id").(float64); ok {
+		info.ImpersonatorId = uint(impersonator)
+	}
+	return info
+}
+
+func (authController *AuthController) requestMagicLink(c echo.Context) error {
+	var body request.ForgotPasswordRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+	if err := authController.ThrottleService.Hit(service.AccountActionSubject("magic-link", body.Email), 3, time.Hour); err != nil {
+		return writeThrottleError(c, err)
+	}
+	_ = authController.AuthService.RequestMagicLink(body.Email)
+	return response.WriteJSONResponse(c, http.StatusOK, "If the email exists, a sign-in link has been sent", nil, false)
+}
+
+func (authController *AuthController) verifyMagicLink(c echo.Context) error {
+	var body request.MagicLinkVerifyRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	token, err := authController.AuthService.LoginWithMagicLink(body.Token, requestInfo(c))
+	if err != nil {
+		if errors.Is(err, service.ErrAccountSuspended) {
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Login failed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusUnauthorized, "Login failed", err.Error(), true)
+	}
+
+	setAuthCookie(c, token)
 
 	data := map[string]interface{}{
 		"token": token,
@@ -78,6 +170,14 @@ func (authController *AuthController) register(c echo.Context) error {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
 	}
 
+	invitation, err := authController.RegistrationService.CheckRegistration(body.Email, body.InviteToken)
+	if err != nil {
+		if errors.Is(err, service.ErrRegistrationNotAllowed) {
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Register failed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Register failed", err.Error(), true)
+	}
+
 	user := models.User{
 		Username:  body.Username,
 		Email:     body.Email,
@@ -89,18 +189,30 @@ func (authController *AuthController) register(c echo.Context) error {
 		Phone:     body.Phone,
 		BirthDate: body.BirthDate,
 		RoleId:    2,
-		Verified:  false,
+		// the invitation link was delivered to this address, which already proves ownership
+		Verified: invitation != nil,
 	}
-	if err := authController.AuthService.RegisterUser(&user); err != nil {
+	if err := authController.AuthService.RegisterUser(&user, requestInfo(c)); err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return response.WriteJSONResponse(c, http.StatusBadRequest, "Password does not meet the policy", policyErr.Violations, true)
+		}
 		return response.WriteJSONResponse(c, http.StatusConflict, "Register failed", err.Error(), true)
 	}
 
+	if invitation != nil {
+		if err := authController.RegistrationService.AcceptInvitation(invitation, user.ID); err != nil {
+			return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error accepting invitation", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusCreated, "User registered", nil, false)
+	}
+
 	_ = authController.AuthService.SendVerificationEmail(&user)
 
 	return response.WriteJSONResponse(c, http.StatusCreated, "User registered, please verify your email", nil, false)
 }
 
-func (authController *AuthController) logout(c echo.Context) error {
+func clearAuthCookie(c echo.Context) {
 	cookie := &http.Cookie{
 		Name:     middleware.AuthCookieName,
 		Value:    "",
@@ -111,6 +223,10 @@ func (authController *AuthController) logout(c echo.Context) error {
 		MaxAge:   -1,
 	}
 	c.SetCookie(cookie)
+}
+
+func (authController *AuthController) logout(c echo.Context) error {
+	clearAuthCookie(c)
 	return response.WriteJSONResponse(c, http.StatusOK, "Logged out", "OK", false)
 }
 
@@ -127,7 +243,10 @@ func (authController *AuthController) forgotPassword(c echo.Context) error {
 		}
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
 	}
-	_ = authController.AuthService.RequestPasswordReset(body.Email)
+	if err := authController.ThrottleService.Hit(service.AccountActionSubject("forgot", body.Email), 3, time.Hour); err != nil {
+		return writeThrottleError(c, err)
+	}
+	_ = authController.AuthService.RequestPasswordReset(body.Email, requestInfo(c))
 	return response.WriteJSONResponse(c, http.StatusOK, "If the email exists, a reset link has been sent", nil, false)
 }
 
@@ -144,7 +263,11 @@ func (authController *AuthController) resetPassword(c echo.Context) error {
 		}
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
 	}
-	if err := authController.AuthService.ResetPassword(body.Token, body.NewPassword); err != nil {
+	if err := authController.AuthService.ResetPassword(body.Token, body.NewPassword, requestInfo(c)); err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return response.WriteJSONResponse(c, http.StatusBadRequest, "Password does not meet the policy", policyErr.Violations, true)
+		}
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Reset failed", err.Error(), true)
 	}
 	return response.WriteJSONResponse(c, http.StatusOK, "Password updated", nil, false)
@@ -155,7 +278,7 @@ func (authController *AuthController) verifyEmail(c echo.Context) error {
 	if token == "" {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Token is required", nil, true)
 	}
-	if err := authController.AuthService.VerifyEmail(token); err != nil {
+	if err := authController.AuthService.VerifyEmail(token, requestInfo(c)); err != nil {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Verification failed", err.Error(), true)
 	}
 	return response.WriteJSONResponse(c, http.StatusOK, "Email verified successfully", nil, false)
@@ -174,6 +297,9 @@ func (authController *AuthController) resendVerificationEmail(c echo.Context) er
 		}
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
 	}
+	if err := authController.ThrottleService.Hit(service.AccountActionSubject("resend-verification", body.Email), 3, time.Hour); err != nil {
+		return writeThrottleError(c, err)
+	}
 
 	err := authController.AuthService.ResendVerificationEmail(body.Email)
 
@@ -189,23 +315,144 @@ func (authController *AuthController) resendVerificationEmail(c echo.Context) er
 	return response.WriteJSONResponse(c, http.StatusOK, "Verification email resent if the account is not verified", nil, false)
 }
 
+func (authController *AuthController) changeEmail(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+	var body request.ChangeEmailRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	err := authController.AuthService.RequestEmailChange(uint(id), body.NewEmail, body.CurrentPassword, requestInfo(c))
+	if err != nil {
+		var throttled *service.ThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			return writeThrottleError(c, err)
+		case errors.Is(err, repository.ErrEmailTaken):
+			return response.WriteJSONResponse(c, http.StatusConflict, "Email change failed", err.Error(), true)
+		case err.Error() == "invalid credentials":
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Email change failed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Email change failed", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "A confirmation link has been sent to the new email", nil, false)
+}
+
+func (authController *AuthController) confirmEmailChange(c echo.Context) error {
+	var body request.ConfirmEmailChangeRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+	if err := authController.AuthService.ConfirmEmailChange(body.Token, requestInfo(c)); err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return response.WriteJSONResponse(c, http.StatusConflict, "Email change failed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Email change failed", err.Error(), true)
+	}
+	clearAuthCookie(c)
+	return response.WriteJSONResponse(c, http.StatusOK, "Email changed successfully", nil, false)
+}
+
+func (authController *AuthController) getPasswordPolicy(c echo.Context) error {
+	policy := authController.AuthService.PasswordPolicyService.Policy()
+	data := response.PasswordPolicyResponseDto{
+		MinLength:     policy.MinLength,
+		RequireUpper:  policy.RequireUpper,
+		RequireLower:  policy.RequireLower,
+		RequireDigit:  policy.RequireDigit,
+		RequireSymbol: policy.RequireSymbol,
+		History:       policy.History,
+		BreachedCheck: policy.BreachedListPath != "",
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Password policy", data, false)
+}
+
+func (authController *AuthController) getRegistrationMode(c echo.Context) error {
+	settings, err := authController.RegistrationService.GetSettings()
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching registration settings", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Registration settings", settings, false)
+}
+
+func (authController *AuthController) previewInvitation(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Token is required", nil, true)
+	}
+	preview, err := authController.RegistrationService.PreviewInvitation(token)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "Invitation not found", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Invitation fetched successfully", preview, false)
+}
+
+func writeThrottleError(c echo.Context, err error) error {
+	var throttled *service.ThrottledError
+	if errors.As(err, &throttled) {
+		return middleware.WriteTooManyRequests(c, throttled)
+	}
+	return response.WriteJSONResponse(c, http.StatusInternalServerError, "Rate limit check failed", err.Error(), true)
+}
+
 func (authController *AuthController) getCurrentUser(c echo.Context) error {
 	userID := c.Get("user_id")
 	userEmail := c.Get("user_email")
 	userRole := c.Get("user_role")
 
+	permissions := make([]string, 0)
+	if role, ok := userRole.(float64); ok {
+		names, err := authController.RoleService.GetPermissionNames(uint(role))
+		if err != nil {
+			return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching permissions", err.Error(), true)
+		}
+		permissions = names
+	}
+
 	data := map[string]interface{}{
-		"id":    userID,
-		"email": userEmail,
-		"role":  userRole,
+		"id":          userID,
+		"email":       userEmail,
+		"role":        userRole,
+		"permissions": permissions,
+	}
+	if impersonator := c.Get("impersonator_id"); impersonator != nil {
+		data["impersonatorId"] = impersonator
 	}
 	return response.WriteJSONResponse(c, http.StatusOK, "Current user", data, false)
 }
 
 func AuthRouters(db *gorm.DB, v1 *echo.Group) {
 	authRepository := repository.NewAuthRepository(db)
-	authService := service.NewAuthService(authRepository)
-	authController := NewAuthController(authService)
+	throttleService := service.NewThrottleService(repository.NewThrottleRepository(db))
+	passwordPolicyService := service.NewPasswordPolicyService(repository.NewPasswordHistoryRepository(db))
+	auditService := service.NewAuditService(repository.NewSecurityEventRepository(db))
+	preferencesService := service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db))
+	authService := service.NewAuthService(authRepository, throttleService, passwordPolicyService, auditService,
+		preferencesService)
+	roleRepository := repository.NewRoleRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	roleService := service.NewRoleService(roleRepository, userRepository)
+	registrationService := service.NewRegistrationService(repository.NewSettingRepository(db),
+		repository.NewInvitationRepository(db), repository.NewProjectRepository(db), roleRepository, userRepository,
+		preferencesService)
+	authController := NewAuthController(authService, throttleService, roleService, registrationService)
 
 	authGroup := v1.Group("/auth")
 
@@ -220,11 +467,44 @@ func AuthRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Param payload body request.LoginRequest true "Login payload"
 	// @Success 200 {object} response.StandardResponseOk
 	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
 	// @Router /auth/login [post]
-	authGroup.POST("/login", authController.login)
+	authGroup.POST("/login", authController.login,
+		middleware.RateLimitMiddleware(throttleService, "login", 20, 15*time.Minute))
+
+	// @Summary Request magic link
+	// @Description Email a single-use passwordless sign-in link if the account exists
+	// @Tags Auth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.ForgotPasswordRequest true "Magic link payload (email)"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
+	// @Router /auth/magic-link [post]
+	authGroup.POST("/magic-link", authController.requestMagicLink,
+		middleware.RateLimitMiddleware(throttleService, "magic-link", 5, 15*time.Minute))
+
+	// @Summary Login with magic link
+	// @Description Exchange a magic link token for a JWT, set in HTTP-only cookie like login
+	// @Tags Auth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.MagicLinkVerifyRequest true "Magic link token"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
+	// @Router /auth/magic-link/verify [post]
+	authGroup.POST("/magic-link/verify", authController.verifyMagicLink,
+		middleware.RateLimitMiddleware(throttleService, "magic-link-verify", 20, 15*time.Minute))
 
 	// @Summary Register
-	// @Description Create a new user and send verification email
+	// @Description Create a new user and send verification email. Depending on the registration mode an invitation token or an allowed email domain is required
 	// @Tags Auth
 	// @Accept json
 	// @Produce json
@@ -232,10 +512,40 @@ func AuthRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Success 201 {object} response.StandardResponseOk
 	// @Failure 400 {object} response.StandardResponseError
 	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
 	// @Failure 409 {object} response.StandardResponseError
 	// @Router /auth/register [post]
 	authGroup.POST("/register", authController.register)
 
+	// @Summary Registration mode
+	// @Description Current registration mode (open, closed, domains or invite) and allowed email domains
+	// @Tags Auth
+	// @Produce json
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 500 {object} response.StandardResponseError
+	// @Router /auth/registration [get]
+	authGroup.GET("/registration", authController.getRegistrationMode)
+
+	// @Summary Preview invitation
+	// @Description Returns the invited email and project to pre-fill the registration form
+	// @Tags Auth
+	// @Produce json
+	// @Param token query string true "Invitation token"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /auth/invitation [get]
+	authGroup.GET("/invitation", authController.previewInvitation,
+		middleware.RateLimitMiddleware(throttleService, "invitation", 30, 15*time.Minute))
+
+	// @Summary Password policy
+	// @Description Rules a new password must satisfy at register, reset and password change
+	// @Tags Auth
+	// @Produce json
+	// @Success 200 {object} response.StandardResponseOk
+	// @Router /auth/password-policy [get]
+	authGroup.GET("/password-policy", authController.getPasswordPolicy)
+
 	// @Summary Forgot password
 	// @Description Send password reset email if account exists
 	// @Tags Auth
@@ -245,8 +555,11 @@ func AuthRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Success 200 {object} response.StandardResponseOk
 	// @Failure 400 {object} response.StandardResponseError
 	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
 	// @Router /auth/forgot [post]
-	authGroup.POST("/forgot", authController.forgotPassword)
+	authGroup.POST("/forgot", authController.forgotPassword,
+		middleware.RateLimitMiddleware(throttleService, "forgot", 5, 15*time.Minute))
 
 	// @Summary Reset password
 	// @Description Reset password using a one-time token sent by email
@@ -282,8 +595,41 @@ func AuthRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Failure 400 {object} response.StandardResponseError
 	// @Failure 401 {object} response.StandardResponseError
 	// @Failure 404 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
 	// @Router /auth/resend-verification [post]
-	authGroup.POST("/resend-verification", authController.resendVerificationEmail)
+	authGroup.POST("/resend-verification", authController.resendVerificationEmail,
+		middleware.RateLimitMiddleware(throttleService, "resend-verification", 5, 15*time.Minute))
+
+	// @Summary Confirm email change
+	// @Description Switch the account email to the address the confirmation link was sent to. Every session of the account is signed out
+	// @Tags Auth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.ConfirmEmailChangeRequest true "Email change token"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 409 {object} response.StandardResponseError
+	// @Router /auth/confirm-email-change [post]
+	authGroup.POST("/confirm-email-change", authController.confirmEmailChange)
+
+	// @Summary Request email change
+	// @Description Send a confirmation link to the new address and a notice to the current one (requires current password)
+	// @Tags Auth
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.ChangeEmailRequest true "Email change payload"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 409 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
+	// @Router /auth/change-email [post]
+	authProtectedGroup.POST("/change-email", authController.changeEmail, middleware.NoImpersonationMiddleware,
+		middleware.RateLimitMiddleware(throttleService, "change-email", 5, 15*time.Minute))
 
 	// @Summary Logout
 	// @Description Invalidate user session by clearing auth cookie
@@ -296,7 +642,7 @@ func AuthRouters(db *gorm.DB, v1 *echo.Group) {
 	authProtectedGroup.DELETE("/logout", authController.logout)
 
 	// @Summary Get current authenticated user
-	// @Description Returns basic info from the JWT claims
+	// @Description Returns basic info from the JWT claims and the permissions granted by the user's role
 	// @Tags Auth
 	// @Security BearerAuth
 	// @Produce json
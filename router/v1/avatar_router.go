@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"SimpleToDo/util/mapper"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type AvatarController struct {
+	AvatarService *service.AvatarService
+}
+
+func NewAvatarController(avatarService *service.AvatarService) *AvatarController {
+	return &AvatarController{AvatarService: avatarService}
+}
+
+func (ac *AvatarController) uploadAvatar(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "avatar file is required", true)
+	}
+	if file.Size > service.MaxAvatarBytes {
+		return response.WriteJSONResponse(c, http.StatusRequestEntityTooLarge, "Avatar upload failed", service.ErrImageTooLarge.Error(), true)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+	defer src.Close()
+	raw, err := io.ReadAll(io.LimitReader(src, service.MaxAvatarBytes+1))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+
+	version, err := ac.AvatarService.Set(uint(id), raw)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImageTooLarge):
+			return response.WriteJSONResponse(c, http.StatusRequestEntityTooLarge, "Avatar upload failed", err.Error(), true)
+		case errors.Is(err, service.ErrUnsupportedImage):
+			return response.WriteJSONResponse(c, http.StatusUnsupportedMediaType, "Avatar upload failed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Avatar upload failed", err.Error(), true)
+	}
+
+	data := response.AvatarResponseDto{AvatarUrl: mapper.AvatarURL(uint(id), version)}
+	return response.WriteJSONResponse(c, http.StatusOK, "Avatar updated", data, false)
+}
+
+func (ac *AvatarController) deleteAvatar(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+	if err := ac.AvatarService.Remove(uint(id)); err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error removing avatar", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Avatar removed", nil, false)
+}
+
+func (ac *AvatarController) getAvatar(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+	size := service.DefaultAvatarSize
+	if s := c.QueryParam("size"); s != "" {
+		if size, err = strconv.Atoi(s); err != nil {
+			return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid size", service.ErrInvalidAvatarSize.Error(), true)
+		}
+	}
+
+	avatar, version, err := ac.AvatarService.Get(uint(id), size)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAvatarSize):
+			return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid size", err.Error(), true)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Avatar not found", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching avatar", err.Error(), true)
+	}
+
+	header := c.Response().Header()
+	header.Set("ETag", avatar.ETag)
+	// a versioned URL always points at the same image; the bare one must be revalidated
+	if c.QueryParam("v") == version {
+		header.Set("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		header.Set("Cache-Control", "private, no-cache")
+	}
+	header.Set("X-Content-Type-Options", "nosniff")
+	if c.Request().Header.Get("If-None-Match") == avatar.ETag {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.Blob(http.StatusOK, avatar.ContentType, avatar.Data)
+}
+
+func AvatarRouters(db *gorm.DB, v1 *echo.Group) {
+	throttleService := service.NewThrottleService(repository.NewThrottleRepository(db))
+	avatarController := NewAvatarController(service.NewAvatarService(repository.NewAvatarRepository(db)))
+
+	profileGroup := v1.Group("/profile")
+	profileGroup.Use(middleware.JWTMiddleware)
+
+	// @Summary Upload my avatar
+	// @Description Upload a JPEG, PNG, GIF or WebP image of up to 5 MB. It is cropped to a square, resized to 64, 128, 256 and 512 pixels and stripped of metadata
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Accept multipart/form-data
+	// @Produce json
+	// @Param avatar formData file true "Image file"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 413 {object} response.StandardResponseError
+	// @Failure 415 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Router /profile/avatar [put]
+	profileGroup.PUT("/avatar", avatarController.uploadAvatar,
+		middleware.RateLimitMiddleware(throttleService, "avatar", 20, time.Hour))
+
+	// @Summary Remove my avatar
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 401 {object} response.StandardResponseError
+	// @Router /profile/avatar [delete]
+	profileGroup.DELETE("/avatar", avatarController.deleteAvatar)
+
+	avatarGroup := v1.Group("/avatars")
+	avatarGroup.Use(middleware.JWTMiddleware)
+
+	// @Summary Get a user's avatar
+	// @Description Serve the avatar image of a user. Use the avatarUrl of the user, which carries a version and can be cached indefinitely
+	// @Tags Users
+	// @Security BearerAuth
+	// @Produce image/jpeg
+	// @Produce image/png
+	// @Param id path int true "User ID"
+	// @Param size query int false "Side in pixels: 64, 128 (default), 256 or 512"
+	// @Param v query string false "Avatar version"
+	// @Success 200 {file} file
+	// @Success 304 "Not modified"
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /avatars/{id} [get]
+	avatarGroup.GET("/:id", avatarController.getAvatar)
+}
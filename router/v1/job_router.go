@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type JobController struct {
+	JobService *service.JobService
+}
+
+func NewJobController(jobService *service.JobService) *JobController {
+	return &JobController{JobService: jobService}
+}
+
+func (jc *JobController) getJob(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+
+	jobId, err := strconv.Atoi(c.Param("id"))
+	if err != nil || jobId < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid Job ID", true)
+	}
+	wait := 0
+	if value := c.QueryParam("wait"); value != "" {
+		if wait, err = strconv.Atoi(value); err != nil || wait < 0 {
+			return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "wait must be a number of seconds", true)
+		}
+	}
+
+	job, err := jc.JobService.Get(c.Request().Context(), uint(userId), uint(jobId), time.Duration(wait)*time.Second)
+	if errors.Is(err, service.ErrJobNotFound) {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "Error getting job", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error getting job", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Job fetched successfully", job, false)
+}
+
+func JobRouters(db *gorm.DB, v1 *echo.Group) {
+	jobController := NewJobController(service.NewJobService(repository.NewJobRepository(db)))
+
+	jobGroup := v1.Group("/jobs")
+	jobGroup.Use(middleware.JWTMiddleware)
+
+	// @Summary Get a background job
+	// @Description Get the status of a job queued by an async request such as POST /vision/analyze. Once the job succeeded its result is what the request would have returned; once it failed error tells why. With wait the request is held until the job finishes or wait seconds (at most 60) pass, so clients are told as soon as it is done
+	// @Tags Jobs
+	// @Security BearerAuth
+	// @Produce json
+	// @Param id path int true "Job ID"
+	// @Param wait query int false "Seconds to wait for the job to finish"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /jobs/{id} [get]
+	jobGroup.GET("/:id", jobController.getJob)
+}
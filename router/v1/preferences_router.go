@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+type PreferencesController struct {
+	PreferencesService *service.PreferencesService
+}
+
+func NewPreferencesController(preferencesService *service.PreferencesService) *PreferencesController {
+	return &PreferencesController{PreferencesService: preferencesService}
+}
+
+func (pc *PreferencesController) getPreferences(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+	prefs, err := pc.PreferencesService.Get(uint(id))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Failed to fetch preferences", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Preferences fetched", prefs, false)
+}
+
+func (pc *PreferencesController) updatePreferences(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+	var body request.UpdatePreferencesRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	prefs, err := pc.PreferencesService.Update(uint(id), body)
+	if errors.Is(err, service.ErrDefaultProjectNotAllowed) || errors.Is(err, service.ErrDefaultPromptNotFound) {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Failed to update preferences", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Preferences updated", prefs, false)
+}
+
+func PreferencesRouters(db *gorm.DB, v1 *echo.Group) {
+	preferencesController := NewPreferencesController(service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db)))
+
+	profileGroup := v1.Group("/profile")
+	profileGroup.Use(middleware.JWTMiddleware)
+
+	// @Summary Get my preferences
+	// @Description Get the timezone, locale, week start, date format, default project, default task sort and default vision prompt of the current user
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Produce json
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 401 {object} response.StandardResponseError
+	// @Router /profile/preferences [get]
+	profileGroup.GET("/preferences", preferencesController.getPreferences)
+
+	// @Summary Update my preferences
+	// @Description Change any of the preferences; omitted fields keep their value. The timezone decides what "today" and "overdue" mean for due dates and how dates appear in emails; the locale picks the language of emails, falling back to English
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.UpdatePreferencesRequest true "Preferences"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Router /profile/preferences [put]
+	profileGroup.PUT("/preferences", preferencesController.updatePreferences)
+}
@@ -4,9 +4,11 @@ import (
 	"SimpleToDo/dto/request"
 	"SimpleToDo/dto/response"
 	"SimpleToDo/middleware"
+	"SimpleToDo/models"
 	"SimpleToDo/repository"
 	"SimpleToDo/service"
 	"SimpleToDo/util/mapper"
+	"errors"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 	"net/http"
@@ -14,11 +16,12 @@ import (
 )
 
 type ProjectController struct {
-	ProjectService *service.ProjectService
+	ProjectService        *service.ProjectService
+	ProjectSummaryService *service.ProjectSummaryService
 }
 
-func NewProjectController(projectService *service.ProjectService) *ProjectController {
-	return &ProjectController{ProjectService: projectService}
+func NewProjectController(projectService *service.ProjectService, projectSummaryService *service.ProjectSummaryService) *ProjectController {
+	return &ProjectController{ProjectService: projectService, ProjectSummaryService: projectSummaryService}
 }
 
 func (p *ProjectController) getAllProjectsByUser(c echo.Context) error {
@@ -119,12 +122,66 @@ func (p *ProjectController) updateProject(c echo.Context) error {
 	return response.WriteJSONResponse(c, http.StatusOK, "Project updated successfully", projectResponse, false)
 }
 
+func (p *ProjectController) getSummary(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+
+	projectIdInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil || projectIdInt < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid Project ID", true)
+	}
+
+	summary, err := p.ProjectSummaryService.GetCached(uint(userId), projectIdInt)
+	if errors.Is(err, service.ErrProjectNotFound) || errors.Is(err, service.ErrProjectSummaryNotFound) {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "Error getting project summary", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error getting project summary", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Project summary fetched successfully", summary, false)
+}
+
+func (p *ProjectController) generateSummary(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+
+	projectIdInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil || projectIdInt < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid Project ID", true)
+	}
+
+	var body request.ProjectSummaryRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+
+	events := newAIEventStream(c)
+	summary, err := p.ProjectSummaryService.Generate(c.Request().Context(), uint(userId), projectIdInt, body.UseCache, body.Email,
+		events.Stream())
+	switch {
+	case errors.Is(err, service.ErrProjectNotFound):
+		return events.Respond(http.StatusNotFound, "Error summarizing project", err.Error(), true)
+	case errors.Is(err, service.ErrAINotConfigured):
+		return events.Respond(http.StatusBadRequest, "AI processing failed", err.Error(), true)
+	case errors.Is(err, service.ErrAIQuotaExceeded):
+		return events.Respond(http.StatusTooManyRequests, "AI quota exceeded", err.Error(), true)
+	case err != nil:
+		return events.Respond(http.StatusInternalServerError, "AI processing failed", err.Error(), true)
+	}
+	return events.Respond(http.StatusOK, "Project summarized successfully", summary, false)
+}
+
 func ProjectRoutes(db *gorm.DB, apiV1 *echo.Group) {
 	projectRepository := repository.NewProjectRepository(db)
 	projectMapper := mapper.NewProjectMapperImpl()
 
 	projectService := service.NewProjectService(projectRepository, projectMapper)
-	projectController := NewProjectController(projectService)
+	projectSummaryService := service.NewProjectSummaryService(projectRepository, repository.NewTaskRepository(db),
+		repository.NewProjectSummaryRepository(db), repository.NewAIServerRepository(db), repository.NewUserRepository(db),
+		service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+			repository.NewPromptRepository(db)),
+		service.NewAIUsageService(repository.NewAIUsageRepository(db), repository.NewSettingRepository(db),
+			repository.NewUserRepository(db)))
+	projectController := NewProjectController(projectService, projectSummaryService)
+	roleService := service.NewRoleService(repository.NewRoleRepository(db), repository.NewUserRepository(db))
 
 	projectGroup := apiV1.Group("/projects")
 	projectGroup.Use(middleware.JWTMiddleware)
@@ -143,6 +200,7 @@ func ProjectRoutes(db *gorm.DB, apiV1 *echo.Group) {
 	projectGroup.GET("/user", projectController.getAllProjectsByUser)
 
 	// @Summary      List all projects
+	// @Description  Lists the projects of every user (requires projects:read_all)
 	// @Tags         Projects
 	// @Security     BearerAuth
 	// @Produce      json
@@ -151,9 +209,11 @@ func ProjectRoutes(db *gorm.DB, apiV1 *echo.Group) {
 	// @Param        sort  query string false "Sort order" Enums(asc, desc) default(asc)
 	// @Success      200 {object} response.StandardResponseOk
 	// @Failure      400 {object} response.StandardResponseError
+	// @Failure      403 {object} response.StandardResponseError
 	// @Failure      500 {object} response.StandardResponseError
 	// @Router       /projects [get]
-	projectGroup.GET("", projectController.getAllProjects)
+	projectGroup.GET("", projectController.getAllProjects,
+		middleware.PermissionMiddleware(roleService, models.PermissionProjectsReadAll))
 
 	// @Summary      Get a project by ID
 	// @Tags         Projects
@@ -201,4 +261,33 @@ func ProjectRoutes(db *gorm.DB, apiV1 *echo.Group) {
 	// @Failure      500 {object} response.StandardResponseError
 	// @Router       /projects/project/{id} [put]
 	projectGroup.PUT("/project/:id", projectController.updateProject)
+
+	// @Summary      Get the last status summary of a project
+	// @Description  Returns the summary cached by the last POST /projects/project/{id}/summary. stale tells whether tasks changed since
+	// @Tags         Projects
+	// @Security     BearerAuth
+	// @Produce      json
+	// @Param        id path int true "Project ID"
+	// @Success      200 {object} response.StandardResponseOk
+	// @Failure      400 {object} response.StandardResponseError
+	// @Failure      404 {object} response.StandardResponseError
+	// @Router       /projects/project/{id}/summary [get]
+	projectGroup.GET("/project/:id/summary", projectController.getSummary)
+
+	// @Summary      Summarize the status of a project
+	// @Description  Ask the user's AI for a short status report of the project (done, in progress, blocked, risks) from its tasks, their statuses and recent changes, and cache it as the project's last summary. With useCache the cached summary is returned while no task changed since. With email the summary is also sent to the user. With Accept: text/event-stream the summary is streamed as Server-Sent Events: delta events with the text as it is written, then done with the response, or error
+	// @Tags         Projects
+	// @Security     BearerAuth
+	// @Accept       json
+	// @Produce      json
+	// @Produce      text/event-stream
+	// @Param        id path int true "Project ID"
+	// @Param        payload body request.ProjectSummaryRequestDto false "Options"
+	// @Success      200 {object} response.StandardResponseOk
+	// @Failure      400 {object} response.StandardResponseError
+	// @Failure      404 {object} response.StandardResponseError
+	// @Failure      429 {object} response.StandardResponseError
+	// @Failure      500 {object} response.StandardResponseError
+	// @Router       /projects/project/{id}/summary [post]
+	projectGroup.POST("/project/:id/summary", projectController.generateSummary)
 }
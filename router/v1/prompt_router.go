@@ -4,8 +4,10 @@ import (
 	"SimpleToDo/dto/request"
 	"SimpleToDo/dto/response"
 	"SimpleToDo/middleware"
+	"SimpleToDo/models"
 	"SimpleToDo/repository"
 	"SimpleToDo/service"
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -50,7 +52,12 @@ func (pc *PromptController) getAllPrompts(c echo.Context) error {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
 	}
 
-	prompts, err := pc.PromptService.GetAll(pagination)
+	purpose := c.QueryParam("purpose")
+	if purpose != "" && purpose != models.PromptPurposeVision && purpose != models.PromptPurposeBreakdown {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", "purpose must be vision or breakdown", true)
+	}
+
+	prompts, err := pc.PromptService.GetAll(pagination, purpose)
 	if err != nil {
 		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching prompts", err.Error(), true)
 	}
@@ -113,17 +120,35 @@ func (pc *PromptController) deletePrompt(c echo.Context) error {
 	return response.WriteJSONResponse(c, http.StatusOK, "Prompt deleted successfully", nil, false)
 }
 
+func (pc *PromptController) setDefaultPrompt(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+
+	prompt, err := pc.PromptService.SetDefault(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "Prompt not found", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Failed to set default prompt", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Default prompt updated", prompt, false)
+}
+
 func PromptRouters(db *gorm.DB, v1 *echo.Group) {
 	promptRepository := repository.NewPromptRepository(db)
 	promptService := service.NewPromptService(promptRepository)
 	promptController := NewPromptController(promptService)
+	roleService := service.NewRoleService(repository.NewRoleRepository(db), repository.NewUserRepository(db))
 
 	promptGroup := v1.Group("/prompts")
 	promptGroup.Use(middleware.JWTMiddleware)
-	promptGroup.Use(middleware.AdminOnlyMIddleware)
+	promptGroup.Use(middleware.PermissionMiddleware(roleService, models.PermissionPromptsManage))
 
 	// @Summary Create Prompt
-	// @Description Create a new AI system prompt (Admin only)
+	// @Description Create a new AI system prompt (requires prompts:manage)
 	// @Tags Prompts
 	// @Security BearerAuth
 	// @Accept json
@@ -137,11 +162,12 @@ func PromptRouters(db *gorm.DB, v1 *echo.Group) {
 	promptGroup.POST("", promptController.createPrompt)
 
 	// @Summary Get all prompts
-	// @Description Retrieve a paginated list of all prompts (Admin only)
+	// @Description Retrieve a paginated list of all prompts (requires prompts:manage)
 	// @Tags Prompts
 	// @Security BearerAuth
 	// @Param page query int false "Page number"
 	// @Param limit query int false "Items per page"
+	// @Param purpose query string false "Only prompts for vision or breakdown"
 	// @Success 200 {object} response.StandardResponseOkPaginated
 	// @Failure 400 {object} response.StandardResponseError
 	// @Failure 401 {object} response.StandardResponseError
@@ -151,7 +177,7 @@ func PromptRouters(db *gorm.DB, v1 *echo.Group) {
 	promptGroup.GET("", promptController.getAllPrompts)
 
 	// @Summary Get Prompt by ID
-	// @Description Retrieve details of a specific prompt (Admin only)
+	// @Description Retrieve details of a specific prompt (requires prompts:manage)
 	// @Tags Prompts
 	// @Security BearerAuth
 	// @Param id path int true "Prompt ID"
@@ -164,7 +190,7 @@ func PromptRouters(db *gorm.DB, v1 *echo.Group) {
 	promptGroup.GET("/:id", promptController.getPromptByID)
 
 	// @Summary Update Prompt
-	// @Description Update prompt details (Admin only)
+	// @Description Update prompt details (requires prompts:manage)
 	// @Tags Prompts
 	// @Security BearerAuth
 	// @Param id path int true "Prompt ID"
@@ -178,7 +204,7 @@ func PromptRouters(db *gorm.DB, v1 *echo.Group) {
 	promptGroup.PUT("/:id", promptController.updatePrompt)
 
 	// @Summary Delete Prompt
-	// @Description Delete a specific prompt (Admin only)
+	// @Description Delete a specific prompt (requires prompts:manage)
 	// @Tags Prompts
 	// @Security BearerAuth
 	// @Param id path int true "Prompt ID"
@@ -189,4 +215,17 @@ func PromptRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Failure 500 {object} response.StandardResponseError
 	// @Router /prompts/{id} [delete]
 	promptGroup.DELETE("/:id", promptController.deletePrompt)
+
+	// @Summary Set default prompt
+	// @Description Make the prompt the default for its purpose: for image analysis, of users who have not picked their own, or for task breakdown (requires prompts:manage)
+	// @Tags Prompts
+	// @Security BearerAuth
+	// @Param id path int true "Prompt ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /prompts/{id}/default [put]
+	promptGroup.PUT("/:id/default", promptController.setDefaultPrompt)
 }
@@ -0,0 +1,214 @@
+package v1
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type RegistrationController struct {
+	RegistrationService *service.RegistrationService
+	AuditService        *service.AuditService
+}
+
+func NewRegistrationController(registrationService *service.RegistrationService,
+	auditService *service.AuditService) *RegistrationController {
+	return &RegistrationController{RegistrationService: registrationService, AuditService: auditService}
+}
+
+func (rc *RegistrationController) getRegistrationSettings(c echo.Context) error {
+	settings, err := rc.RegistrationService.GetSettings()
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching registration settings", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Registration settings fetched successfully", settings, false)
+}
+
+func (rc *RegistrationController) updateRegistrationSettings(c echo.Context) error {
+	var body request.UpdateRegistrationSettingsRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	settings, err := rc.RegistrationService.UpdateSettings(body)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Failed to update registration settings", err.Error(), true)
+	}
+	rc.AuditService.Record(requestInfo(c), models.SecurityEventRegistrationUpdated, models.SecurityOutcomeSuccess, nil,
+		fmt.Sprintf("mode: %s, domains: %s", settings.Mode, strings.Join(settings.AllowedDomains, ",")))
+	return response.WriteJSONResponse(c, http.StatusOK, "Registration settings updated successfully", settings, false)
+}
+
+func (rc *RegistrationController) createInvitation(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+	roleId := c.Get("user_role").(float64)
+
+	var body request.CreateInvitationRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	invitation, err := rc.RegistrationService.Invite(uint(userId), uint(roleId), body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvitationForbidden):
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Failed to send invitation", err.Error(), true)
+		case errors.Is(err, repository.ErrEmailTaken):
+			return response.WriteJSONResponse(c, http.StatusConflict, "Failed to send invitation", err.Error(), true)
+		case err.Error() == "project not found":
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Failed to send invitation", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Failed to send invitation", err.Error(), true)
+	}
+	rc.AuditService.Record(requestInfo(c), models.SecurityEventInvitationSent, models.SecurityOutcomeSuccess, nil,
+		fmt.Sprintf("invitation %d to %s", invitation.Id, invitation.Email))
+	return response.WriteJSONResponse(c, http.StatusCreated, "Invitation sent successfully", invitation, false)
+}
+
+func (rc *RegistrationController) getAllInvitations(c echo.Context) error {
+	pagination, err := validatePagination(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
+	}
+	invitations, err := rc.RegistrationService.GetInvitations(pagination)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching invitations", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Invitations fetched successfully", invitations, false)
+}
+
+func (rc *RegistrationController) revokeInvitation(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+	if err := rc.RegistrationService.RevokeInvitation(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Invitation not found", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error revoking invitation", err.Error(), true)
+	}
+	rc.AuditService.Record(requestInfo(c), models.SecurityEventInvitationRevoked, models.SecurityOutcomeSuccess, nil,
+		fmt.Sprintf("invitation %d", id))
+	return response.WriteJSONResponse(c, http.StatusOK, "Invitation revoked successfully", nil, false)
+}
+
+func RegistrationRouters(db *gorm.DB, v1 *echo.Group) {
+	roleRepository := repository.NewRoleRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	roleService := service.NewRoleService(roleRepository, userRepository)
+	registrationService := service.NewRegistrationService(repository.NewSettingRepository(db),
+		repository.NewInvitationRepository(db), repository.NewProjectRepository(db), roleRepository, userRepository,
+		service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+			repository.NewPromptRepository(db)))
+	registrationController := NewRegistrationController(registrationService,
+		service.NewAuditService(repository.NewSecurityEventRepository(db)))
+
+	settingsGroup := v1.Group("/settings")
+	settingsGroup.Use(middleware.JWTMiddleware)
+	settingsGroup.Use(middleware.PermissionMiddleware(roleService, models.PermissionSettingsManage))
+
+	invitationsGroup := v1.Group("/invitations")
+	invitationsGroup.Use(middleware.JWTMiddleware)
+
+	// @Summary Get registration settings
+	// @Description Registration mode and allowed email domains (requires settings:manage)
+	// @Tags Settings
+	// @Security BearerAuth
+	// @Produce json
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 500 {object} response.StandardResponseError
+	// @Router /settings/registration [get]
+	settingsGroup.GET("/registration", registrationController.getRegistrationSettings)
+
+	// @Summary Update registration settings
+	// @Description Set the registration mode: open, closed, domains (allowedDomains required) or invite (requires settings:manage)
+	// @Tags Settings
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.UpdateRegistrationSettingsRequest true "Registration settings payload"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /settings/registration [put]
+	settingsGroup.PUT("/registration", registrationController.updateRegistrationSettings)
+
+	// @Summary Send invitation
+	// @Description Email an invitation to register. Without projectId it requires users:manage, with projectId the caller must own the project or have users:manage
+	// @Tags Invitations
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.CreateInvitationRequest true "Invitation payload"
+	// @Success 201 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Failure 409 {object} response.StandardResponseError
+	// @Router /invitations [post]
+	invitationsGroup.POST("", registrationController.createInvitation)
+
+	// @Summary Get all invitations
+	// @Description List sent invitations (requires users:manage)
+	// @Tags Invitations
+	// @Security BearerAuth
+	// @Produce json
+	// @Param page query int false "Page number"
+	// @Param limit query int false "Items per page"
+	// @Success 200 {object} response.StandardResponseOkPaginated
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /invitations [get]
+	invitationsGroup.GET("", registrationController.getAllInvitations,
+		middleware.PermissionMiddleware(roleService, models.PermissionUsersManage))
+
+	// @Summary Revoke invitation
+	// @Description Invalidate a pending invitation (requires users:manage)
+	// @Tags Invitations
+	// @Security BearerAuth
+	// @Produce json
+	// @Param id path int true "Invitation ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /invitations/{id} [delete]
+	invitationsGroup.DELETE("/:id", registrationController.revokeInvitation,
+		middleware.PermissionMiddleware(roleService, models.PermissionUsersManage))
+}
@@ -0,0 +1,227 @@
+package v1
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/middleware"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type RoleController struct {
+	RoleService  *service.RoleService
+	AuditService *service.AuditService
+}
+
+func NewRoleController(roleService *service.RoleService, auditService *service.AuditService) *RoleController {
+	return &RoleController{RoleService: roleService, AuditService: auditService}
+}
+
+func (rc *RoleController) getAllRoles(c echo.Context) error {
+	pagination, err := validatePagination(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
+	}
+	roles, err := rc.RoleService.GetAll(pagination)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching roles", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Roles fetched successfully", roles, false)
+}
+
+func (rc *RoleController) getAllPermissions(c echo.Context) error {
+	pagination, err := validatePagination(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
+	}
+	permissions, err := rc.RoleService.GetAllPermissions(pagination)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching permissions", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Permissions fetched successfully", permissions, false)
+}
+
+func (rc *RoleController) createRole(c echo.Context) error {
+	var body request.CreateRoleRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	role, err := rc.RoleService.Create(uint(c.Get("user_role").(float64)), body)
+	if errors.Is(err, service.ErrPermissionNotHeld) {
+		return response.WriteJSONResponse(c, http.StatusForbidden, "Failed to create role", err.Error(), true)
+	}
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusConflict, "Failed to create role", err.Error(), true)
+	}
+	rc.AuditService.Record(requestInfo(c), models.SecurityEventRoleCreated, models.SecurityOutcomeSuccess, nil,
+		fmt.Sprintf("role %d %s: %s", role.Id, role.Name, strings.Join(role.Permissions, ",")))
+	return response.WriteJSONResponse(c, http.StatusCreated, "Role created successfully", role, false)
+}
+
+func (rc *RoleController) updateRolePermissions(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+
+	var body request.UpdateRolePermissionsRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	role, err := rc.RoleService.SetPermissions(uint(c.Get("user_role").(float64)), uint(id), body)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Role not found", err.Error(), true)
+		}
+		if errors.Is(err, service.ErrOwnRoleEdit) || errors.Is(err, service.ErrPermissionNotHeld) {
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Failed to update role permissions", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Failed to update role permissions", err.Error(), true)
+	}
+	rc.AuditService.Record(requestInfo(c), models.SecurityEventRolePermissionsChanged, models.SecurityOutcomeSuccess, nil,
+		fmt.Sprintf("role %d %s: %s", role.Id, role.Name, strings.Join(role.Permissions, ",")))
+	return response.WriteJSONResponse(c, http.StatusOK, "Role permissions updated successfully", role, false)
+}
+
+func (rc *RoleController) assignRoleToUser(c echo.Context) error {
+	roleId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid role ID", err.Error(), true)
+	}
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid user ID", err.Error(), true)
+	}
+
+	callerId := c.Get("user_id").(float64)
+	callerRole := c.Get("user_role").(float64)
+	if err := rc.RoleService.AssignToUser(uint(callerId), uint(callerRole), uint(roleId), uint(userId)); err != nil {
+		if errors.Is(err, service.ErrOwnRoleChange) || errors.Is(err, service.ErrAdminRoleRequired) {
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Failed to assign role", err.Error(), true)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Role or user not found", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Failed to assign role", err.Error(), true)
+	}
+	target, _ := rc.RoleService.UserRepository.FindByID(uint(userId))
+	rc.AuditService.Record(requestInfo(c), models.SecurityEventRoleAssigned, models.SecurityOutcomeSuccess, target,
+		fmt.Sprintf("role %d", roleId))
+	return response.WriteJSONResponse(c, http.StatusOK, "Role assigned successfully", nil, false)
+}
+
+func RoleRouters(db *gorm.DB, v1 *echo.Group) {
+	roleService := service.NewRoleService(repository.NewRoleRepository(db), repository.NewUserRepository(db))
+	roleController := NewRoleController(roleService, service.NewAuditService(repository.NewSecurityEventRepository(db)))
+
+	rolesGroup := v1.Group("/roles")
+	rolesGroup.Use(middleware.JWTMiddleware)
+	rolesGroup.Use(middleware.PermissionMiddleware(roleService, models.PermissionRolesManage))
+
+	// @Summary Get all roles
+	// @Description List every role with its permissions (requires roles:manage)
+	// @Tags Roles
+	// @Security BearerAuth
+	// @Produce json
+	// @Param page query int false "Page number"
+	// @Param limit query int false "Items per page"
+	// @Success 200 {object} response.StandardResponseOkPaginated
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 500 {object} response.StandardResponseError
+	// @Router /roles [get]
+	rolesGroup.GET("", roleController.getAllRoles)
+
+	// @Summary Get all permissions
+	// @Description List every permission that can be attached to a role (requires roles:manage)
+	// @Tags Roles
+	// @Security BearerAuth
+	// @Produce json
+	// @Param page query int false "Page number"
+	// @Param limit query int false "Items per page"
+	// @Success 200 {object} response.StandardResponseOkPaginated
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 500 {object} response.StandardResponseError
+	// @Router /roles/permissions [get]
+	rolesGroup.GET("/permissions", roleController.getAllPermissions)
+
+	// @Summary Create role
+	// @Description Create a new role with an initial set of permissions; only administrators can include permissions they do not hold (requires roles:manage)
+	// @Tags Roles
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.CreateRoleRequest true "Create Role payload"
+	// @Success 201 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 409 {object} response.StandardResponseError
+	// @Router /roles [post]
+	rolesGroup.POST("", roleController.createRole)
+
+	// @Summary Set role permissions
+	// @Description Replace the permissions attached to a role; the Admin role cannot be changed, and only administrators can change their own role or add permissions they do not hold (requires roles:manage)
+	// @Tags Roles
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param id path int true "Role ID"
+	// @Param payload body request.UpdateRolePermissionsRequest true "Permissions payload"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /roles/{id}/permissions [put]
+	rolesGroup.PUT("/:id/permissions", roleController.updateRolePermissions)
+
+	// @Summary Assign role to user
+	// @Description Change the role of a user (requires roles:manage). Nobody can change their own role, and only administrators can grant the admin role or change the role of an administrator. The new role applies to the user's open sessions at once
+	// @Tags Roles
+	// @Security BearerAuth
+	// @Produce json
+	// @Param id path int true "Role ID"
+	// @Param userId path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Failure 500 {object} response.StandardResponseError
+	// @Router /roles/{id}/users/{userId} [put]
+	rolesGroup.PUT("/:id/users/:userId", roleController.assignRoleToUser)
+}
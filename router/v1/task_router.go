@@ -19,11 +19,14 @@ import (
 )
 
 type TaskController struct {
-	TaskService *service.TaskService
+	TaskService          *service.TaskService
+	QuickAddService      *service.QuickAddService
+	TaskBreakdownService *service.TaskBreakdownService
 }
 
-func NewTaskController(taskService *service.TaskService) *TaskController {
-	return &TaskController{TaskService: taskService}
+func NewTaskController(taskService *service.TaskService, quickAddService *service.QuickAddService,
+	taskBreakdownService *service.TaskBreakdownService) *TaskController {
+	return &TaskController{TaskService: taskService, QuickAddService: quickAddService, TaskBreakdownService: taskBreakdownService}
 }
 
 func (taskController *TaskController) getAll(c echo.Context) error {
@@ -39,7 +42,14 @@ func (taskController *TaskController) getAll(c echo.Context) error {
 	if err != nil {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
 	}
-	tasks, err := taskController.TaskService.GetAll(pagination, userIdInt)
+	due := c.QueryParam("due")
+	if !validDueFilter(due) {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "due must be today, overdue or upcoming", true)
+	}
+	if c.QueryParam("sort") == "" {
+		pagination.Sort = taskController.TaskService.DefaultSort(userIdInt)
+	}
+	tasks, err := taskController.TaskService.GetAll(pagination, userIdInt, due)
 	if err != nil {
 		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Internal Server Error", err.Error(), true)
 	}
@@ -71,8 +81,15 @@ func (taskController *TaskController) getAllTaskByProject(c echo.Context) error
 
 	taskTitle := c.QueryParam("taskTitle")
 	status := c.QueryParam("status")
+	due := c.QueryParam("due")
+	if !validDueFilter(due) {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "due must be today, overdue or upcoming", true)
+	}
+	if c.QueryParam("sort") == "" {
+		pagination.Sort = taskController.TaskService.DefaultSort(userIdInt)
+	}
 
-	tasks, err := taskController.TaskService.GetAllTaskByProjectId(pagination, projectIdInt, userIdInt, taskTitle, status)
+	tasks, err := taskController.TaskService.GetAllTaskByProjectId(pagination, projectIdInt, userIdInt, taskTitle, status, due)
 	if err != nil {
 		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Internal Server Error", err.Error(), true)
 	}
@@ -147,6 +164,146 @@ func (taskController *TaskController) saveTask(c echo.Context) error {
 	return response.WriteJSONResponse(c, http.StatusCreated, "Task created successfully", taskResponse, false)
 }
 
+func (taskController *TaskController) saveTasks(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+
+	projectIdInt, err := strconv.Atoi(c.Param("projectId"))
+	if err != nil || projectIdInt < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid project ID", true)
+	}
+
+	var body request.CreateTasksRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Namespace()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	tasksResponse, err := taskController.TaskService.SaveTasks(body.Tasks, projectIdInt, int(userId))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Error saving tasks", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error saving tasks", err.Error(), true)
+	}
+
+	return response.WriteJSONResponse(c, http.StatusCreated, "Tasks created successfully", response.TasksResponseDto{Tasks: tasksResponse}, false)
+}
+
+func (taskController *TaskController) quickAdd(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+
+	var body request.QuickAddRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	result, err := taskController.QuickAddService.QuickAdd(c.Request().Context(), uint(userId), body)
+	if err != nil {
+		if errors.Is(err, service.ErrQuickAddNoProject) {
+			return response.WriteJSONResponse(c, http.StatusBadRequest, "Error saving task", err.Error(), true)
+		}
+		if strings.Contains(err.Error(), "not found") {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Error saving task", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error saving task", err.Error(), true)
+	}
+
+	return response.WriteJSONResponse(c, http.StatusCreated, "Task created successfully", result, false)
+}
+
+func (taskController *TaskController) breakdownTask(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+
+	taskIdInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil || taskIdInt < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid Task ID", true)
+	}
+
+	var body request.TaskBreakdownRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	events := newAIEventStream(c)
+	steps, err := taskController.TaskBreakdownService.Suggest(c.Request().Context(), uint(userId), taskIdInt, body.PromptId, events.Stream())
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		return events.Respond(http.StatusNotFound, "Task not found", err.Error(), true)
+	case errors.Is(err, service.ErrPromptNotFound):
+		return events.Respond(http.StatusNotFound, "Prompt not found", err.Error(), true)
+	case errors.Is(err, service.ErrAINotConfigured):
+		return events.Respond(http.StatusBadRequest, "AI processing failed", err.Error(), true)
+	case errors.Is(err, service.ErrAIQuotaExceeded):
+		return events.Respond(http.StatusTooManyRequests, "AI quota exceeded", err.Error(), true)
+	case errors.Is(err, service.ErrNoStepsSuggested), errors.Is(err, service.ErrAIInvalidOutput):
+		return events.Respond(http.StatusUnprocessableEntity, "AI processing failed", err.Error(), true)
+	case err != nil:
+		return events.Respond(http.StatusInternalServerError, "AI processing failed", err.Error(), true)
+	}
+
+	// the suggestion is the body to accept the steps with
+	return events.Respond(http.StatusOK, "Task broken down successfully", request.CreateTasksRequestDto{Tasks: steps}, false)
+}
+
+func (taskController *TaskController) acceptBreakdown(c echo.Context) error {
+	userId := c.Get("user_id").(float64)
+
+	taskIdInt, err := strconv.Atoi(c.Param("id"))
+	if err != nil || taskIdInt < 1 {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", "Invalid Task ID", true)
+	}
+
+	var body request.CreateTasksRequestDto
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Namespace()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	tasksResponse, err := taskController.TaskBreakdownService.Accept(uint(userId), taskIdInt, body.Tasks)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "Error saving tasks", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error saving tasks", err.Error(), true)
+	}
+
+	return response.WriteJSONResponse(c, http.StatusCreated, "Tasks created successfully", response.TasksResponseDto{Tasks: tasksResponse}, false)
+}
+
 func (taskController *TaskController) updateTask(c echo.Context) error {
 	taskId := c.Param("id")
 	if taskId == "" {
@@ -208,6 +365,14 @@ func (taskController *TaskController) deleteTasks(c echo.Context) error {
 	return response.WriteJSONResponse(c, http.StatusOK, "Tasks deleted", "OK", false)
 }
 
+func validDueFilter(due string) bool {
+	switch due {
+	case "", service.TaskDueToday, service.TaskDueOverdue, service.TaskDueUpcoming:
+		return true
+	}
+	return false
+}
+
 func validatePagination(c echo.Context) (response.Pagination, error) {
 	limit := c.QueryParam("limit")
 	page := c.QueryParam("page")
@@ -255,8 +420,18 @@ func TaskRouters(db *gorm.DB, v1 *echo.Group) {
 	statusRepository := repository.NewStatusRepository(db)
 	taskMapper := mapper.NewTaskMapperImpl()
 
-	taskService := service.NewTaskService(taskRepository, statusRepository, taskMapper)
-	taskController := NewTaskController(taskService)
+	preferencesService := service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db))
+
+	aiUsageService := service.NewAIUsageService(repository.NewAIUsageRepository(db), repository.NewSettingRepository(db),
+		repository.NewUserRepository(db))
+
+	taskService := service.NewTaskService(taskRepository, statusRepository, repository.NewProjectRepository(db), taskMapper, preferencesService)
+	quickAddService := service.NewQuickAddService(repository.NewAIServerRepository(db), repository.NewProjectRepository(db),
+		taskService, preferencesService, aiUsageService)
+	taskBreakdownService := service.NewTaskBreakdownService(repository.NewAIServerRepository(db), repository.NewPromptRepository(db),
+		taskRepository, repository.NewProjectRepository(db), taskService, preferencesService, aiUsageService)
+	taskController := NewTaskController(taskService, quickAddService, taskBreakdownService)
 
 	tasksGroup := v1.Group("/tasks")
 	tasksGroup.Use(middleware.JWTMiddleware)
@@ -267,7 +442,8 @@ func TaskRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Produce      json
 	// @Param        limit query int false "Limit per page" default(10)
 	// @Param        page  query int false "Page number" default(1)
-	// @Param        sort  query string false "Sort order" Enums(asc, desc) default(asc)
+	// @Param        sort  query string false "Sort order by ID; defaults to the user's task sort preference" Enums(asc, desc)
+	// @Param        due   query string false "Only tasks due today, overdue or upcoming, in the user's timezone" Enums(today, overdue, upcoming)
 	// @Success      200 {object} response.StandardResponseOk
 	// @Failure      400 {object} response.StandardResponseError
 	// @Failure      500 {object} response.StandardResponseError
@@ -281,7 +457,8 @@ func TaskRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Param        projectId path int true "Project ID"
 	// @Param        limit query int false "Limit per page" default(10)
 	// @Param        page  query int false "Page number" default(1)
-	// @Param        sort  query string false "Sort order" Enums(asc, desc) default(asc)
+	// @Param        sort  query string false "Sort order by ID; defaults to the user's task sort preference" Enums(asc, desc)
+	// @Param        due   query string false "Only tasks due today, overdue or upcoming, in the user's timezone" Enums(today, overdue, upcoming)
 	// @Success      200 {object} response.StandardResponseOk
 	// @Failure      400 {object} response.StandardResponseError
 	// @Failure      500 {object} response.StandardResponseError
@@ -323,6 +500,65 @@ func TaskRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Router       /tasks/task/{projectId} [post]
 	tasksGroup.POST("/task/:projectId", taskController.saveTask)
 
+	// @Summary      Create several tasks in a project
+	// @Description  Create up to 50 tasks at once, e.g. the ones previewed from an image, in a project the user owns or is a member of. Either all tasks are created or none
+	// @Tags         Tasks
+	// @Security     BearerAuth
+	// @Accept       json
+	// @Produce      json
+	// @Param        projectId path int true "Project ID"
+	// @Param        payload body request.CreateTasksRequestDto true "Tasks data"
+	// @Success      201 {object} response.StandardResponseOk
+	// @Failure      400 {object} response.StandardResponseError
+	// @Failure      404 {object} response.StandardResponseError
+	// @Router       /tasks/batch/{projectId} [post]
+	tasksGroup.POST("/batch/:projectId", taskController.saveTasks)
+
+	// @Summary      Quick-add a task from text
+	// @Description  Turn a sentence such as "call supplier about invoice next Tuesday high priority #finance" into a task, reading the title, due date and priority with the user's AI or, without AI settings, with built-in rules. The task goes to the project named by a #tag, else to projectId, else to the user's default project
+	// @Tags         Tasks
+	// @Security     BearerAuth
+	// @Accept       json
+	// @Produce      json
+	// @Param        payload body request.QuickAddRequestDto true "Text of the task"
+	// @Success      201 {object} response.StandardResponseOk
+	// @Failure      400 {object} response.StandardResponseError
+	// @Failure      404 {object} response.StandardResponseError
+	// @Router       /tasks/quick-add [post]
+	tasksGroup.POST("/quick-add", taskController.quickAdd)
+
+	// @Summary      Break a task down into steps
+	// @Description  Ask the user's AI for smaller steps of the task, given its title, description and project. The steps are not saved: accept them, edited or not, with POST /tasks/task/{id}/breakdown/accept. The prompt is promptId if given, else the default breakdown prompt. With Accept: text/event-stream the reply is streamed as Server-Sent Events: delta events with the text of the reply as it is written, retry when it was rejected and is written again, then done with the response, or error
+	// @Tags         Tasks
+	// @Security     BearerAuth
+	// @Accept       json
+	// @Produce      json
+	// @Produce      text/event-stream
+	// @Param        id path int true "Task ID"
+	// @Param        payload body request.TaskBreakdownRequestDto false "Prompt to use"
+	// @Success      200 {object} response.StandardResponseOk
+	// @Failure      400 {object} response.StandardResponseError
+	// @Failure      404 {object} response.StandardResponseError
+	// @Failure      422 {object} response.StandardResponseError
+	// @Failure      429 {object} response.StandardResponseError
+	// @Failure      500 {object} response.StandardResponseError
+	// @Router       /tasks/task/{id}/breakdown [post]
+	tasksGroup.POST("/task/:id/breakdown", taskController.breakdownTask)
+
+	// @Summary      Accept the steps of a task
+	// @Description  Create the suggested steps of a task as new tasks in the task's project
+	// @Tags         Tasks
+	// @Security     BearerAuth
+	// @Accept       json
+	// @Produce      json
+	// @Param        id path int true "Task ID"
+	// @Param        payload body request.CreateTasksRequestDto true "Steps to create"
+	// @Success      201 {object} response.StandardResponseOk
+	// @Failure      400 {object} response.StandardResponseError
+	// @Failure      404 {object} response.StandardResponseError
+	// @Router       /tasks/task/{id}/breakdown/accept [post]
+	tasksGroup.POST("/task/:id/breakdown/accept", taskController.acceptBreakdown)
+
 	// @Summary      Update a task by ID
 	// @Tags         Tasks
 	// @Security     BearerAuth
@@ -4,22 +4,28 @@ import (
 	"SimpleToDo/dto/request"
 	"SimpleToDo/dto/response"
 	"SimpleToDo/middleware"
+	"SimpleToDo/models"
 	"SimpleToDo/repository"
 	"SimpleToDo/service"
 	"SimpleToDo/util/mapper"
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type UserController struct {
-	UserService *service.UserService
+	UserService          *service.UserService
+	AuthService          *service.AuthService
+	ImpersonationService *service.ImpersonationService
 }
 
-func NewUserController(s *service.UserService) *UserController {
-	return &UserController{UserService: s}
+func NewUserController(s *service.UserService, authService *service.AuthService,
+	impersonationService *service.ImpersonationService) *UserController {
+	return &UserController{UserService: s, AuthService: authService, ImpersonationService: impersonationService}
 }
 
 func (uc *UserController) getUserByID(c echo.Context) error {
@@ -53,6 +59,9 @@ func (uc *UserController) updateUser(c echo.Context) error {
 	if err != nil {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
 	}
+	if err := uc.UserService.CheckManageable(uint(c.Get("user_role").(float64)), uint(id)); err != nil {
+		return writeManageableError(c, err)
+	}
 
 	var body request.UpdateUserRequest
 	if err := c.Bind(&body); err != nil {
@@ -81,25 +90,189 @@ func (uc *UserController) deleteUser(c echo.Context) error {
 	if err != nil {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
 	}
+	if err := uc.UserService.CheckManageable(uint(c.Get("user_role").(float64)), uint(id)); err != nil {
+		return writeManageableError(c, err)
+	}
+
+	err = uc.UserService.Delete(uint(id), requestInfo(c))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error deleting user", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "User deleted successfully", nil, false)
+}
+
+func (uc *UserController) unlockUser(c echo.Context) error {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+	if err := uc.UserService.CheckManageable(uint(c.Get("user_role").(float64)), uint(id)); err != nil {
+		return writeManageableError(c, err)
+	}
+	if err := uc.UserService.Unlock(uint(id), requestInfo(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error unlocking user", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "User unlocked successfully", nil, false)
+}
+
+func (uc *UserController) suspendUser(c echo.Context) error {
+	return uc.setSuspended(c, true)
+}
+
+func (uc *UserController) reactivateUser(c echo.Context) error {
+	return uc.setSuspended(c, false)
+}
+
+func (uc *UserController) setSuspended(c echo.Context, suspended bool) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+	if currentId := c.Get("user_id").(float64); suspended && uint(currentId) == uint(id) {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Cannot suspend your own account", nil, true)
+	}
+	if err := uc.UserService.CheckManageable(uint(c.Get("user_role").(float64)), uint(id)); err != nil {
+		return writeManageableError(c, err)
+	}
+	if err := uc.UserService.SetSuspended(uint(id), suspended, requestInfo(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error updating user", err.Error(), true)
+	}
+	if suspended {
+		return response.WriteJSONResponse(c, http.StatusOK, "User suspended successfully", nil, false)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "User reactivated successfully", nil, false)
+}
+
+func (uc *UserController) verifyUser(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+	if err := uc.UserService.CheckManageable(uint(c.Get("user_role").(float64)), uint(id)); err != nil {
+		return writeManageableError(c, err)
+	}
+	if err := uc.UserService.ForceVerify(uint(id), requestInfo(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error verifying user", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "User verified successfully", nil, false)
+}
+
+func (uc *UserController) resendUserVerification(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+	if err := uc.UserService.CheckManageable(uint(c.Get("user_role").(float64)), uint(id)); err != nil {
+		return writeManageableError(c, err)
+	}
 	user, err := uc.UserService.GetByID(uint(id))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+	}
+	if err := uc.AuthService.ResendVerificationEmail(user.Email); err != nil {
+		if err.Error() == "user already verified" {
+			return response.WriteJSONResponse(c, http.StatusConflict, "User already verified", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error sending verification email", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Verification email sent", nil, false)
+}
 
+func (uc *UserController) triggerPasswordReset(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching user", err.Error(), true)
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
 	}
+	if err := uc.UserService.CheckManageable(uint(c.Get("user_role").(float64)), uint(id)); err != nil {
+		return writeManageableError(c, err)
+	}
+	user, err := uc.UserService.GetByID(uint(id))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+	}
+	_ = uc.AuthService.RequestPasswordReset(user.Email, requestInfo(c))
+	return response.WriteJSONResponse(c, http.StatusOK, "Password reset email sent", nil, false)
+}
 
-	if user == nil {
-		return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", nil, true)
+// writeManageableError answers a request whose target account the caller may not act on.
+func writeManageableError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+	case errors.Is(err, service.ErrAdminTarget):
+		return response.WriteJSONResponse(c, http.StatusForbidden, "Cannot manage an administrator", err.Error(), true)
 	}
+	return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching user", err.Error(), true)
+}
 
-	if user.Role == "ADMIN" {
-		return response.WriteJSONResponse(c, http.StatusForbidden, "Cannot delete admin user", nil, true)
+func (uc *UserController) impersonateUser(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid ID", err.Error(), true)
+	}
+	var body request.ImpersonateUserRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
 	}
 
-	err = uc.UserService.Delete(uint(id))
+	ttl := time.Duration(body.Minutes) * time.Minute
+	token, session, err := uc.ImpersonationService.Start(uint(id), body.Reason, ttl, requestInfo(c))
 	if err != nil {
-		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error deleting user", err.Error(), true)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return response.WriteJSONResponse(c, http.StatusNotFound, "User not found", err.Error(), true)
+		case errors.Is(err, service.ErrImpersonationNotAllowed):
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Impersonation not allowed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error starting impersonation", err.Error(), true)
 	}
-	return response.WriteJSONResponse(c, http.StatusOK, "User deleted successfully", nil, false)
+
+	data := map[string]interface{}{
+		"token":   token,
+		"session": session,
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Impersonation started", data, false)
+}
+
+func (uc *UserController) getImpersonations(c echo.Context) error {
+	pagination, err := validatePagination(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
+	}
+	sessions, err := uc.ImpersonationService.GetAll(pagination)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching impersonation sessions", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Impersonation sessions fetched successfully", sessions, false)
+}
+
+func (uc *UserController) stopImpersonation(c echo.Context) error {
+	sessionId, ok := c.Get("impersonation_id").(float64)
+	if !ok {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Not impersonating", nil, true)
+	}
+	if err := uc.ImpersonationService.Stop(uint(sessionId), requestInfo(c)); err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error ending impersonation", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Impersonation ended", nil, false)
 }
 
 func (uc *UserController) getUserProfile(c echo.Context) error {
@@ -134,6 +307,45 @@ func (uc *UserController) updateProfile(c echo.Context) error {
 	return response.WriteJSONResponse(c, http.StatusOK, "User updated successfully", updatedUser, false)
 }
 
+func (uc *UserController) changePassword(c echo.Context) error {
+	id := c.Get("user_id").(float64)
+	var body request.ChangePasswordRequest
+	if err := c.Bind(&body); err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid data", err.Error(), true)
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		var errorsString []string
+		for _, e := range err.(validator.ValidationErrors) {
+			errorsString = append(errorsString, e.Field()+" is "+e.Tag()+" "+e.Param())
+		}
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
+	}
+
+	token, err := uc.AuthService.ChangePassword(uint(id), body.CurrentPassword, body.NewPassword, requestInfo(c))
+	if err != nil {
+		var throttled *service.ThrottledError
+		var policyErr *service.PasswordPolicyError
+		switch {
+		case errors.As(err, &throttled):
+			return writeThrottleError(c, err)
+		case errors.As(err, &policyErr):
+			return response.WriteJSONResponse(c, http.StatusBadRequest, "Password does not meet the policy", policyErr.Violations, true)
+		case err.Error() == "invalid credentials":
+			return response.WriteJSONResponse(c, http.StatusForbidden, "Password change failed", err.Error(), true)
+		}
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Password change failed", err.Error(), true)
+	}
+
+	// the old cookie was revoked together with every other session
+	setAuthCookie(c, token)
+
+	data := map[string]interface{}{
+		"token": token,
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Password changed successfully", data, false)
+}
+
 func (uc *UserController) getAISettings(c echo.Context) error {
 	id := c.Get("user_id").(float64)
 	settings, err := uc.UserService.GetAISettings(uint(id))
@@ -159,7 +371,10 @@ func (uc *UserController) updateAISettings(c echo.Context) error {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", errorsString, true)
 	}
 
-	settings, err := uc.UserService.UpdateAISettings(uint(id), body)
+	settings, err := uc.UserService.UpdateAISettings(uint(id), body, requestInfo(c))
+	if errors.Is(err, service.ErrAPIKeyRequired) || errors.Is(err, service.ErrBaseURLRequired) {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Invalid request", err.Error(), true)
+	}
 	if err != nil {
 		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Failed to update AI settings", err.Error(), true)
 	}
@@ -169,20 +384,33 @@ func (uc *UserController) updateAISettings(c echo.Context) error {
 func UserRouters(db *gorm.DB, v1 *echo.Group) {
 	userRepository := repository.NewUserRepository(db)
 	aiServerRepository := repository.NewAIServerRepository(db)
+	throttleService := service.NewThrottleService(repository.NewThrottleRepository(db))
 	userMapper := mapper.NewUserMapperImpl()
 
-	userService := service.NewUserService(userRepository, aiServerRepository, userMapper)
-	userController := NewUserController(userService)
+	auditService := service.NewAuditService(repository.NewSecurityEventRepository(db))
+
+	userService := service.NewUserService(userRepository, aiServerRepository, throttleService, userMapper, auditService)
+	passwordPolicyService := service.NewPasswordPolicyService(repository.NewPasswordHistoryRepository(db))
+	preferencesService := service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db))
+	authService := service.NewAuthService(repository.NewAuthRepository(db), throttleService, passwordPolicyService, auditService,
+		preferencesService)
+	roleRepository := repository.NewRoleRepository(db)
+	impersonationService := service.NewImpersonationService(repository.NewImpersonationRepository(db),
+		userRepository, roleRepository, authService)
+	userController := NewUserController(userService, authService, impersonationService)
+
+	roleService := service.NewRoleService(roleRepository, userRepository)
 
 	usersGroup := v1.Group("/users")
 	usersGroup.Use(middleware.JWTMiddleware)
-	usersGroup.Use(middleware.AdminOnlyMIddleware)
+	usersGroup.Use(middleware.PermissionMiddleware(roleService, models.PermissionUsersManage))
 
 	userProfileGroup := v1.Group("/profile")
 	userProfileGroup.Use(middleware.JWTMiddleware)
 
 	// @Summary Get all users
-	// @Description Retrieve a paginated list of all users (Admin only)
+	// @Description Retrieve a paginated list of all users (requires users:manage)
 	// @Tags Users
 	// @Security BearerAuth
 	// @Param page query int false "Page number"
@@ -196,7 +424,7 @@ func UserRouters(db *gorm.DB, v1 *echo.Group) {
 	usersGroup.GET("", userController.getAllUsers)
 
 	// @Summary Get user by ID
-	// @Description Retrieve details of a specific user by ID (Admin only)
+	// @Description Retrieve details of a specific user by ID (requires users:manage)
 	// @Tags Users
 	// @Security BearerAuth
 	// @Param id path int true "User ID"
@@ -210,7 +438,7 @@ func UserRouters(db *gorm.DB, v1 *echo.Group) {
 	usersGroup.GET("/user/:id", userController.getUserByID)
 
 	// @Summary Delete user
-	// @Description Delete a specific user by ID (Admin only, cannot delete admin users)
+	// @Description Delete a specific user by ID (requires users:manage. Only administrators can act on administrators)
 	// @Tags Users
 	// @Security BearerAuth
 	// @Param id path int true "User ID"
@@ -224,7 +452,7 @@ func UserRouters(db *gorm.DB, v1 *echo.Group) {
 	usersGroup.DELETE("/user/:id", userController.deleteUser)
 
 	// @Summary Update user
-	// @Description Update user details by ID (Admin only)
+	// @Description Update user details by ID (requires users:manage. Only administrators can act on administrators)
 	// @Tags Users
 	// @Security BearerAuth
 	// @Param id path int true "User ID"
@@ -233,10 +461,130 @@ func UserRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Failure 400 {object} response.StandardResponseError
 	// @Failure 401 {object} response.StandardResponseError
 	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
 	// @Failure 500 {object} response.StandardResponseError
 	// @Router /users/user/{id} [put]
 	usersGroup.PUT("/user/:id", userController.updateUser)
 
+	// @Summary Unlock user
+	// @Description Clear failed login attempts and lift a temporary account lockout (requires users:manage. Only administrators can act on administrators)
+	// @Tags Users
+	// @Security BearerAuth
+	// @Param id path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Failure 500 {object} response.StandardResponseError
+	// @Router /users/user/{id}/unlock [post]
+	usersGroup.POST("/user/:id/unlock", userController.unlockUser)
+
+	// @Summary Suspend user
+	// @Description Block login and revoke every existing token of the user (requires users:manage. Only administrators can act on administrators)
+	// @Tags Users
+	// @Security BearerAuth
+	// @Param id path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /users/user/{id}/suspend [post]
+	usersGroup.POST("/user/:id/suspend", userController.suspendUser)
+
+	// @Summary Reactivate user
+	// @Description Lift a suspension; tokens issued before it stay revoked (requires users:manage. Only administrators can act on administrators)
+	// @Tags Users
+	// @Security BearerAuth
+	// @Param id path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /users/user/{id}/reactivate [post]
+	usersGroup.POST("/user/:id/reactivate", userController.reactivateUser)
+
+	// @Summary Force verify user
+	// @Description Mark the user's email as verified without the verification link (requires users:manage. Only administrators can act on administrators)
+	// @Tags Users
+	// @Security BearerAuth
+	// @Param id path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /users/user/{id}/verify [post]
+	usersGroup.POST("/user/:id/verify", userController.verifyUser)
+
+	// @Summary Resend verification email
+	// @Description Send a new verification link to an unverified user (requires users:manage. Only administrators can act on administrators)
+	// @Tags Users
+	// @Security BearerAuth
+	// @Param id path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Failure 409 {object} response.StandardResponseError
+	// @Router /users/user/{id}/resend-verification [post]
+	usersGroup.POST("/user/:id/resend-verification", userController.resendUserVerification)
+
+	// @Summary Trigger password reset
+	// @Description Email the user a password reset link (requires users:manage. Only administrators can act on administrators)
+	// @Tags Users
+	// @Security BearerAuth
+	// @Param id path int true "User ID"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /users/user/{id}/password-reset [post]
+	usersGroup.POST("/user/:id/password-reset", userController.triggerPasswordReset)
+
+	// @Summary Impersonate user
+	// @Description Start an audited, time-limited support session as the user (max 60 minutes, requires users:manage). Administrators cannot be impersonated
+	// @Tags Users
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param id path int true "User ID"
+	// @Param payload body request.ImpersonateUserRequest true "Impersonation payload"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Router /users/user/{id}/impersonate [post]
+	usersGroup.POST("/user/:id/impersonate", userController.impersonateUser, middleware.NoImpersonationMiddleware)
+
+	// @Summary Get impersonation sessions
+	// @Description Audit trail of every impersonation session (requires users:manage)
+	// @Tags Users
+	// @Security BearerAuth
+	// @Param page query int false "Page number"
+	// @Param limit query int false "Items per page"
+	// @Success 200 {object} response.StandardResponseOkPaginated
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Router /users/impersonations [get]
+	usersGroup.GET("/impersonations", userController.getImpersonations)
+
+	// @Summary Stop impersonation
+	// @Description End the impersonation session the current token belongs to; the token stops working immediately
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Router /profile/impersonation [delete]
+	userProfileGroup.DELETE("/impersonation", userController.stopImpersonation)
+
 	// @Summary Get current user profile
 	// @Description Retrieve the profile of the authenticated user
 	// @Tags Profile
@@ -261,8 +609,25 @@ func UserRouters(db *gorm.DB, v1 *echo.Group) {
 	// @Router /profile [patch]
 	userProfileGroup.PATCH("", userController.updateProfile)
 
+	// @Summary Change password
+	// @Description Change the password of the authenticated user. Requires the current password, applies the password policy and signs out every other session
+	// @Tags Profile
+	// @Security BearerAuth
+	// @Accept json
+	// @Produce json
+	// @Param payload body request.ChangePasswordRequest true "Change password payload"
+	// @Success 200 {object} response.StandardResponseOk
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
+	// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
+	// @Router /profile/password [put]
+	userProfileGroup.PUT("/password", userController.changePassword, middleware.NoImpersonationMiddleware,
+		middleware.RateLimitMiddleware(throttleService, "change-password", 5, 15*time.Minute))
+
 	// @Summary Get AI Settings
-	// @Description Get the current user's AI server configuration
+	// @Description Get the current user's AI server configuration. Only the last characters of the API key are returned
 	// @Tags Profile
 	// @Security BearerAuth
 	// @Success 200 {object} response.StandardResponseOk
@@ -272,14 +637,15 @@ func UserRouters(db *gorm.DB, v1 *echo.Group) {
 	userProfileGroup.GET("/ai-settings", userController.getAISettings)
 
 	// @Summary Update AI Settings
-	// @Description Update the current user's AI server configuration
+	// @Description Update the current user's AI server configuration: the provider (openai, anthropic, ollama or azure), its base URL, API key and model. Leave apiKey empty, or send the masked key back, to keep the stored key; changing the provider or base URL requires the key again. Not available while impersonating
 	// @Tags Profile
 	// @Security BearerAuth
 	// @Param payload body request.UpdateAISettingsRequest true "AI Settings payload"
 	// @Success 200 {object} response.StandardResponseOk
 	// @Failure 400 {object} response.StandardResponseError
 	// @Failure 401 {object} response.StandardResponseError
+	// @Failure 403 {object} response.StandardResponseError
 	// @Failure 500 {object} response.StandardResponseError
 	// @Router /profile/ai-settings [put]
-	userProfileGroup.PUT("/ai-settings", userController.updateAISettings)
+	userProfileGroup.PUT("/ai-settings", userController.updateAISettings, middleware.NoImpersonationMiddleware)
 }
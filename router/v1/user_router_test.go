@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/service"
+	"SimpleToDo/util/mapper"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const (
+	testManagerRoleId = 2
+	testAdminUserId   = 1
+	testUserId        = 2
+)
+
+// newTestUserController returns a UserController on a fresh database holding an administrator and a
+// user, and a manager role besides the admin one.
+func newTestUserController(t *testing.T) (*UserController, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening the database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}, &models.SecurityEvent{}); err != nil {
+		t.Fatalf("migrating the database: %v", err)
+	}
+	roles := []models.Role{{ID: service.AdminRoleId, Name: "Admin"}, {ID: testManagerRoleId, Name: "Manager"}}
+	users := []models.User{
+		{Model: gorm.Model{ID: testAdminUserId}, Username: "admin", Email: "admin@example.com", Password: "x", RoleId: service.AdminRoleId},
+		{Model: gorm.Model{ID: testUserId}, Username: "user", Email: "user@example.com", Password: "x", RoleId: testManagerRoleId},
+	}
+	if err := db.Create(&roles).Error; err != nil {
+		t.Fatalf("creating the roles: %v", err)
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("creating the users: %v", err)
+	}
+
+	userService := service.NewUserService(repository.NewUserRepository(db), repository.NewAIServerRepository(db), nil,
+		mapper.NewUserMapperImpl(), service.NewAuditService(repository.NewSecurityEventRepository(db)))
+	return NewUserController(userService, nil, nil), db
+}
+
+// newTestContext returns the context of a request on the user with the given id, made by a caller with
+// the given role.
+func newTestContext(method string, callerRoleId uint, id uint) (echo.Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(method, "/", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(int(id)))
+	c.Set("user_id", float64(99))
+	c.Set("user_role", float64(callerRoleId))
+	return c, rec
+}
+
+func TestDeleteUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		callerRoleId uint
+		id           uint
+		wantStatus   int
+	}{
+		{"manager deletes an administrator", testManagerRoleId, testAdminUserId, http.StatusForbidden},
+		{"manager deletes a user", testManagerRoleId, testUserId, http.StatusOK},
+		{"administrator deletes an administrator", service.AdminRoleId, testAdminUserId, http.StatusOK},
+		{"unknown user", testManagerRoleId, 42, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, db := newTestUserController(t)
+			c, rec := newTestContext(http.MethodDelete, tt.callerRoleId, tt.id)
+			if err := uc.deleteUser(c); err != nil {
+				t.Fatalf("deleteUser() error = %v", err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			err := db.First(&models.User{}, tt.id).Error
+			deleted := errors.Is(err, gorm.ErrRecordNotFound)
+			if wantDeleted := tt.wantStatus != http.StatusForbidden; deleted != wantDeleted {
+				t.Errorf("user deleted = %v, want %v", deleted, wantDeleted)
+			}
+		})
+	}
+}
+
+func TestManagerCannotActOnAdministrator(t *testing.T) {
+	uc, db := newTestUserController(t)
+	handlers := map[string]echo.HandlerFunc{
+		"updateUser":             uc.updateUser,
+		"deleteUser":             uc.deleteUser,
+		"unlockUser":             uc.unlockUser,
+		"suspendUser":            uc.suspendUser,
+		"reactivateUser":         uc.reactivateUser,
+		"verifyUser":             uc.verifyUser,
+		"resendUserVerification": uc.resendUserVerification,
+		"triggerPasswordReset":   uc.triggerPasswordReset,
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodPost, testManagerRoleId, testAdminUserId)
+			if err := handler(c); err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body)
+			}
+		})
+	}
+
+	var admin models.User
+	if err := db.First(&admin, testAdminUserId).Error; err != nil {
+		t.Fatalf("the administrator is gone: %v", err)
+	}
+	if admin.Suspended || admin.Verified {
+		t.Errorf("the administrator was changed: %+v", admin)
+	}
+}
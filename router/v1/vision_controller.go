@@ -6,6 +6,8 @@ import (
 	"SimpleToDo/middleware"
 	"SimpleToDo/repository"
 	"SimpleToDo/service"
+	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -15,10 +17,11 @@ import (
 
 type VisionController struct {
 	VisionService *service.VisionService
+	PromptService *service.PromptService
 }
 
-func NewVisionController(visionService *service.VisionService) *VisionController {
-	return &VisionController{VisionService: visionService}
+func NewVisionController(visionService *service.VisionService, promptService *service.PromptService) *VisionController {
+	return &VisionController{VisionService: visionService, PromptService: promptService}
 }
 
 func (vc *VisionController) analyzeImage(c echo.Context) error {
@@ -44,37 +47,95 @@ func (vc *VisionController) analyzeImage(c echo.Context) error {
 		return response.WriteJSONResponse(c, http.StatusBadRequest, "Validation failed", err.Error(), true)
 	}
 
-	ctx := c.Request().Context()
+	if body.Async {
+		job, err := vc.VisionService.AnalyzeImageAsync(uint(userID), projectIdInt, body)
+		if err != nil {
+			return writeVisionError(c, err)
+		}
+		c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/v1/jobs/%d", job.Id))
+		return response.WriteJSONResponse(c, http.StatusAccepted, "Image analysis queued", job, false)
+	}
 
-	taskCreated, err := vc.VisionService.ExtractTaskFromImage(ctx, uint(userID), uint(projectIdInt), body.ImageBase64)
+	result, err := vc.VisionService.AnalyzeImage(c.Request().Context(), uint(userID), projectIdInt, body)
 	if err != nil {
+		return writeVisionError(c, err)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Image analyzed successfully", result, false)
+}
+
+func writeVisionError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrAINotConfigured):
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "AI processing failed", err.Error(), true)
+	case errors.Is(err, service.ErrProjectNotFound):
+		return response.WriteJSONResponse(c, http.StatusNotFound, "Project not found", err.Error(), true)
+	case errors.Is(err, service.ErrPromptNotFound):
+		return response.WriteJSONResponse(c, http.StatusNotFound, "Prompt not found", err.Error(), true)
+	case errors.Is(err, service.ErrAIQuotaExceeded):
+		return response.WriteJSONResponse(c, http.StatusTooManyRequests, "AI quota exceeded", err.Error(), true)
+	case errors.Is(err, service.ErrNoTasksExtracted), errors.Is(err, service.ErrAIInvalidOutput):
+		return response.WriteJSONResponse(c, http.StatusUnprocessableEntity, "AI processing failed", err.Error(), true)
+	default:
 		return response.WriteJSONResponse(c, http.StatusInternalServerError, "AI processing failed", err.Error(), true)
 	}
+}
+
+func (vc *VisionController) getPrompts(c echo.Context) error {
+	pagination, err := validatePagination(c)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusBadRequest, "Bad request error", err.Error(), true)
+	}
 
-	return response.WriteJSONResponse(c, http.StatusOK, "Image analyzed successfully", taskCreated, false)
+	prompts, err := vc.PromptService.GetSummaries(pagination)
+	if err != nil {
+		return response.WriteJSONResponse(c, http.StatusInternalServerError, "Error fetching prompts", err.Error(), true)
+	}
+	return response.WriteJSONResponse(c, http.StatusOK, "Prompts fetched successfully", prompts, false)
 }
 
 func VisionRouters(db *gorm.DB, v1 *echo.Group) {
 	aiRepo := repository.NewAIServerRepository(db)
 	promptRepo := repository.NewPromptRepository(db)
-	taskService := service.NewTaskService(repository.NewTaskRepository(db), repository.NewStatusRepository(db), nil)
+	preferencesService := service.NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db))
+	taskService := service.NewTaskService(repository.NewTaskRepository(db), repository.NewStatusRepository(db),
+		repository.NewProjectRepository(db), nil, preferencesService)
+	aiUsageService := service.NewAIUsageService(repository.NewAIUsageRepository(db), repository.NewSettingRepository(db),
+		repository.NewUserRepository(db))
 
-	visionService := service.NewVisionService(aiRepo, promptRepo, taskService)
-	visionController := NewVisionController(visionService)
+	visionService := service.NewVisionService(aiRepo, promptRepo, repository.NewProjectRepository(db), taskService,
+		preferencesService, service.NewJobService(repository.NewJobRepository(db)), aiUsageService)
+	visionController := NewVisionController(visionService, service.NewPromptService(promptRepo))
 
 	visionGroup := v1.Group("/vision")
 	visionGroup.Use(middleware.JWTMiddleware)
 
 	// @Summary Analyze Image
-	// @Description Extract task details from image using AI with User settings
+	// @Description Extract the action items in an image as tasks using AI with User settings, and create them all in the project. With preview they are returned without being saved. The image is analyzed with promptId if given, else with the user's default prompt, else with the default prompt. With async the analysis is queued as a job and 202 is returned with the job, whose Location is GET /jobs/{id}; the job's result is what the request would have returned
 	// @Tags Vision
 	// @Security BearerAuth
 	// @Accept json
 	// @Produce json
 	// @Param payload body request.AnalyzeImageRequest true "Base64 Image"
 	// @Success 200 {object} response.StandardResponseOk
+	// @Success 202 {object} response.StandardResponseOk
 	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 404 {object} response.StandardResponseError
+	// @Failure 422 {object} response.StandardResponseError
+	// @Failure 429 {object} response.StandardResponseError
 	// @Failure 500 {object} response.StandardResponseError
 	// @Router /vision/analyze [post]
 	visionGroup.POST("/analyze/:projectId", visionController.analyzeImage)
+
+	// @Summary List vision prompts
+	// @Description List the prompts that can be chosen for image analysis, marking the default one
+	// @Tags Vision
+	// @Security BearerAuth
+	// @Param page query int false "Page number"
+	// @Param limit query int false "Items per page"
+	// @Success 200 {object} response.StandardResponseOkPaginated
+	// @Failure 400 {object} response.StandardResponseError
+	// @Failure 401 {object} response.StandardResponseError
+	// @Router /vision/prompts [get]
+	visionGroup.GET("/prompts", visionController.getPrompts)
 }
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"SimpleToDo/util/jwtkeys"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// WellKnownRouters serves discovery documents outside the versioned API, so other services
+// can verify tokens issued by this one without sharing a secret.
+func WellKnownRouters(e *echo.Echo) {
+	e.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "public, max-age=300")
+		return c.JSON(http.StatusOK, jwtkeys.Default().PublicJWKS())
+	})
+}
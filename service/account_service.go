@@ -0,0 +1,256 @@
+package service
+
+import (
+	"SimpleToDo/config"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/mailer"
+	"SimpleToDo/util/mapper"
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+var ErrAccountDeletionNotAllowed = errors.New("account deletion not allowed")
+
+type AccountService struct {
+	AccountRepository  *repository.AccountRepository
+	UserRepository     *repository.UserRepository
+	AIServerRepository *repository.AIServerRepository
+	RoleRepository     *repository.RoleRepository
+	AuthService        *AuthService
+	UserMapper         *mapper.UserMapperImpl
+	ProjectMapper      *mapper.ProjectMapperImpl
+	TaskMapper         *mapper.TaskMapperImpl
+}
+
+func NewAccountService(accountRepo *repository.AccountRepository, userRepo *repository.UserRepository,
+	aiRepo *repository.AIServerRepository, roleRepo *repository.RoleRepository, authService *AuthService,
+	userMapper *mapper.UserMapperImpl, projectMapper *mapper.ProjectMapperImpl, taskMapper *mapper.TaskMapperImpl) *AccountService {
+	return &AccountService{
+		AccountRepository:  accountRepo,
+		UserRepository:     userRepo,
+		AIServerRepository: aiRepo,
+		RoleRepository:     roleRepo,
+		AuthService:        authService,
+		UserMapper:         userMapper,
+		ProjectMapper:      projectMapper,
+		TaskMapper:         taskMapper,
+	}
+}
+
+// Export writes a ZIP archive with everything stored about the user as JSON files. The largest
+// rendition of the avatar, if any, is added as an image file and the AI API key is redacted.
+func (s *AccountService) Export(userID uint, w io.Writer, info RequestInfo) error {
+	user, err := s.UserRepository.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	profile := s.UserMapper.ToDto(user)
+
+	ownedProjects, err := s.AccountRepository.FindOwnedProjects(userID)
+	if err != nil {
+		return err
+	}
+	sharedProjects, err := s.AccountRepository.FindMemberProjects(userID)
+	if err != nil {
+		return err
+	}
+	projects := response.AccountExportProjectsDto{
+		Owned:  make([]response.ProjectResponseDto, 0),
+		Shared: make([]response.ProjectResponseDto, 0),
+	}
+	for _, project := range ownedProjects {
+		projects.Owned = append(projects.Owned, s.ProjectMapper.ToDto(&project))
+	}
+	for _, project := range sharedProjects {
+		projects.Shared = append(projects.Shared, s.ProjectMapper.ToDto(&project))
+	}
+
+	taskList, err := s.AccountRepository.FindTasks(userID)
+	if err != nil {
+		return err
+	}
+	tasks := make([]response.TaskResponseDto, 0)
+	for _, task := range taskList {
+		tasks = append(tasks, s.TaskMapper.ToDto(&task))
+	}
+
+	// Only the prompts the user's AI calls were recorded with, and only what they were shown of them.
+	promptList, err := s.AccountRepository.FindUsedPrompts(userID)
+	if err != nil {
+		return err
+	}
+	prompts := make([]response.AccountExportPromptDto, 0)
+	for _, prompt := range promptList {
+		prompts = append(prompts, response.AccountExportPromptDto{
+			Title:       prompt.Title,
+			Description: prompt.Description,
+		})
+	}
+
+	var aiSettings *response.AISettingsResponseDto
+	if settings, err := s.AIServerRepository.FindByUserID(userID); err == nil {
+		aiSettings = &response.AISettingsResponseDto{BaseUrl: settings.BaseUrl, Model: settings.Model}
+		if settings.APIKey != "" {
+			aiSettings.APIKey = "REDACTED"
+		}
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"projects.json", projects},
+		{"tasks.json", tasks},
+		{"prompts.json", prompts},
+		{"ai_settings.json", aiSettings},
+	}
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	if avatar, err := s.AccountRepository.FindAvatar(userID); err == nil {
+		name := "avatar.png"
+		if avatar.ContentType == "image/jpeg" {
+			name = "avatar.jpg"
+		}
+		f, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(avatar.Data); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	s.AuthService.AuditService.Record(info, models.SecurityEventAccountExported, models.SecurityOutcomeSuccess, user, "")
+	return nil
+}
+
+// RequestDeletion schedules the account for deletion after the configured grace period and signs it
+// out everywhere. Signing in again and cancelling before the date keeps the account.
+func (s *AccountService) RequestDeletion(userID uint, password string, info RequestInfo) (*time.Time, error) {
+	user, err := s.AuthService.VerifyPassword(userID, password, info)
+	if err != nil {
+		s.AuthService.AuditService.RecordResult(info, models.SecurityEventDeletionRequested,
+			s.AuthService.AuthRepository.FindById(userID), err)
+		return nil, err
+	}
+	privileged, err := s.RoleRepository.HasPermission(user.RoleId, models.PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+	if privileged {
+		return nil, fmt.Errorf("%w: administrators must give up their role before deleting their account", ErrAccountDeletionNotAllowed)
+	}
+
+	grace := config.GetAppEnv().AccountDeletionGrace
+	if grace == 0 {
+		if err := s.AccountRepository.Purge(user.ID); err != nil {
+			return nil, err
+		}
+		s.AuthService.AuditService.Record(info, models.SecurityEventAccountPurged, models.SecurityOutcomeSuccess, user, "")
+		fmt.Printf("Account %d deleted\n", user.ID)
+		now := time.Now()
+		return &now, nil
+	}
+
+	deleteAt := time.Now().Add(grace)
+	if err := s.AccountRepository.ScheduleDeletion(user.ID, &deleteAt); err != nil {
+		return nil, err
+	}
+	if err := s.AuthService.AuthRepository.RevokeUserTokens(user.ID, time.Now().Truncate(time.Second)); err != nil {
+		return nil, err
+	}
+	s.AuthService.AuditService.Record(info, models.SecurityEventDeletionRequested, models.SecurityOutcomeSuccess, user,
+		"scheduled for "+deleteAt.UTC().Format(time.RFC3339))
+	s.sendDeletionScheduledEmail(user, deleteAt)
+	return &deleteAt, nil
+}
+
+func (s *AccountService) CancelDeletion(userID uint, info RequestInfo) error {
+	user, err := s.UserRepository.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.AccountRepository.ScheduleDeletion(userID, nil); err != nil {
+		return err
+	}
+	s.AuthService.AuditService.Record(info, models.SecurityEventDeletionCancelled, models.SecurityOutcomeSuccess, user, "")
+	return nil
+}
+
+// PurgeDueAccounts hard-deletes every account whose grace period is over.
+func (s *AccountService) PurgeDueAccounts() {
+	users, err := s.AccountRepository.FindDueForDeletion(time.Now())
+	if err != nil {
+		fmt.Printf("Error finding accounts to delete: %v\n", err)
+		return
+	}
+	for _, user := range users {
+		if err := s.AccountRepository.Purge(user.ID); err != nil {
+			fmt.Printf("Error deleting account %d: %v\n", user.ID, err)
+			continue
+		}
+		s.AuthService.AuditService.Record(RequestInfo{}, models.SecurityEventAccountPurged, models.SecurityOutcomeSuccess,
+			&user, "grace period over")
+		fmt.Printf("Account %d deleted\n", user.ID)
+	}
+}
+
+// RunDeletionPurger calls PurgeDueAccounts every interval. It never returns.
+func (s *AccountService) RunDeletionPurger(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.PurgeDueAccounts()
+		<-ticker.C
+	}
+}
+
+func (s *AccountService) sendDeletionScheduledEmail(user *models.User, deleteAt time.Time) {
+	m, err := mailer.New()
+	if err != nil {
+		fmt.Printf("Error sending account deletion email: %v\n", err)
+		return
+	}
+
+	preferences := s.AuthService.PreferencesService
+	msg, err := mailer.Render(mailer.AccountDeletion, preferences.Locale(user.ID), map[string]string{
+		"Username":     user.FirstName,
+		"DeletionDate": preferences.FormatDateTime(user.ID, deleteAt),
+		"LoginURL":     config.GetAppEnv().BaseURL + "/auth",
+		"Year":         strconv.Itoa(time.Now().Year()),
+	})
+	if err != nil {
+		fmt.Printf("Error rendering account deletion email: %v\n", err)
+		return
+	}
+
+	go func() {
+		errSent := m.SendMessage(user.Email, msg)
+		if errSent != nil {
+			fmt.Printf("Error sending account deletion email: %v\n", errSent)
+		} else {
+			fmt.Println("Account deletion email sent successfully")
+		}
+	}()
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"SimpleToDo/models"
+	"SimpleToDo/util/aiprovider"
+	"errors"
+)
+
+var ErrAINotConfigured = errors.New("AI configuration not found for user")
+
+// ErrAIInvalidOutput matches the error of AI replies that did not have the expected format, even after
+// the model was asked to repair them.
+var ErrAIInvalidOutput = aiprovider.ErrInvalidOutput
+
+// aiProviderFor returns the AI provider configured in the user's AI settings.
+func aiProviderFor(settings *models.AIServerSettings) (aiprovider.Provider, error) {
+	return aiprovider.New(aiprovider.Config{
+		Provider:   settings.Provider,
+		BaseURL:    settings.BaseUrl,
+		APIKey:     settings.APIKey,
+		Model:      settings.Model,
+		APIVersion: settings.APIVersion,
+	})
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/aiprovider"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrAIQuotaExceeded = errors.New("monthly AI quota exceeded")
+	ErrAIQuotaNotFound = errors.New("the user has no AI quota of their own")
+	ErrAIPriceNotFound = errors.New("the model has no price")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrAIUsageRange    = errors.New("invalid report range")
+)
+
+const (
+	settingAIQuotaTokens   = "ai.quota.monthly_tokens"
+	settingAIQuotaRequests = "ai.quota.monthly_requests"
+
+	defaultAIUsageReportDays = 30
+	maxAIUsageReportDays     = 366
+	maxAIUsageErrorLength    = 500
+)
+
+type AIUsageService struct {
+	AIUsageRepository *repository.AIUsageRepository
+	SettingRepository *repository.SettingRepository
+	UserRepository    *repository.UserRepository
+}
+
+func NewAIUsageService(usageRepo *repository.AIUsageRepository, settingRepo *repository.SettingRepository,
+	userRepo *repository.UserRepository) *AIUsageService {
+	return &AIUsageService{AIUsageRepository: usageRepo, SettingRepository: settingRepo, UserRepository: userRepo}
+}
+
+// Provider returns the provider of the user's AI settings for feature. Every call it makes is checked
+// against the user's quota first and recorded afterwards, with promptID when the prompt came from the
+// prompts table.
+func (s *AIUsageService) Provider(settings *models.AIServerSettings, feature string, promptID *uint) (aiprovider.Provider, error) {
+	provider, err := aiProviderFor(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &meteredProvider{
+		provider:     provider,
+		usage:        s,
+		userID:       settings.UserID,
+		feature:      feature,
+		promptID:     promptID,
+		providerName: settings.Provider,
+		model:        settings.Model,
+	}, nil
+}
+
+// CheckQuota returns an error wrapping ErrAIQuotaExceeded when the user used up a limit of this month.
+func (s *AIUsageService) CheckQuota(userID uint) error {
+	status, err := s.QuotaStatus(userID)
+	if err != nil {
+		return err
+	}
+	return quotaExceeded(status)
+}
+
+// reserve records the call in usage unless the user used up a limit of this month, in which case it
+// returns the error of CheckQuota. The check and the record are a single transaction, so that concurrent
+// calls cannot all pass the check.
+func (s *AIUsageService) reserve(usage *models.AIUsage) error {
+	quota, err := s.quotaFor(usage.UserID)
+	if err != nil {
+		return err
+	}
+	monthStart := currentMonthStart()
+	return s.AIUsageRepository.Reserve(usage, monthStart, func(tokens, requests int64) error {
+		return quotaExceeded(&response.AIQuotaStatusDto{
+			MonthlyTokens:   quota.MonthlyTokens,
+			MonthlyRequests: quota.MonthlyRequests,
+			UsedTokens:      tokens,
+			UsedRequests:    requests,
+			ResetsAt:        monthStart.AddDate(0, 1, 0),
+		})
+	})
+}
+
+func quotaExceeded(status *response.AIQuotaStatusDto) error {
+	if status.MonthlyRequests > 0 && status.UsedRequests >= status.MonthlyRequests {
+		return fmt.Errorf("%w: %d of %d requests used, resets at %s", ErrAIQuotaExceeded, status.UsedRequests,
+			status.MonthlyRequests, status.ResetsAt.Format(time.RFC3339))
+	}
+	if status.MonthlyTokens > 0 && status.UsedTokens >= status.MonthlyTokens {
+		return fmt.Errorf("%w: %d of %d tokens used, resets at %s", ErrAIQuotaExceeded, status.UsedTokens,
+			status.MonthlyTokens, status.ResetsAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// QuotaStatus returns the quota of the user and what they used of it this month.
+func (s *AIUsageService) QuotaStatus(userID uint) (*response.AIQuotaStatusDto, error) {
+	quota, err := s.quotaFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	monthStart := currentMonthStart()
+	tokens, requests, err := s.AIUsageRepository.UsageSince(userID, monthStart)
+	if err != nil {
+		return nil, err
+	}
+	return &response.AIQuotaStatusDto{
+		MonthlyTokens:   quota.MonthlyTokens,
+		MonthlyRequests: quota.MonthlyRequests,
+		UsedTokens:      tokens,
+		UsedRequests:    requests,
+		ResetsAt:        monthStart.AddDate(0, 1, 0),
+	}, nil
+}
+
+// currentMonthStart returns the start of the calendar month (UTC) quotas are counted in.
+func currentMonthStart() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// quotaFor returns the user's own quota, else the default one.
+func (s *AIUsageService) quotaFor(userID uint) (*response.AIQuotaDto, error) {
+	quota, err := s.AIUsageRepository.FindQuota(userID)
+	if err == nil {
+		return &response.AIQuotaDto{UserId: userID, MonthlyTokens: quota.MonthlyTokens, MonthlyRequests: quota.MonthlyRequests}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return s.defaultQuota()
+}
+
+func (s *AIUsageService) defaultQuota() (*response.AIQuotaDto, error) {
+	tokens, err := s.SettingRepository.Get(settingAIQuotaTokens, "0")
+	if err != nil {
+		return nil, err
+	}
+	requests, err := s.SettingRepository.Get(settingAIQuotaRequests, "0")
+	if err != nil {
+		return nil, err
+	}
+	quota := &response.AIQuotaDto{}
+	quota.MonthlyTokens, _ = strconv.ParseInt(tokens, 10, 64)
+	quota.MonthlyRequests, _ = strconv.ParseInt(requests, 10, 64)
+	return quota, nil
+}
+
+// GetReport sums the AI calls by day, user and model. A userID of 0 reports every user, or the one of
+// filter.UserId. The report of a single user includes their quota.
+func (s *AIUsageService) GetReport(userID uint, filter request.AIUsageFilter) (*response.AIUsageReportDto, error) {
+	from, to, err := reportRange(filter)
+	if err != nil {
+		return nil, err
+	}
+	ownReport := userID != 0
+	if !ownReport {
+		userID = filter.UserId
+	}
+
+	rows, err := s.AIUsageRepository.Report(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.AIUsageRepository.FindPrices()
+	if err != nil {
+		return nil, err
+	}
+	priceOf := make(map[string]models.AIModelPrice, len(prices))
+	for _, price := range prices {
+		priceOf[price.Model] = price
+	}
+
+	report := &response.AIUsageReportDto{From: from, To: to, Rows: make([]response.AIUsageRowDto, 0, len(rows))}
+	for _, row := range rows {
+		dto := response.AIUsageRowDto{
+			Day:          row.Day,
+			UserId:       row.UserID,
+			Model:        row.Model,
+			Requests:     row.Requests,
+			Failures:     row.Failures,
+			InputTokens:  row.InputTokens,
+			OutputTokens: row.OutputTokens,
+		}
+		if row.Requests > 0 {
+			dto.AvgLatencyMs = row.LatencyMs / row.Requests
+		}
+		if price, ok := priceOf[row.Model]; ok {
+			cost := (float64(row.InputTokens)*price.InputPerMillion + float64(row.OutputTokens)*price.OutputPerMillion) / 1e6
+			dto.Cost = &cost
+			total := cost
+			if report.Totals.Cost != nil {
+				total += *report.Totals.Cost
+			}
+			report.Totals.Cost = &total
+		}
+		report.Rows = append(report.Rows, dto)
+		report.Totals.Requests += row.Requests
+		report.Totals.Failures += row.Failures
+		report.Totals.InputTokens += row.InputTokens
+		report.Totals.OutputTokens += row.OutputTokens
+	}
+
+	if ownReport {
+		if report.Quota, err = s.QuotaStatus(userID); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// reportRange returns the days of the filter, defaulting to the last defaultAIUsageReportDays.
+func reportRange(filter request.AIUsageFilter) (string, string, error) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if filter.To != "" {
+		to, _ = time.Parse("2006-01-02", filter.To)
+	}
+	from := to.AddDate(0, 0, 1-defaultAIUsageReportDays)
+	if filter.From != "" {
+		from, _ = time.Parse("2006-01-02", filter.From)
+	}
+	if from.After(to) {
+		return "", "", fmt.Errorf("%w: from must not be after to", ErrAIUsageRange)
+	}
+	if to.Sub(from) >= maxAIUsageReportDays*24*time.Hour {
+		return "", "", fmt.Errorf("%w: the report can cover at most %d days", ErrAIUsageRange, maxAIUsageReportDays)
+	}
+	return from.Format("2006-01-02"), to.Format("2006-01-02"), nil
+}
+
+// GetQuotas lists the default quota and the users with a quota of their own.
+func (s *AIUsageService) GetQuotas() (*response.AIQuotasResponseDto, error) {
+	defaultQuota, err := s.defaultQuota()
+	if err != nil {
+		return nil, err
+	}
+	quotas, err := s.AIUsageRepository.FindQuotas()
+	if err != nil {
+		return nil, err
+	}
+	result := &response.AIQuotasResponseDto{Default: *defaultQuota, Users: make([]response.AIQuotaDto, 0, len(quotas))}
+	for _, quota := range quotas {
+		result.Users = append(result.Users, response.AIQuotaDto{
+			UserId:          quota.UserID,
+			MonthlyTokens:   quota.MonthlyTokens,
+			MonthlyRequests: quota.MonthlyRequests,
+		})
+	}
+	return result, nil
+}
+
+// SetDefaultQuota sets the quota of the users without one of their own.
+func (s *AIUsageService) SetDefaultQuota(data request.AIQuotaRequestDto) (*response.AIQuotaDto, error) {
+	if err := s.SettingRepository.Set(settingAIQuotaTokens, strconv.FormatInt(data.MonthlyTokens, 10)); err != nil {
+		return nil, err
+	}
+	if err := s.SettingRepository.Set(settingAIQuotaRequests, strconv.FormatInt(data.MonthlyRequests, 10)); err != nil {
+		return nil, err
+	}
+	return s.defaultQuota()
+}
+
+func (s *AIUsageService) SetUserQuota(userID uint, data request.AIQuotaRequestDto) (*response.AIQuotaDto, error) {
+	if _, err := s.UserRepository.FindByID(userID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	quota := &models.AIQuota{UserID: userID, MonthlyTokens: data.MonthlyTokens, MonthlyRequests: data.MonthlyRequests}
+	if err := s.AIUsageRepository.SaveQuota(quota); err != nil {
+		return nil, err
+	}
+	return &response.AIQuotaDto{UserId: userID, MonthlyTokens: quota.MonthlyTokens, MonthlyRequests: quota.MonthlyRequests}, nil
+}
+
+// DeleteUserQuota removes the user's own quota, so that the default one applies again.
+func (s *AIUsageService) DeleteUserQuota(userID uint) error {
+	deleted, err := s.AIUsageRepository.DeleteQuota(userID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrAIQuotaNotFound
+	}
+	return nil
+}
+
+func (s *AIUsageService) GetPrices() (*response.AIModelPricesResponseDto, error) {
+	prices, err := s.AIUsageRepository.FindPrices()
+	if err != nil {
+		return nil, err
+	}
+	result := &response.AIModelPricesResponseDto{Prices: make([]response.AIModelPriceDto, 0, len(prices))}
+	for _, price := range prices {
+		result.Prices = append(result.Prices, toAIModelPriceResponse(&price))
+	}
+	return result, nil
+}
+
+func (s *AIUsageService) SetPrice(model string, data request.AIModelPriceRequestDto) (*response.AIModelPriceDto, error) {
+	price := &models.AIModelPrice{Model: model, InputPerMillion: data.InputPerMillion, OutputPerMillion: data.OutputPerMillion}
+	if err := s.AIUsageRepository.SavePrice(price); err != nil {
+		return nil, err
+	}
+	dto := toAIModelPriceResponse(price)
+	return &dto, nil
+}
+
+func (s *AIUsageService) DeletePrice(model string) error {
+	deleted, err := s.AIUsageRepository.DeletePrice(model)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrAIPriceNotFound
+	}
+	return nil
+}
+
+func toAIModelPriceResponse(price *models.AIModelPrice) response.AIModelPriceDto {
+	return response.AIModelPriceDto{
+		Model:            price.Model,
+		InputPerMillion:  price.InputPerMillion,
+		OutputPerMillion: price.OutputPerMillion,
+		UpdatedAt:        price.UpdatedAt,
+	}
+}
+
+// meteredProvider reserves a call within the quota of the user before every call of the provider and
+// records its outcome afterwards.
+type meteredProvider struct {
+	provider     aiprovider.Provider
+	usage        *AIUsageService
+	userID       uint
+	feature      string
+	promptID     *uint
+	providerName string
+	model        string
+}
+
+func (p *meteredProvider) Complete(ctx context.Context, req aiprovider.Request) (*aiprovider.Response, error) {
+	usage := &models.AIUsage{
+		UserID:   p.userID,
+		Feature:  p.feature,
+		Provider: p.providerName,
+		Model:    p.model,
+		PromptID: p.promptID,
+		// what a call keeps when the server stops before it returns
+		Error: "no reply recorded",
+		Day:   time.Now().UTC().Format("2006-01-02"),
+	}
+	if err := p.usage.reserve(usage); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := p.provider.Complete(ctx, req)
+	usage.LatencyMs = time.Since(start).Milliseconds()
+	usage.Success = err == nil
+	usage.Error = ""
+	if resp != nil {
+		usage.InputTokens = resp.InputTokens
+		usage.OutputTokens = resp.OutputTokens
+	}
+	if err != nil {
+		usage.Error = truncateRunes(err.Error(), maxAIUsageErrorLength)
+	}
+	// a lost record must not cost the user the reply they waited for
+	if errRecord := p.usage.AIUsageRepository.Complete(usage); errRecord != nil {
+		fmt.Printf("Error recording AI usage: %v\n", errRecord)
+	}
+	return resp, err
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestInfo identifies who performed an audited action and from where. ActorId is zero for
+// anonymous requests such as a login attempt.
+type RequestInfo struct {
+	ActorId        uint
+	ActorEmail     string
+	ImpersonatorId uint
+	IP             string
+	UserAgent      string
+}
+
+// as returns a copy of info acting as user, for flows where the caller is only known once the
+// credentials or token have been checked.
+func (info RequestInfo) as(user *models.User) RequestInfo {
+	info.ActorId = user.ID
+	info.ActorEmail = user.Email
+	return info
+}
+
+type AuditService struct {
+	SecurityEventRepository *repository.SecurityEventRepository
+}
+
+func NewAuditService(eventRepo *repository.SecurityEventRepository) *AuditService {
+	return &AuditService{SecurityEventRepository: eventRepo}
+}
+
+// Record appends an event to the security log. Failing to write it never fails the audited action.
+func (s *AuditService) Record(info RequestInfo, eventType, outcome string, target *models.User, detail string) {
+	event := &models.SecurityEvent{
+		EventType:      eventType,
+		Outcome:        outcome,
+		ActorId:        info.ActorId,
+		ActorEmail:     info.ActorEmail,
+		ImpersonatorId: info.ImpersonatorId,
+		IP:             info.IP,
+		UserAgent:      info.UserAgent,
+		Detail:         detail,
+	}
+	if target != nil {
+		event.TargetId = target.ID
+		event.TargetEmail = target.Email
+	}
+	if err := s.SecurityEventRepository.Save(event); err != nil {
+		fmt.Printf("Error recording security event %s: %v\n", eventType, err)
+	}
+}
+
+// RecordResult records eventType as a success when err is nil and as a failure carrying the error otherwise.
+func (s *AuditService) RecordResult(info RequestInfo, eventType string, target *models.User, err error) {
+	if err != nil {
+		s.Record(info, eventType, models.SecurityOutcomeFailure, target, err.Error())
+		return
+	}
+	s.Record(info, eventType, models.SecurityOutcomeSuccess, target, "")
+}
+
+func (s *AuditService) GetAll(filter request.SecurityEventFilter, pagination response.Pagination) (*response.Pagination, error) {
+	conditions, err := securityEventConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+	eventsPaginated, err := s.SecurityEventRepository.FindAll(conditions, pagination)
+	if err != nil {
+		return nil, err
+	}
+	events, ok := eventsPaginated.Items.([]*models.SecurityEvent)
+	if !ok {
+		return nil, errors.New("error converting security events to entity")
+	}
+
+	var eventsResponse = make([]response.SecurityEventResponseDto, 0)
+	for _, event := range events {
+		eventsResponse = append(eventsResponse, toSecurityEventDto(event))
+	}
+	eventsPaginated.Items = eventsResponse
+	return eventsPaginated, nil
+}
+
+// ExportCSV writes every event matching filter to w, newest first.
+func (s *AuditService) ExportCSV(filter request.SecurityEventFilter, w io.Writer) error {
+	conditions, err := securityEventConditions(filter)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "created_at", "type", "outcome", "actor_id", "actor_email",
+		"impersonator_id", "target_id", "target_email", "ip", "user_agent", "detail"}); err != nil {
+		return err
+	}
+	err = s.SecurityEventRepository.FindEach(conditions, func(events []*models.SecurityEvent) error {
+		for _, e := range events {
+			if err := writer.Write([]string{
+				strconv.FormatUint(uint64(e.ID), 10),
+				e.CreatedAt.UTC().Format(time.RFC3339),
+				e.EventType,
+				e.Outcome,
+				formatOptionalId(e.ActorId),
+				csvCell(e.ActorEmail),
+				formatOptionalId(e.ImpersonatorId),
+				formatOptionalId(e.TargetId),
+				csvCell(e.TargetEmail),
+				csvCell(e.IP),
+				csvCell(e.UserAgent),
+				csvCell(e.Detail),
+			}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func securityEventConditions(filter request.SecurityEventFilter) ([]response.Condition, error) {
+	var conditions []response.Condition
+	add := func(column string, operator response.Operator, value any) {
+		conditions = append(conditions, response.Condition{
+			Column:   column,
+			Operator: operator,
+			Value:    value,
+			Modifier: response.And,
+		})
+	}
+
+	if filter.Type != "" {
+		add("event_type", response.Equal, filter.Type)
+	}
+	if filter.Outcome != "" {
+		add("outcome", response.Equal, filter.Outcome)
+	}
+	if filter.ActorId != 0 {
+		add("actor_id", response.Equal, filter.ActorId)
+	}
+	if filter.TargetId != 0 {
+		add("target_id", response.Equal, filter.TargetId)
+	}
+	if filter.IP != "" {
+		add("ip", response.Equal, filter.IP)
+	}
+	if filter.From != "" {
+		from, err := time.Parse(time.RFC3339, filter.From)
+		if err != nil {
+			return nil, err
+		}
+		add("created_at", response.GreaterThanOrEqual, from)
+	}
+	if filter.To != "" {
+		to, err := time.Parse(time.RFC3339, filter.To)
+		if err != nil {
+			return nil, err
+		}
+		add("created_at", response.LessThanOrEqual, to)
+	}
+	return conditions, nil
+}
+
+// csvCell prefixes value with a quote when it starts like a formula, so spreadsheets opening the
+// export show it as text instead of evaluating it.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatOptionalId(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func toSecurityEventDto(event *models.SecurityEvent) response.SecurityEventResponseDto {
+	return response.SecurityEventResponseDto{
+		Id:             event.ID,
+		CreatedAt:      event.CreatedAt,
+		Type:           event.EventType,
+		Outcome:        event.Outcome,
+		ActorId:        event.ActorId,
+		ActorEmail:     event.ActorEmail,
+		ImpersonatorId: event.ImpersonatorId,
+		TargetId:       event.TargetId,
+		TargetEmail:    event.TargetEmail,
+		IP:             event.IP,
+		UserAgent:      event.UserAgent,
+		Detail:         event.Detail,
+	}
+}
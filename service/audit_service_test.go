@@ -0,0 +1,62 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"bytes"
+	"encoding/csv"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditServiceExportCSVEscapesFormulas(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening the database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SecurityEvent{}); err != nil {
+		t.Fatalf("migrating the database: %v", err)
+	}
+	s := NewAuditService(repository.NewSecurityEventRepository(db))
+	s.Record(RequestInfo{ActorEmail: "@SUM(1+1)", IP: "-1+1", UserAgent: "=HYPERLINK(\"http://evil\")"},
+		"login", models.SecurityOutcomeFailure, nil, "+cmd|' /C calc'!A0")
+	s.Record(RequestInfo{ActorEmail: "user@example.com", IP: "10.0.0.1", UserAgent: "curl/8.0"},
+		"login", models.SecurityOutcomeSuccess, nil, "a-b=c")
+
+	var buf bytes.Buffer
+	if err := s.ExportCSV(request.SecurityEventFilter{}, &buf); err != nil {
+		t.Fatalf("ExportCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the export: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("rows = %d, want 3", len(records))
+	}
+
+	tests := []struct {
+		name   string
+		row    int
+		column int
+		want   string
+	}{
+		{"actor email", 2, 5, "'@SUM(1+1)"},
+		{"ip", 2, 9, "'-1+1"},
+		{"user agent", 2, 10, "'=HYPERLINK(\"http://evil\")"},
+		{"detail", 2, 11, "'+cmd|' /C calc'!A0"},
+		{"plain actor email", 1, 5, "user@example.com"},
+		{"plain user agent", 1, 10, "curl/8.0"},
+		{"plain detail", 1, 11, "a-b=c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := records[tt.row][tt.column]; got != tt.want {
+				t.Errorf("cell = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
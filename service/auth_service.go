@@ -1,42 +1,85 @@
 package service
 
 import (
-	embedfs "SimpleToDo"
 	"SimpleToDo/config"
 	"SimpleToDo/models"
 	"SimpleToDo/repository"
+	"SimpleToDo/util/jwtkeys"
 	"SimpleToDo/util/mailer"
-	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
-	"html/template"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type AuthService struct {
-	AuthRepository *repository.AuthRepository
+	AuthRepository        *repository.AuthRepository
+	ThrottleService       *ThrottleService
+	PasswordPolicyService *PasswordPolicyService
+	AuditService          *AuditService
+	PreferencesService    *PreferencesService
 }
 
-func NewAuthService(authRepository *repository.AuthRepository) *AuthService {
-	return &AuthService{AuthRepository: authRepository}
+func NewAuthService(authRepository *repository.AuthRepository, throttleService *ThrottleService,
+	passwordPolicyService *PasswordPolicyService, auditService *AuditService,
+	preferencesService *PreferencesService) *AuthService {
+	return &AuthService{
+		AuthRepository:        authRepository,
+		ThrottleService:       throttleService,
+		PasswordPolicyService: passwordPolicyService,
+		AuditService:          auditService,
+		PreferencesService:    preferencesService,
+	}
 }
 
-func (s *AuthService) RegisterUser(user *models.User) error {
+func (s *AuthService) RegisterUser(user *models.User, info RequestInfo) error {
 	existing := s.AuthRepository.FindByEmail(user.Email)
 	if existing != nil {
 		return errors.New("user already exists")
 	}
-	return s.AuthRepository.Save(user)
+	if err := s.PasswordPolicyService.Validate(user.Password, user); err != nil {
+		return err
+	}
+	if err := s.AuthRepository.Save(user); err != nil {
+		return err
+	}
+	s.AuditService.Record(info.as(user), models.SecurityEventRegister, models.SecurityOutcomeSuccess, user, "")
+	return s.PasswordPolicyService.Record(user.ID, user.Password)
 }
 
-func (s *AuthService) LoginUser(email, password string) (string, error) {
+// LoginUser checks the credentials and issues a JWT. Every attempt, successful or not, is written to the
+// security log; failures against unknown emails keep the attempted address as the detail.
+func (s *AuthService) LoginUser(email, password string, info RequestInfo) (string, error) {
 	user := s.AuthRepository.FindByEmail(email)
+	token, err := s.login(user, email, password, info.IP)
 	if user == nil {
+		if err != nil {
+			s.AuditService.Record(info, models.SecurityEventLogin, models.SecurityOutcomeFailure, nil,
+				fmt.Sprintf("%v (%s)", err, email))
+		}
+		return token, err
+	}
+	s.AuditService.RecordResult(info.as(user), models.SecurityEventLogin, user, err)
+	return token, err
+}
+
+func (s *AuthService) login(user *models.User, email, password, ip string) (string, error) {
+	accountSubject := AccountFailureSubject(email)
+	ipSubject := IPFailureSubject(ip)
+	if err := s.ThrottleService.CheckFailures(accountSubject); err != nil {
+		return "", err
+	}
+	if err := s.ThrottleService.CheckFailures(ipSubject); err != nil {
+		return "", err
+	}
+
+	if user == nil {
+		s.registerLoginFailure(accountSubject, ipSubject)
 		return "", errors.New("invalid credentials")
 	}
 
@@ -47,22 +90,49 @@ func (s *AuthService) LoginUser(email, password string) (string, error) {
 
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
+		s.registerLoginFailure(accountSubject, ipSubject)
 		return "", errors.New("invalid credentials")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	if err := s.ThrottleService.Reset(accountSubject); err != nil {
+		return "", err
+	}
+	if user.Suspended {
+		return "", ErrAccountSuspended
+	}
+
+	return s.issueToken(user)
+}
+
+func (s *AuthService) issueToken(user *models.User) (string, error) {
+	return s.issueTokenWithClaims(user, jwtkeys.TokenTTL, nil)
+}
+
+func (s *AuthService) issueTokenWithClaims(user *models.User, ttl time.Duration, extra jwt.MapClaims) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
 		"role":    user.RoleId,
-		"exp":     time.Now().Add(72 * time.Hour).Unix(),
-	})
-	secret := config.GetAppEnv().JWTSecret
-	if secret == "" {
-		secret = "supersecretkey"
+		"iss":     config.GetAppEnv().BaseURL,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	return jwtkeys.Default().Sign(claims)
+}
+
+func (s *AuthService) registerLoginFailure(accountSubject, ipSubject string) {
+	if err := s.ThrottleService.RegisterFailure(accountSubject, AccountFailurePolicy); err != nil {
+		fmt.Printf("Error registering login failure: %v\n", err)
+	}
+	if err := s.ThrottleService.RegisterFailure(ipSubject, IPFailurePolicy); err != nil {
+		fmt.Printf("Error registering login failure: %v\n", err)
 	}
-	return token.SignedString([]byte(secret))
 }
 
-func (s *AuthService) generateToken(n int) (string, error) {
+func generateToken(n int) (string, error) {
 	b := make([]byte, n)
 	if _, err := rand.Read(b); err != nil {
 		return "", err
@@ -70,12 +140,13 @@ func (s *AuthService) generateToken(n int) (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
-func (s *AuthService) RequestPasswordReset(email string) error {
+func (s *AuthService) RequestPasswordReset(email string, info RequestInfo) error {
 	user := s.AuthRepository.FindByEmail(email)
 	if user == nil {
 		return nil
 	}
-	token, err := s.generateToken(32)
+	s.AuditService.Record(info, models.SecurityEventPasswordResetRequested, models.SecurityOutcomeSuccess, user, "")
+	token, err := generateToken(32)
 	if err != nil {
 		return nil
 	}
@@ -92,21 +163,18 @@ func (s *AuthService) RequestPasswordReset(email string) error {
 	base := config.GetAppEnv().BaseURL
 	link := fmt.Sprintf("%s/reseting-password?token=%s", base, token)
 
-	tpl, _ := template.ParseFS(embedfs.TemplatesFS, "config/static/templates/reset_password.html")
-	var body bytes.Buffer
-	errExc := tpl.Execute(&body, map[string]string{
+	msg, err := mailer.Render(mailer.ResetPassword, s.PreferencesService.Locale(user.ID), map[string]string{
 		"Username": user.FirstName,
 		"ResetURL": link,
 		"Year":     strconv.Itoa(time.Now().Year()),
 	})
-	if errExc != nil {
-		return err
+	if err != nil {
+		fmt.Printf("Error rendering reset password email: %v\n", err)
+		return nil
 	}
 
-	subject := "Reset your password"
-
 	go func() {
-		errSent := m.SendWithTemplate(user.Email, subject, body.String())
+		errSent := m.SendMessage(user.Email, msg)
 		if errSent != nil {
 			fmt.Printf("Error sending reset password email: %v\n", errSent)
 		} else {
@@ -116,26 +184,282 @@ func (s *AuthService) RequestPasswordReset(email string) error {
 	return nil
 }
 
-func (s *AuthService) ResetPassword(tokenPlain, newPassword string) error {
+func (s *AuthService) ResetPassword(tokenPlain, newPassword string, info RequestInfo) error {
 	t, err := s.AuthRepository.GetResetToken(tokenPlain)
 	if err != nil {
 		return errors.New("invalid token")
 	}
+	user := s.AuthRepository.FindById(t.UserID)
+	if user == nil {
+		return errors.New("invalid token")
+	}
 	if t.Used || time.Now().After(t.ExpiresAt) {
+		s.AuditService.Record(info, models.SecurityEventPasswordReset, models.SecurityOutcomeFailure, user, "expired or used token")
 		return errors.New("invalid token")
 	}
+	if err := s.PasswordPolicyService.Validate(newPassword, user); err != nil {
+		return err
+	}
 
-	if err := s.AuthRepository.UpdateUserPassword(t.UserID, newPassword); err != nil {
+	if err := s.setPassword(user.ID, newPassword); err != nil {
 		return err
 	}
 	if err := s.AuthRepository.MarkResetTokenUsed(t.ID); err != nil {
 		return err
 	}
+	s.AuditService.Record(info.as(user), models.SecurityEventPasswordReset, models.SecurityOutcomeSuccess, user, "")
+	return nil
+}
+
+// setPassword stores the new password, records it in the password history and revokes every
+// token issued so far.
+func (s *AuthService) setPassword(userID uint, newPassword string) error {
+	if err := s.AuthRepository.UpdateUserPassword(userID, newPassword); err != nil {
+		return err
+	}
+	user := s.AuthRepository.FindById(userID)
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if err := s.PasswordPolicyService.Record(user.ID, user.Password); err != nil {
+		return err
+	}
+	// iat has second precision, so the cut-off is truncated to keep the token issued right after valid
+	return s.AuthRepository.RevokeUserTokens(user.ID, time.Now().Truncate(time.Second))
+}
+
+// VerifyPassword re-authenticates a logged-in user before a sensitive action. Wrong passwords count
+// towards the same lockout as failed logins.
+func (s *AuthService) VerifyPassword(userID uint, password string, info RequestInfo) (*models.User, error) {
+	user := s.AuthRepository.FindById(userID)
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	accountSubject := AccountFailureSubject(user.Email)
+	ipSubject := IPFailureSubject(info.IP)
+	if err := s.ThrottleService.CheckFailures(accountSubject); err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.registerLoginFailure(accountSubject, ipSubject)
+		return nil, errors.New("invalid credentials")
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password of a logged-in user after checking the current one. Every other
+// session is revoked and a fresh token for the caller is returned.
+func (s *AuthService) ChangePassword(userID uint, currentPassword, newPassword string, info RequestInfo) (string, error) {
+	user, err := s.VerifyPassword(userID, currentPassword, info)
+	if err != nil {
+		s.AuditService.RecordResult(info, models.SecurityEventPasswordChanged, s.AuthRepository.FindById(userID), err)
+		return "", err
+	}
+	if err := s.PasswordPolicyService.Validate(newPassword, user); err != nil {
+		return "", err
+	}
+
+	if err := s.setPassword(user.ID, newPassword); err != nil {
+		return "", err
+	}
+	s.AuditService.Record(info, models.SecurityEventPasswordChanged, models.SecurityOutcomeSuccess, user, "")
+	s.sendPasswordChangedEmail(user)
+
+	return s.issueToken(user)
+}
+
+func (s *AuthService) sendPasswordChangedEmail(user *models.User) {
+	m, err := mailer.New()
+	if err != nil {
+		fmt.Printf("Error sending password changed email: %v\n", err)
+		return
+	}
+
+	msg, err := mailer.Render(mailer.PasswordChanged, s.PreferencesService.Locale(user.ID), map[string]string{
+		"Username":  user.FirstName,
+		"ChangedAt": s.PreferencesService.FormatDateTime(user.ID, time.Now()),
+		"Year":      strconv.Itoa(time.Now().Year()),
+	})
+	if err != nil {
+		fmt.Printf("Error rendering password changed email: %v\n", err)
+		return
+	}
+
+	go func() {
+		errSent := m.SendMessage(user.Email, msg)
+		if errSent != nil {
+			fmt.Printf("Error sending password changed email: %v\n", errSent)
+		} else {
+			fmt.Println("Password changed email sent successfully")
+		}
+	}()
+}
+
+const MagicLinkTTL = 15 * time.Minute
+
+// RequestMagicLink emails a single-use sign-in link. Like RequestPasswordReset it never reveals whether the
+// account exists.
+func (s *AuthService) RequestMagicLink(email string) error {
+	user := s.AuthRepository.FindByEmail(email)
+	if user == nil {
+		return nil
+	}
+	token, err := generateToken(32)
+	if err != nil {
+		return nil
+	}
+	_, err = s.AuthRepository.CreateMagicLinkToken(user.ID, token, MagicLinkTTL)
+	if err != nil {
+		return nil
+	}
+
+	m, err := mailer.New()
+	if err != nil {
+		return nil
+	}
+
+	base := config.GetAppEnv().BaseURL
+	link := fmt.Sprintf("%s/magic-login?token=%s", base, token)
+
+	msg, err := mailer.Render(mailer.MagicLink, s.PreferencesService.Locale(user.ID), map[string]string{
+		"Username": user.FirstName,
+		"LoginURL": link,
+		"Year":     strconv.Itoa(time.Now().Year()),
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		errSent := m.SendMessage(user.Email, msg)
+		if errSent != nil {
+			fmt.Printf("Error sending magic link email: %v\n", errSent)
+		} else {
+			fmt.Println("Magic link email sent successfully")
+		}
+	}()
+	return nil
+}
+
+// LoginWithMagicLink consumes the link and issues the same JWT as LoginUser. Following the link proves
+// ownership of the address, so an unverified account is verified on the way.
+func (s *AuthService) LoginWithMagicLink(tokenPlain string, info RequestInfo) (string, error) {
+	t, err := s.AuthRepository.ConsumeMagicLinkToken(tokenPlain)
+	if err != nil {
+		s.AuditService.Record(info, models.SecurityEventLogin, models.SecurityOutcomeFailure, nil, "invalid magic link")
+		return "", errors.New("invalid or expired token")
+	}
+	user := s.AuthRepository.FindById(t.UserID)
+	if user == nil {
+		return "", errors.New("invalid or expired token")
+	}
+	info = info.as(user)
+	if user.Suspended {
+		s.AuditService.Record(info, models.SecurityEventLogin, models.SecurityOutcomeFailure, user, ErrAccountSuspended.Error())
+		return "", ErrAccountSuspended
+	}
+	if !user.Verified {
+		if err := s.AuthRepository.MarkUserVerified(user.ID); err != nil {
+			return "", err
+		}
+	}
+	if err := s.ThrottleService.Reset(AccountFailureSubject(user.Email)); err != nil {
+		return "", err
+	}
+	s.AuditService.Record(info, models.SecurityEventLogin, models.SecurityOutcomeSuccess, user, "magic link")
+	return s.issueToken(user)
+}
+
+const EmailChangeTTL = time.Hour
+
+// RequestEmailChange sends a confirmation link to newEmail and a notice to the current address. The email
+// only changes once the link is confirmed. A wrong password counts as a failed login attempt.
+func (s *AuthService) RequestEmailChange(userID uint, newEmail, currentPassword string, info RequestInfo) error {
+	user, err := s.VerifyPassword(userID, currentPassword, info)
+	if err != nil {
+		s.AuditService.RecordResult(info, models.SecurityEventEmailChangeRequested, s.AuthRepository.FindById(userID), err)
+		return err
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		return errors.New("new email must be different from the current one")
+	}
+	if s.AuthRepository.FindByEmail(newEmail) != nil {
+		return repository.ErrEmailTaken
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		return err
+	}
+	if _, err := s.AuthRepository.CreateEmailChangeToken(user.ID, newEmail, token, EmailChangeTTL); err != nil {
+		return err
+	}
+	s.AuditService.Record(info, models.SecurityEventEmailChangeRequested, models.SecurityOutcomeSuccess, user,
+		"new email: "+newEmail)
+
+	m, err := mailer.New()
+	if err != nil {
+		return err
+	}
+	base := config.GetAppEnv().BaseURL
+	link := fmt.Sprintf("%s/confirm-email-change?token=%s", base, token)
+	year := strconv.Itoa(time.Now().Year())
+
+	locale := s.PreferencesService.Locale(user.ID)
+
+	confirmMsg, err := mailer.Render(mailer.ChangeEmail, locale, map[string]string{
+		"Username":   user.FirstName,
+		"NewEmail":   newEmail,
+		"ConfirmURL": link,
+		"Year":       year,
+	})
+	if err != nil {
+		return err
+	}
+
+	noticeMsg, err := mailer.Render(mailer.EmailChangeNotice, locale, map[string]string{
+		"Username": user.FirstName,
+		"NewEmail": newEmail,
+		"Year":     year,
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if errSent := m.SendMessage(newEmail, confirmMsg); errSent != nil {
+			fmt.Printf("Error sending email change confirmation: %v\n", errSent)
+		} else {
+			fmt.Println("Email change confirmation sent successfully")
+		}
+		if errSent := m.SendMessage(user.Email, noticeMsg); errSent != nil {
+			fmt.Printf("Error sending email change notice: %v\n", errSent)
+		} else {
+			fmt.Println("Email change notice sent successfully")
+		}
+	}()
+	return nil
+}
+
+func (s *AuthService) ConfirmEmailChange(tokenPlain string, info RequestInfo) error {
+	t, err := s.AuthRepository.ConsumeEmailChangeToken(tokenPlain)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return err
+		}
+		return errors.New("invalid or expired token")
+	}
+	if user := s.AuthRepository.FindById(t.UserID); user != nil {
+		s.AuditService.Record(info.as(user), models.SecurityEventEmailChanged, models.SecurityOutcomeSuccess, user,
+			"new email: "+t.NewEmail)
+	}
 	return nil
 }
 
 func (s *AuthService) SendVerificationEmail(user *models.User) error {
-	token, err := s.generateToken(32)
+	token, err := generateToken(32)
 	if err != nil {
 		return err
 	}
@@ -151,19 +475,17 @@ func (s *AuthService) SendVerificationEmail(user *models.User) error {
 	base := config.GetAppEnv().BaseURL
 	link := fmt.Sprintf("%s/verification-email?token=%s", base, token)
 
-	tpl, _ := template.ParseFS(embedfs.TemplatesFS, "config/static/templates/verify_email.html")
-	var body bytes.Buffer
-	errExc := tpl.Execute(&body, map[string]string{
+	msg, err := mailer.Render(mailer.VerifyEmail, s.PreferencesService.Locale(user.ID), map[string]string{
 		"Username":  user.FirstName,
 		"VerifyURL": link,
 		"Year":      strconv.Itoa(time.Now().Year()),
 	})
-	if errExc != nil {
+	if err != nil {
 		return err
 	}
 
 	go func() {
-		errSent := m.SendWithTemplate(user.Email, "Verify your email", body.String())
+		errSent := m.SendMessage(user.Email, msg)
 		if errSent != nil {
 			fmt.Printf("Error sending verification email: %v\n", errSent)
 		} else {
@@ -185,7 +507,7 @@ func (s *AuthService) ResendVerificationEmail(email string) error {
 	return s.SendVerificationEmail(user)
 }
 
-func (s *AuthService) VerifyEmail(tokenPlain string) error {
+func (s *AuthService) VerifyEmail(tokenPlain string, info RequestInfo) error {
 	t, err := s.AuthRepository.GetEmailVerificationToken(tokenPlain)
 	if err != nil {
 		return errors.New("invalid token")
@@ -196,5 +518,8 @@ func (s *AuthService) VerifyEmail(tokenPlain string) error {
 	if err := s.AuthRepository.MarkUserVerified(t.UserID); err != nil {
 		return err
 	}
+	if user := s.AuthRepository.FindById(t.UserID); user != nil {
+		s.AuditService.Record(info.as(user), models.SecurityEventEmailVerified, models.SecurityOutcomeSuccess, user, "")
+	}
 	return s.AuthRepository.MarkEmailVerificationTokenUsed(t.ID)
 }
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"slices"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxAvatarBytes caps the size of an uploaded avatar file.
+	MaxAvatarBytes = 5 << 20
+	// DefaultAvatarSize is served when a client does not ask for a size.
+	DefaultAvatarSize = 128
+	// maxAvatarSide rejects images whose decoded pixels would not fit comfortably in memory: a
+	// 4096x4096 RGBA image already takes 64 MB once decoded.
+	maxAvatarSide = 4096
+)
+
+// AvatarSizes are the square renditions, in pixels, generated for every uploaded avatar.
+var AvatarSizes = []int{64, 128, 256, 512}
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image: upload a JPEG, PNG, GIF or WebP file")
+	ErrImageTooLarge    = fmt.Errorf("image too large: files are limited to %d MB and %d pixels per side",
+		MaxAvatarBytes>>20, maxAvatarSide)
+	ErrInvalidAvatarSize = fmt.Errorf("size must be one of %v", AvatarSizes)
+)
+
+var avatarContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+type AvatarService struct {
+	AvatarRepository *repository.AvatarRepository
+}
+
+func NewAvatarService(avatarRepo *repository.AvatarRepository) *AvatarService {
+	return &AvatarService{AvatarRepository: avatarRepo}
+}
+
+// Set replaces the user's avatar with raw, which is cropped to a square, resized to every size in
+// AvatarSizes and re-encoded so that EXIF and other metadata are dropped. It returns the new version.
+func (s *AvatarService) Set(userID uint, raw []byte) (string, error) {
+	avatars, version, err := renderAvatars(userID, raw)
+	if err != nil {
+		return "", err
+	}
+	if err := s.AvatarRepository.Replace(userID, avatars, version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+func (s *AvatarService) Remove(userID uint) error {
+	return s.AvatarRepository.Delete(userID)
+}
+
+// Get returns the rendition of the user's avatar in the given size, or gorm.ErrRecordNotFound when the
+// user has none.
+func (s *AvatarService) Get(userID uint, size int) (*models.UserAvatar, string, error) {
+	if !slices.Contains(AvatarSizes, size) {
+		return nil, "", ErrInvalidAvatarSize
+	}
+	avatar, err := s.AvatarRepository.Find(userID, size)
+	if err != nil {
+		return nil, "", err
+	}
+	version, err := s.AvatarRepository.FindVersion(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	return avatar, version, nil
+}
+
+// MigrateLegacyImages converts avatars stored as raw bytes on the user, from before uploads were
+// resized, into renditions. Images that cannot be decoded are dropped.
+func (s *AvatarService) MigrateLegacyImages() (int, error) {
+	ids, err := s.AvatarRepository.FindLegacyImageOwners()
+	if err != nil {
+		return 0, err
+	}
+	converted := 0
+	for _, id := range ids {
+		raw, err := s.AvatarRepository.FindLegacyImage(id)
+		if err != nil {
+			return converted, err
+		}
+		if len(raw) == 0 {
+			if err := s.AvatarRepository.Delete(id); err != nil {
+				return converted, err
+			}
+			continue
+		}
+		if _, err := s.Set(id, raw); err != nil {
+			fmt.Printf("Dropping unreadable avatar of user %d: %v\n", id, err)
+			if err := s.AvatarRepository.Delete(id); err != nil {
+				return converted, err
+			}
+			continue
+		}
+		converted++
+	}
+	return converted, nil
+}
+
+func renderAvatars(userID uint, raw []byte) ([]models.UserAvatar, string, error) {
+	if len(raw) > MaxAvatarBytes {
+		return nil, "", ErrImageTooLarge
+	}
+	// the declared content type of an upload is not trusted, only the file's signature
+	if !slices.Contains(avatarContentTypes, http.DetectContentType(raw)) {
+		return nil, "", ErrUnsupportedImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+	if config.Width > maxAvatarSide || config.Height > maxAvatarSide {
+		return nil, "", ErrImageTooLarge
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return nil, "", ErrUnsupportedImage
+	}
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	// centre crop to a square
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+	opaque := isOpaque(src)
+
+	versionHash := sha256.New()
+	avatars := make([]models.UserAvatar, 0, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+		var encoded bytes.Buffer
+		contentType := "image/png"
+		if opaque {
+			contentType = "image/jpeg"
+			err = jpeg.Encode(&encoded, dst, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&encoded, dst)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		sum := sha256.Sum256(encoded.Bytes())
+		versionHash.Write(sum[:])
+		avatars = append(avatars, models.UserAvatar{
+			UserID:      userID,
+			Size:        size,
+			ContentType: contentType,
+			Data:        encoded.Bytes(),
+			ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		})
+	}
+	return avatars, hex.EncodeToString(versionHash.Sum(nil)[:8]), nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
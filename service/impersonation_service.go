@@ -0,0 +1,146 @@
+package service
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultImpersonationTTL = 30 * time.Minute
+	MaxImpersonationTTL     = time.Hour
+)
+
+var ErrImpersonationNotAllowed = errors.New("impersonation not allowed")
+
+type ImpersonationService struct {
+	ImpersonationRepository *repository.ImpersonationRepository
+	UserRepository          *repository.UserRepository
+	RoleRepository          *repository.RoleRepository
+	AuthService             *AuthService
+}
+
+func NewImpersonationService(impersonationRepo *repository.ImpersonationRepository, userRepo *repository.UserRepository,
+	roleRepo *repository.RoleRepository, authService *AuthService) *ImpersonationService {
+	return &ImpersonationService{
+		ImpersonationRepository: impersonationRepo,
+		UserRepository:          userRepo,
+		RoleRepository:          roleRepo,
+		AuthService:             authService,
+	}
+}
+
+// Start records an impersonation session for the calling admin and returns a short-lived token for the
+// target user. The token carries the impersonator and session id so the session can be ended early and every
+// request traced back.
+func (s *ImpersonationService) Start(userId uint, reason string, ttl time.Duration, info RequestInfo) (string, *response.ImpersonationSessionResponseDto, error) {
+	adminId := info.ActorId
+	if adminId == userId {
+		return "", nil, fmt.Errorf("%w: cannot impersonate yourself", ErrImpersonationNotAllowed)
+	}
+	if ttl <= 0 {
+		ttl = DefaultImpersonationTTL
+	}
+	ttl = min(ttl, MaxImpersonationTTL)
+
+	admin, err := s.UserRepository.FindByID(adminId)
+	if err != nil {
+		return "", nil, err
+	}
+	target, err := s.UserRepository.FindByID(userId)
+	if err != nil {
+		return "", nil, err
+	}
+	if target.Suspended {
+		return "", nil, fmt.Errorf("%w: the account is suspended", ErrImpersonationNotAllowed)
+	}
+	privileged, err := s.RoleRepository.HasPermission(target.RoleId, models.PermissionUsersManage)
+	if err != nil {
+		return "", nil, err
+	}
+	if privileged {
+		return "", nil, fmt.Errorf("%w: cannot impersonate another administrator", ErrImpersonationNotAllowed)
+	}
+
+	session := &models.ImpersonationSession{
+		AdminId:    adminId,
+		AdminEmail: admin.Email,
+		UserId:     userId,
+		Reason:     reason,
+		IP:         info.IP,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+	if err := s.ImpersonationRepository.Save(session); err != nil {
+		return "", nil, err
+	}
+
+	token, err := s.AuthService.issueTokenWithClaims(target, ttl, jwt.MapClaims{
+		"impersonator":     adminId,
+		"impersonation_id": session.ID,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.AuthService.AuditService.Record(info, models.SecurityEventImpersonationStarted, models.SecurityOutcomeSuccess, target,
+		fmt.Sprintf("session %d: %s", session.ID, reason))
+	fmt.Printf("Impersonation %d started: admin %d as user %d until %s\n",
+		session.ID, adminId, userId, session.ExpiresAt.Format(time.RFC3339))
+
+	session.User = *target
+	dto := toImpersonationDto(session)
+	return token, &dto, nil
+}
+
+func (s *ImpersonationService) Stop(sessionId uint, info RequestInfo) error {
+	session, err := s.ImpersonationRepository.FindById(sessionId)
+	if err != nil {
+		return err
+	}
+	if err := s.ImpersonationRepository.End(sessionId); err != nil {
+		return err
+	}
+	target, _ := s.UserRepository.FindByID(session.UserId)
+	s.AuthService.AuditService.Record(info, models.SecurityEventImpersonationEnded, models.SecurityOutcomeSuccess, target,
+		fmt.Sprintf("session %d", sessionId))
+	fmt.Printf("Impersonation %d ended\n", sessionId)
+	return nil
+}
+
+func (s *ImpersonationService) GetAll(pagination response.Pagination) (*response.Pagination, error) {
+	sessionsPaginated, err := s.ImpersonationRepository.FindAll(pagination)
+	if err != nil {
+		return nil, err
+	}
+	sessions, ok := sessionsPaginated.Items.([]*models.ImpersonationSession)
+	if !ok {
+		return nil, errors.New("error converting impersonation sessions to entity")
+	}
+
+	var sessionsResponse = make([]response.ImpersonationSessionResponseDto, 0)
+	for _, session := range sessions {
+		sessionsResponse = append(sessionsResponse, toImpersonationDto(session))
+	}
+	sessionsPaginated.Items = sessionsResponse
+	return sessionsPaginated, nil
+}
+
+func toImpersonationDto(session *models.ImpersonationSession) response.ImpersonationSessionResponseDto {
+	return response.ImpersonationSessionResponseDto{
+		Id:         session.ID,
+		AdminId:    session.AdminId,
+		AdminEmail: session.AdminEmail,
+		UserId:     session.UserId,
+		UserEmail:  session.User.Email,
+		Reason:     session.Reason,
+		IP:         session.IP,
+		StartedAt:  session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+		EndedAt:    session.EndedAt,
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// jobTimeout bounds a single job, leaving room for the retries of an AI call.
+	jobTimeout = 5 * time.Minute
+	// jobRequeueAfter is how long after it started a job still running is taken for interrupted. The
+	// margin past jobTimeout lets a cancelled job finish.
+	jobRequeueAfter = jobTimeout + time.Minute
+)
+
+// JobHandler runs a job and returns its result, which is stored as JSON.
+type JobHandler func(ctx context.Context, job *models.Job) (any, error)
+
+// jobRunnerWake lets Enqueue and finished jobs start the next job without waiting for the next poll.
+var jobRunnerWake = make(chan struct{}, 1)
+
+func wakeJobRunner() {
+	select {
+	case jobRunnerWake <- struct{}{}:
+	default:
+	}
+}
+
+// JobRunner runs the queued jobs with a pool of Workers, running at most PerUser jobs of a user at once.
+type JobRunner struct {
+	JobRepository *repository.JobRepository
+	Workers       int
+	PerUser       int
+	// Retention is how long finished jobs can still be polled before they are deleted.
+	Retention time.Duration
+	handlers  map[string]JobHandler
+}
+
+func NewJobRunner(jobRepo *repository.JobRepository, workers, perUser int, retention time.Duration) *JobRunner {
+	return &JobRunner{
+		JobRepository: jobRepo,
+		Workers:       workers,
+		PerUser:       perUser,
+		Retention:     retention,
+		handlers:      make(map[string]JobHandler),
+	}
+}
+
+// Handle registers the handler of a job kind. Jobs of kinds without a handler fail.
+func (r *JobRunner) Handle(kind string, handler JobHandler) {
+	r.handlers[kind] = handler
+}
+
+// Run starts queued jobs as workers free up, checking the queue at least every interval. Every minute
+// it requeues the jobs left running by a runner that stopped, possibly of another instance, and every
+// hour it deletes the finished jobs past the retention. It never returns.
+func (r *JobRunner) Run(interval time.Duration) {
+	slots := make(chan struct{}, r.Workers)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastRequeue, lastCleanup time.Time
+	for {
+		if time.Since(lastRequeue) >= time.Minute {
+			if n, err := r.JobRepository.RequeueRunning(time.Now().Add(-jobRequeueAfter)); err != nil {
+				fmt.Printf("Error requeuing interrupted jobs: %v\n", err)
+			} else if n > 0 {
+				fmt.Printf("Requeued %d interrupted jobs\n", n)
+			}
+			lastRequeue = time.Now()
+		}
+		if time.Since(lastCleanup) >= time.Hour {
+			if _, err := r.JobRepository.DeleteFinishedBefore(time.Now().Add(-r.Retention)); err != nil {
+				fmt.Printf("Error deleting old jobs: %v\n", err)
+			}
+			lastCleanup = time.Now()
+		}
+		r.startQueued(slots)
+
+		select {
+		case <-ticker.C:
+		case <-jobRunnerWake:
+		}
+	}
+}
+
+// startQueued claims queued jobs while a worker is free. Claiming happens only here, so the per-user
+// limit counted by ClaimNext holds.
+func (r *JobRunner) startQueued(slots chan struct{}) {
+	for {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return
+		}
+		job, err := r.JobRepository.ClaimNext(r.PerUser)
+		if err != nil {
+			fmt.Printf("Error claiming job: %v\n", err)
+		}
+		if job == nil {
+			<-slots
+			return
+		}
+		go func() {
+			r.process(job)
+			<-slots
+			// the user of the job may have more queued
+			wakeJobRunner()
+		}()
+	}
+}
+
+func (r *JobRunner) process(job *models.Job) {
+	status, result, message := models.JobStatusFailed, "", ""
+	defer func() {
+		if p := recover(); p != nil {
+			status, result, message = models.JobStatusFailed, "", fmt.Sprintf("job crashed: %v", p)
+		}
+		if err := r.JobRepository.Finish(job.ID, status, result, message); err != nil {
+			fmt.Printf("Error finishing job %d: %v\n", job.ID, err)
+		}
+	}()
+
+	handler, ok := r.handlers[job.Kind]
+	if !ok {
+		message = fmt.Sprintf("unknown job kind %q", job.Kind)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+	out, err := handler(ctx, job)
+	if err != nil {
+		message = err.Error()
+		return
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		message = err.Error()
+		return
+	}
+	status, result = models.JobStatusSucceeded, string(data)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+const (
+	// MaxJobWait caps how long Get waits for a job to finish.
+	MaxJobWait      = 60 * time.Second
+	jobPollInterval = 500 * time.Millisecond
+)
+
+type JobService struct {
+	JobRepository *repository.JobRepository
+}
+
+func NewJobService(jobRepo *repository.JobRepository) *JobService {
+	return &JobService{JobRepository: jobRepo}
+}
+
+// Enqueue queues a job of the given kind for the job runner; payload is stored as JSON.
+func (s *JobService) Enqueue(userID uint, kind string, payload any) (*response.JobResponseDto, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.Job{UserID: userID, Kind: kind, Status: models.JobStatusQueued, Payload: string(data)}
+	if err := s.JobRepository.Create(job); err != nil {
+		return nil, err
+	}
+	wakeJobRunner()
+	return toJobResponse(job), nil
+}
+
+// Get returns a job of the user. When wait is positive and the job has not finished, Get waits up to
+// wait, at most MaxJobWait, for it to finish before returning its state.
+func (s *JobService) Get(ctx context.Context, userID, id uint, wait time.Duration) (*response.JobResponseDto, error) {
+	deadline := time.Now().Add(min(wait, MaxJobWait))
+	for {
+		job, err := s.JobRepository.FindByIdForUser(id, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if job.FinishedAt != nil || !time.Now().Before(deadline) {
+			return toJobResponse(job), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return toJobResponse(job), nil
+		case <-time.After(min(jobPollInterval, time.Until(deadline))):
+		}
+	}
+}
+
+func toJobResponse(job *models.Job) *response.JobResponseDto {
+	dto := &response.JobResponseDto{
+		Id:         job.ID,
+		Kind:       job.Kind,
+		Status:     job.Status,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Result != "" {
+		dto.Result = json.RawMessage(job.Result)
+	}
+	return dto
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"SimpleToDo/config"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/breached"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordPolicyError lists every rule a candidate password breaks so the client can show them all at once.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+type PasswordPolicyService struct {
+	PasswordHistoryRepository *repository.PasswordHistoryRepository
+}
+
+func NewPasswordPolicyService(historyRepo *repository.PasswordHistoryRepository) *PasswordPolicyService {
+	return &PasswordPolicyService{PasswordHistoryRepository: historyRepo}
+}
+
+func (s *PasswordPolicyService) Policy() config.PasswordPolicy {
+	return config.GetAppEnv().Password
+}
+
+// Validate checks password against the configured policy for user. user.ID may be zero for a user
+// that is not registered yet, in which case the reuse check is skipped.
+func (s *PasswordPolicyService) Validate(password string, user *models.User) error {
+	policy := s.Policy()
+	var violations []string
+
+	if len(password) < policy.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if len(password) > 72 {
+		violations = append(violations, "must be at most 72 bytes long")
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if policy.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if name := strings.ToLower(strings.TrimSpace(user.Username)); len(name) >= 3 && strings.Contains(lowered, name) {
+		violations = append(violations, "must not contain the username")
+	}
+	localPart, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(user.Email)), "@")
+	if len(localPart) >= 3 && strings.Contains(lowered, localPart) {
+		violations = append(violations, "must not contain the email address")
+	}
+
+	if user.ID != 0 && policy.History > 0 {
+		reused, err := s.isReused(password, user, policy.History)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, fmt.Sprintf("must not match any of the last %d passwords", policy.History))
+		}
+	}
+
+	if policy.BreachedListPath != "" {
+		found, err := breached.Contains(policy.BreachedListPath, password)
+		if err != nil {
+			fmt.Printf("Error checking breached passwords list: %v\n", err)
+		} else if found {
+			violations = append(violations, "appears in a list of breached passwords")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Record remembers the stored bcrypt hash of a newly set password for the reuse check.
+func (s *PasswordPolicyService) Record(userID uint, passwordHash string) error {
+	keep := s.Policy().History
+	if keep <= 0 {
+		return nil
+	}
+	return s.PasswordHistoryRepository.Add(userID, passwordHash, keep)
+}
+
+func (s *PasswordPolicyService) isReused(password string, user *models.User, history int) (bool, error) {
+	// The current password counts even for accounts created before history was kept.
+	if user.Password != "" && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil {
+		return true, nil
+	}
+	previous, err := s.PasswordHistoryRepository.FindLatest(user.ID, history)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range previous {
+		if bcrypt.CompareHashAndPassword([]byte(p.PasswordHash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrDefaultProjectNotAllowed = errors.New("default project must be a project you own or are a member of")
+	ErrDefaultPromptNotFound    = errors.New("default prompt does not exist")
+)
+
+// dateLayouts maps the date formats users can pick to Go layouts.
+var dateLayouts = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD.MM.YYYY": "02.01.2006",
+}
+
+// taskSortOrders maps the default task sorts users can pick to ORDER BY clauses.
+var taskSortOrders = map[string]string{
+	"oldest":   "id asc",
+	"newest":   "id desc",
+	"due_date": "due_date IS NULL, due_date asc, id asc",
+	"title":    "title asc, id asc",
+}
+
+type PreferencesService struct {
+	PreferencesRepository *repository.PreferencesRepository
+	ProjectRepository     *repository.ProjectRepository
+	PromptRepository      *repository.PromptRepository
+}
+
+func NewPreferencesService(prefsRepo *repository.PreferencesRepository, projectRepo *repository.ProjectRepository,
+	promptRepo *repository.PromptRepository) *PreferencesService {
+	return &PreferencesService{PreferencesRepository: prefsRepo, ProjectRepository: projectRepo, PromptRepository: promptRepo}
+}
+
+func (s *PreferencesService) Get(userID uint) (*response.PreferencesResponseDto, error) {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return toPreferencesDto(prefs), nil
+}
+
+func (s *PreferencesService) Update(userID uint, data request.UpdatePreferencesRequest) (*response.PreferencesResponseDto, error) {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Timezone != "" {
+		prefs.Timezone = data.Timezone
+	}
+	if data.Locale != "" {
+		prefs.Locale = data.Locale
+	}
+	if data.WeekStart != "" {
+		prefs.WeekStart = data.WeekStart
+	}
+	if data.DateFormat != "" {
+		prefs.DateFormat = data.DateFormat
+	}
+	if data.DefaultTaskSort != "" {
+		prefs.DefaultTaskSort = data.DefaultTaskSort
+	}
+	if data.DefaultProjectId != nil {
+		if *data.DefaultProjectId == 0 {
+			prefs.DefaultProjectId = nil
+		} else {
+			projectID := uint(*data.DefaultProjectId)
+			allowed, err := s.ProjectRepository.CanUse(userID, projectID)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, ErrDefaultProjectNotAllowed
+			}
+			prefs.DefaultProjectId = &projectID
+		}
+	}
+
+	if data.DefaultPromptId != nil {
+		if *data.DefaultPromptId == 0 {
+			prefs.DefaultPromptId = nil
+		} else {
+			promptID := uint(*data.DefaultPromptId)
+			if _, err := s.PromptRepository.FindByIdAndPurpose(promptID, models.PromptPurposeVision); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, ErrDefaultPromptNotFound
+				}
+				return nil, err
+			}
+			prefs.DefaultPromptId = &promptID
+		}
+	}
+
+	if err := s.PreferencesRepository.Save(prefs); err != nil {
+		return nil, err
+	}
+	return toPreferencesDto(prefs), nil
+}
+
+// Location returns the user's timezone, falling back to UTC.
+func (s *PreferencesService) Location(userID uint) *time.Location {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err != nil {
+		return time.UTC
+	}
+	return preferredLocation(prefs)
+}
+
+// Today returns the current calendar day in the user's timezone, as stored in Task.DueDate.
+func (s *PreferencesService) Today(userID uint) time.Time {
+	y, m, d := time.Now().In(s.Location(userID)).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// FormatDateTime renders t for the user, in their timezone and date format, e.g. for emails.
+func (s *PreferencesService) FormatDateTime(userID uint, t time.Time) string {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err != nil {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	}
+	layout, ok := dateLayouts[prefs.DateFormat]
+	if !ok {
+		layout = "2006-01-02"
+	}
+	return t.In(preferredLocation(prefs)).Format(layout + " 15:04 MST")
+}
+
+// Locale returns the user's preferred language, used to pick the language of their emails.
+func (s *PreferencesService) Locale(userID uint) string {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err != nil {
+		return ""
+	}
+	return prefs.Locale
+}
+
+// WeekStart returns the day the user's weeks begin on.
+func (s *PreferencesService) WeekStart(userID uint) time.Weekday {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err == nil {
+		if day, ok := weekStarts[prefs.WeekStart]; ok {
+			return day
+		}
+	}
+	return time.Monday
+}
+
+// DefaultProjectID returns the project the user picked for new tasks, or nil.
+func (s *PreferencesService) DefaultProjectID(userID uint) *uint {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	return prefs.DefaultProjectId
+}
+
+// DefaultPromptID returns the prompt the user picked for vision analysis, or nil.
+func (s *PreferencesService) DefaultPromptID(userID uint) *uint {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	return prefs.DefaultPromptId
+}
+
+// TaskSortOrder returns the ORDER BY clause for the user's default task sort.
+func (s *PreferencesService) TaskSortOrder(userID uint) string {
+	prefs, err := s.PreferencesRepository.FindByUserID(userID)
+	if err == nil {
+		if order, ok := taskSortOrders[prefs.DefaultTaskSort]; ok {
+			return order
+		}
+	}
+	return taskSortOrders["oldest"]
+}
+
+func preferredLocation(prefs *models.UserPreferences) *time.Location {
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		fmt.Printf("Invalid timezone %q for user %d, using UTC\n", prefs.Timezone, prefs.UserID)
+		return time.UTC
+	}
+	return loc
+}
+
+func toPreferencesDto(prefs *models.UserPreferences) *response.PreferencesResponseDto {
+	return &response.PreferencesResponseDto{
+		Timezone:         prefs.Timezone,
+		Locale:           prefs.Locale,
+		WeekStart:        prefs.WeekStart,
+		DateFormat:       prefs.DateFormat,
+		DefaultProjectId: prefs.DefaultProjectId,
+		DefaultTaskSort:  prefs.DefaultTaskSort,
+		DefaultPromptId:  prefs.DefaultPromptId,
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testVisionPromptId    = 1
+	testBreakdownPromptId = 2
+	testPrefsUserId       = 1
+)
+
+// newTestPreferencesService returns a PreferencesService on a fresh database holding a vision prompt
+// and a breakdown prompt.
+func newTestPreferencesService(t *testing.T) *PreferencesService {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening the database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.UserPreferences{}, &models.Prompt{}); err != nil {
+		t.Fatalf("migrating the database: %v", err)
+	}
+	prompts := []models.Prompt{
+		{ID: testVisionPromptId, Title: "Vision", SystemPrompt: "x", Purpose: models.PromptPurposeVision},
+		{ID: testBreakdownPromptId, Title: "Breakdown", SystemPrompt: "x", Purpose: models.PromptPurposeBreakdown},
+	}
+	if err := db.Create(&prompts).Error; err != nil {
+		t.Fatalf("creating the prompts: %v", err)
+	}
+	return NewPreferencesService(repository.NewPreferencesRepository(db), repository.NewProjectRepository(db),
+		repository.NewPromptRepository(db))
+}
+
+func TestPreferencesServiceUpdateDefaultPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		promptId int
+		wantErr  error
+	}{
+		{"vision prompt", testVisionPromptId, nil},
+		{"breakdown prompt", testBreakdownPromptId, ErrDefaultPromptNotFound},
+		{"unknown prompt", 42, ErrDefaultPromptNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestPreferencesService(t)
+			prefs, err := s.Update(testPrefsUserId, request.UpdatePreferencesRequest{DefaultPromptId: &tt.promptId})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			stored, _ := s.Get(testPrefsUserId)
+			if tt.wantErr != nil {
+				if stored.DefaultPromptId != nil {
+					t.Errorf("default prompt = %v, want none", *stored.DefaultPromptId)
+				}
+				return
+			}
+			if prefs.DefaultPromptId == nil || *prefs.DefaultPromptId != uint(tt.promptId) {
+				t.Errorf("default prompt = %v, want %d", prefs.DefaultPromptId, tt.promptId)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/aiprovider"
+	"SimpleToDo/util/mailer"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrProjectNotFound        = errors.New("project not found")
+	ErrProjectSummaryNotFound = errors.New("the project has no summary yet")
+)
+
+const (
+	// summaryRecentDays is how far back changes to tasks are reported as recent.
+	summaryRecentDays = 7
+	// maxSummaryTasks caps how many tasks are described to the model, most recently changed first.
+	maxSummaryTasks          = 200
+	maxSummaryTaskDescLength = 200
+)
+
+// projectSummaryInstruction is the system prompt of project summaries.
+const projectSummaryInstruction = `You write short status reports of projects for managers. From the project's tasks, write a
+report in plain text, without markdown headings, with these sections in this order: Done, In progress, Blocked, Risks.
+Give each section a few bullet points starting with "- ", or "None". Risks covers overdue tasks, blocked work and
+anything else that puts the project at risk. Mention recent changes where they matter. Keep it under 250 words and
+write it in the language of the locale %q. Today is %s.`
+
+type ProjectSummaryService struct {
+	ProjectRepository        *repository.ProjectRepository
+	TaskRepository           *repository.TaskRepository
+	ProjectSummaryRepository *repository.ProjectSummaryRepository
+	AIServerRepository       *repository.AIServerRepository
+	UserRepository           *repository.UserRepository
+	PreferencesService       *PreferencesService
+	AIUsageService           *AIUsageService
+}
+
+func NewProjectSummaryService(projectRepo *repository.ProjectRepository, taskRepo *repository.TaskRepository,
+	summaryRepo *repository.ProjectSummaryRepository, aiRepo *repository.AIServerRepository, userRepo *repository.UserRepository,
+	preferencesService *PreferencesService, aiUsageService *AIUsageService) *ProjectSummaryService {
+	return &ProjectSummaryService{
+		ProjectRepository:        projectRepo,
+		TaskRepository:           taskRepo,
+		ProjectSummaryRepository: summaryRepo,
+		AIServerRepository:       aiRepo,
+		UserRepository:           userRepo,
+		PreferencesService:       preferencesService,
+		AIUsageService:           aiUsageService,
+	}
+}
+
+// GetCached returns the last summary generated for the project.
+func (s *ProjectSummaryService) GetCached(userID uint, projectID int) (*response.ProjectSummaryResponseDto, error) {
+	if err := s.checkAccess(userID, projectID); err != nil {
+		return nil, err
+	}
+	summary, err := s.ProjectSummaryRepository.FindByProjectId(uint(projectID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProjectSummaryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(summary)
+}
+
+// Generate asks the user's AI provider for a status report of the project and caches it. With useCache
+// the cached report is returned instead while no task changed since it was generated. With email the
+// report is also sent to the user. A non-nil stream receives the report while it is written, or the
+// cached report at once.
+func (s *ProjectSummaryService) Generate(ctx context.Context, userID uint, projectID int, useCache, email bool,
+	stream *aiprovider.Stream) (*response.ProjectSummaryResponseDto, error) {
+	if err := s.checkAccess(userID, projectID); err != nil {
+		return nil, err
+	}
+	project, err := s.ProjectRepository.FindById(projectID)
+	if err != nil {
+		return nil, ErrProjectNotFound
+	}
+
+	var result *response.ProjectSummaryResponseDto
+	if useCache {
+		summary, err := s.ProjectSummaryRepository.FindByProjectId(project.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if summary != nil {
+			if result, err = s.toResponse(summary); err != nil {
+				return nil, err
+			}
+			if result.Stale {
+				result = nil
+			} else if stream != nil {
+				if err := stream.Delta(result.Summary); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if result == nil {
+		summary, err := s.generate(ctx, userID, &project, stream)
+		if err != nil {
+			return nil, err
+		}
+		result = &response.ProjectSummaryResponseDto{
+			ProjectId:   project.ID,
+			Summary:     summary.Content,
+			GeneratedAt: summary.UpdatedAt,
+		}
+	}
+
+	if email {
+		if err := s.sendSummary(userID, &project, result); err != nil {
+			return nil, err
+		}
+		result.Emailed = true
+	}
+	return result, nil
+}
+
+func (s *ProjectSummaryService) generate(ctx context.Context, userID uint, project *models.Project,
+	stream *aiprovider.Stream) (*models.ProjectSummary, error) {
+	settings, err := s.AIServerRepository.FindByUserID(userID)
+	if err != nil {
+		return nil, ErrAINotConfigured
+	}
+
+	today := s.PreferencesService.Today(userID)
+	since := time.Now().AddDate(0, 0, -summaryRecentDays)
+	tasks, deleted, err := s.TaskRepository.FindByProject(project.ID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	locale := s.PreferencesService.Locale(userID)
+	if locale == "" {
+		locale = mailer.DefaultLocale
+	}
+	provider, err := s.AIUsageService.Provider(settings, models.AIFeatureSummary, nil)
+	if err != nil {
+		return nil, err
+	}
+	aiResp, err := provider.Complete(ctx, aiprovider.Request{
+		System:    fmt.Sprintf(projectSummaryInstruction, locale, today.Format("2006-01-02")),
+		Prompt:    projectSummaryContext(project, tasks, deleted, today, since),
+		MaxTokens: 1000,
+		Stream:    stream,
+	})
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimSpace(aiResp.Content)
+	if content == "" {
+		return nil, errors.New("no content received")
+	}
+
+	summary := &models.ProjectSummary{ProjectId: project.ID, Content: content, GeneratedBy: userID}
+	if err := s.ProjectSummaryRepository.Save(summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (s *ProjectSummaryService) checkAccess(userID uint, projectID int) error {
+	allowed, err := s.ProjectRepository.CanUse(userID, uint(projectID))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+func (s *ProjectSummaryService) toResponse(summary *models.ProjectSummary) (*response.ProjectSummaryResponseDto, error) {
+	lastChange, err := s.ProjectSummaryRepository.LastTaskChange(summary.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+	return &response.ProjectSummaryResponseDto{
+		ProjectId:   summary.ProjectId,
+		Summary:     summary.Content,
+		GeneratedAt: summary.UpdatedAt,
+		Cached:      true,
+		Stale:       lastChange != nil && lastChange.After(summary.UpdatedAt),
+	}, nil
+}
+
+// sendSummary renders the summary email in the user's locale and sends it in the background.
+func (s *ProjectSummaryService) sendSummary(userID uint, project *models.Project, summary *response.ProjectSummaryResponseDto) error {
+	user, err := s.UserRepository.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	m, err := mailer.New()
+	if err != nil {
+		return err
+	}
+	msg, err := mailer.Render(mailer.ProjectSummary, s.PreferencesService.Locale(userID), map[string]string{
+		"Username":    user.FirstName,
+		"Project":     project.Name,
+		"Summary":     summary.Summary,
+		"GeneratedAt": s.PreferencesService.FormatDateTime(userID, summary.GeneratedAt),
+		"Year":        strconv.Itoa(time.Now().Year()),
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if errSent := m.SendMessage(user.Email, msg); errSent != nil {
+			fmt.Printf("Error sending project summary email: %v\n", errSent)
+		}
+	}()
+	return nil
+}
+
+// projectSummaryContext describes the project, its tasks and their recent changes to the model.
+func projectSummaryContext(project *models.Project, tasks, deleted []models.Task, today, since time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project: %s\n", project.Name)
+	if project.Description != "" {
+		fmt.Fprintf(&b, "Project description: %s\n", project.Description)
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	for _, task := range tasks {
+		if counts[task.Status.Value] == 0 {
+			order = append(order, task.Status.Value)
+		}
+		counts[task.Status.Value]++
+	}
+	if len(tasks) == 0 {
+		b.WriteString("Tasks: none\n\n")
+	} else {
+		parts := make([]string, 0, len(order))
+		for _, status := range order {
+			parts = append(parts, fmt.Sprintf("%s %d", status, counts[status]))
+		}
+		fmt.Fprintf(&b, "Tasks: %d (%s)\n\n", len(tasks), strings.Join(parts, ", "))
+	}
+
+	var recent []string
+	if len(tasks) > 0 {
+		b.WriteString("Tasks, most recently changed first:\n")
+	}
+	for i, task := range tasks {
+		if i == maxSummaryTasks {
+			fmt.Fprintf(&b, "... and %d older tasks\n", len(tasks)-maxSummaryTasks)
+			break
+		}
+		fmt.Fprintf(&b, "- [%s] %s", task.Status.Value, task.Title)
+		var details []string
+		if task.Priority != "" {
+			details = append(details, "priority "+task.Priority)
+		}
+		if task.DueDate != nil {
+			due := "due " + task.DueDate.Format("2006-01-02")
+			if task.DueDate.Before(today) && task.Status.Value != "completed" && task.Status.Value != "cancelled" {
+				due += ", overdue"
+			}
+			details = append(details, due)
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(details, ", "))
+		}
+		if description := strings.TrimSpace(task.Description); description != "" {
+			fmt.Fprintf(&b, ": %s", truncateRunes(description, maxSummaryTaskDescLength))
+		}
+		b.WriteString("\n")
+
+		switch {
+		case task.CreatedAt.After(since):
+			recent = append(recent, fmt.Sprintf("- created %s: %s", task.CreatedAt.Format("2006-01-02"), task.Title))
+		case task.UpdatedAt.After(since):
+			recent = append(recent, fmt.Sprintf("- updated %s, now %s: %s", task.UpdatedAt.Format("2006-01-02"), task.Status.Value, task.Title))
+		}
+	}
+	for _, task := range deleted {
+		recent = append(recent, fmt.Sprintf("- deleted %s: %s", task.DeletedAt.Time.Format("2006-01-02"), task.Title))
+	}
+
+	fmt.Fprintf(&b, "\nChanges in the last %d days:\n", summaryRecentDays)
+	if len(recent) == 0 {
+		b.WriteString("None\n")
+	} else {
+		b.WriteString(strings.Join(recent, "\n") + "\n")
+	}
+	return b.String()
+}
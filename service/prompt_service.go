@@ -23,6 +23,10 @@ func (s *PromptService) Create(data request.CreatePromptRequest) (*response.Prom
 		Title:        data.Title,
 		Description:  data.Description,
 		SystemPrompt: data.SystemPrompt,
+		Purpose:      data.Purpose,
+	}
+	if prompt.Purpose == "" {
+		prompt.Purpose = models.PromptPurposeVision
 	}
 	err := s.PromptRepository.Save(prompt)
 	if err != nil {
@@ -33,6 +37,8 @@ func (s *PromptService) Create(data request.CreatePromptRequest) (*response.Prom
 		Title:        prompt.Title,
 		Description:  prompt.Description,
 		SystemPrompt: prompt.SystemPrompt,
+		Purpose:      prompt.Purpose,
+		IsDefault:    prompt.IsDefault,
 		CreatedAt:    prompt.CreatedAt,
 		UpdatedAt:    prompt.UpdatedAt,
 	}, nil
@@ -53,6 +59,11 @@ func (s *PromptService) Update(id uint, data request.UpdatePromptRequest) (*resp
 	if data.SystemPrompt != "" {
 		existing.SystemPrompt = data.SystemPrompt
 	}
+	if data.Purpose != "" && data.Purpose != existing.Purpose {
+		// the prompt stops being the default of its old purpose and does not become that of the new one
+		existing.Purpose = data.Purpose
+		existing.IsDefault = false
+	}
 
 	promptUpdated, err := s.PromptRepository.Update(existing)
 	if err != nil {
@@ -64,6 +75,8 @@ func (s *PromptService) Update(id uint, data request.UpdatePromptRequest) (*resp
 		Title:        promptUpdated.Title,
 		Description:  promptUpdated.Description,
 		SystemPrompt: promptUpdated.SystemPrompt,
+		Purpose:      promptUpdated.Purpose,
+		IsDefault:    promptUpdated.IsDefault,
 		CreatedAt:    promptUpdated.CreatedAt,
 		UpdatedAt:    promptUpdated.UpdatedAt,
 	}, nil
@@ -83,13 +96,24 @@ func (s *PromptService) GetByID(id uint) (*response.PromptResponse, error) {
 		Title:        prompt.Title,
 		Description:  prompt.Description,
 		SystemPrompt: prompt.SystemPrompt,
+		Purpose:      prompt.Purpose,
+		IsDefault:    prompt.IsDefault,
 		CreatedAt:    prompt.CreatedAt,
 		UpdatedAt:    prompt.UpdatedAt,
 	}, nil
 }
 
-func (s *PromptService) GetAll(pagination response.Pagination) (*response.Pagination, error) {
-	promptsPaginated, err := s.PromptRepository.FindAll(pagination)
+// SetDefault makes the prompt the default for its purpose, replacing the previous default.
+func (s *PromptService) SetDefault(id uint) (*response.PromptResponse, error) {
+	if err := s.PromptRepository.SetDefault(id); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// GetAll lists the prompts with the given purpose, or all of them when purpose is empty.
+func (s *PromptService) GetAll(pagination response.Pagination, purpose string) (*response.Pagination, error) {
+	promptsPaginated, err := s.PromptRepository.FindAll(pagination, purpose)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +129,8 @@ func (s *PromptService) GetAll(pagination response.Pagination) (*response.Pagina
 			Title:        prompt.Title,
 			Description:  prompt.Description,
 			SystemPrompt: prompt.SystemPrompt,
+			Purpose:      prompt.Purpose,
+			IsDefault:    prompt.IsDefault,
 			CreatedAt:    prompt.CreatedAt,
 			UpdatedAt:    prompt.UpdatedAt,
 		}
@@ -114,3 +140,28 @@ func (s *PromptService) GetAll(pagination response.Pagination) (*response.Pagina
 	promptsPaginated.Items = promptsResponse
 	return promptsPaginated, nil
 }
+
+// GetSummaries lists the prompts users can pick for vision analysis.
+func (s *PromptService) GetSummaries(pagination response.Pagination) (*response.Pagination, error) {
+	promptsPaginated, err := s.PromptRepository.FindAll(pagination, models.PromptPurposeVision)
+	if err != nil {
+		return nil, err
+	}
+	prompts, ok := promptsPaginated.Items.([]*models.Prompt)
+	if !ok {
+		return nil, errors.New("error converting prompts to project entity")
+	}
+
+	var summaries = make([]response.PromptSummaryResponse, 0)
+	for _, prompt := range prompts {
+		summaries = append(summaries, response.PromptSummaryResponse{
+			ID:          int64(prompt.ID),
+			Title:       prompt.Title,
+			Description: prompt.Description,
+			IsDefault:   prompt.IsDefault,
+		})
+	}
+
+	promptsPaginated.Items = summaries
+	return promptsPaginated, nil
+}
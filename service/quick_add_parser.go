@@ -0,0 +1,143 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// quickAddResult is what the text typed into quick-add says about the task.
+type quickAddResult struct {
+	Title    string
+	Priority string
+	DueDate  *time.Time
+	// ProjectTag is the first #tag of the text, without the #.
+	ProjectTag string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var weekStarts = map[string]time.Weekday{
+	"monday":   time.Monday,
+	"sunday":   time.Sunday,
+	"saturday": time.Saturday,
+}
+
+const weekdayPattern = `sunday|sun|monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thur|thu|friday|fri|saturday|sat`
+
+var (
+	quickAddTag      = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_-]+)`)
+	quickAddPriority = regexp.MustCompile(`(?i)(?:^|\s)(?:(high|medium|low)\s+priority|priority\s*:?\s*(high|medium|low)|!(high|medium|low)|p([123]))\b`)
+	// the date phrases, each optionally introduced by due, by or on
+	quickAddDate = regexp.MustCompile(`(?i)(?:^|\s)(?:(?:due|by|on)\s+)?(?:` +
+		`(\d{4}-\d{2}-\d{2})|` +
+		`(today|tonight|tomorrow)|` +
+		`in\s+(\d{1,3})\s+(days?|weeks?)|` +
+		`(next\s+week)|` +
+		`(next|this)?\s*(` + weekdayPattern + `))\b`)
+	quickAddSpaces = regexp.MustCompile(`\s+`)
+)
+
+// parseQuickAdd is the rule-based parser used when the user has no AI configured. It understands
+// "#project" tags, priorities such as "high priority", "priority: low", "!high" or "p1", and due
+// dates such as "2025-01-31", "today", "tomorrow", "in 3 days", "next week", "friday" (the next
+// Friday, today included) and "next friday" (Friday of next week). Dates are relative to today, the
+// current day of the user, and weeks begin on the user's week start. What is left becomes the title.
+func parseQuickAdd(text string, today time.Time, weekStart time.Weekday) quickAddResult {
+	var result quickAddResult
+	rest := " " + text + " "
+
+	if m := quickAddTag.FindStringSubmatch(rest); m != nil {
+		result.ProjectTag = m[1]
+	}
+	rest = quickAddTag.ReplaceAllString(rest, " ")
+
+	if loc := quickAddPriority.FindStringSubmatchIndex(rest); loc != nil {
+		m := submatches(rest, loc)
+		switch value := strings.ToLower(m[1] + m[2] + m[3]); {
+		case value != "":
+			result.Priority = value
+		default:
+			result.Priority = map[string]string{"1": "high", "2": "medium", "3": "low"}[m[4]]
+		}
+		rest = rest[:loc[0]] + " " + rest[loc[1]:]
+	}
+
+	if loc := quickAddDate.FindStringSubmatchIndex(rest); loc != nil {
+		if due, ok := resolveQuickAddDate(submatches(rest, loc), today, weekStart); ok {
+			result.DueDate = &due
+			rest = rest[:loc[0]] + " " + rest[loc[1]:]
+		}
+	}
+
+	title := strings.Trim(quickAddSpaces.ReplaceAllString(rest, " "), " ,;:-")
+	if title == "" {
+		title = strings.TrimSpace(text)
+	}
+	result.Title = capitalize(title)
+	return result
+}
+
+func resolveQuickAddDate(m []string, today time.Time, weekStart time.Weekday) (time.Time, bool) {
+	switch {
+	case m[1] != "":
+		due, err := time.Parse("2006-01-02", m[1])
+		return due, err == nil
+	case m[2] != "":
+		if strings.EqualFold(m[2], "tomorrow") {
+			return today.AddDate(0, 0, 1), true
+		}
+		return today, true
+	case m[3] != "":
+		n, _ := strconv.Atoi(m[3])
+		if strings.HasPrefix(strings.ToLower(m[4]), "week") {
+			n *= 7
+		}
+		return today.AddDate(0, 0, n), true
+	case m[5] != "":
+		return startOfWeek(today, weekStart).AddDate(0, 0, 7), true
+	case m[7] != "":
+		day := weekdays[strings.ToLower(m[7])]
+		if strings.EqualFold(m[6], "next") {
+			nextWeek := startOfWeek(today, weekStart).AddDate(0, 0, 7)
+			return nextWeek.AddDate(0, 0, (int(day)-int(weekStart)+7)%7), true
+		}
+		return today.AddDate(0, 0, (int(day)-int(today.Weekday())+7)%7), true
+	}
+	return time.Time{}, false
+}
+
+func startOfWeek(day time.Time, weekStart time.Weekday) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) - int(weekStart) + 7) % 7))
+}
+
+// submatches returns the text of every group of a FindStringSubmatchIndex match, "" for groups that
+// did not take part.
+func submatches(s string, loc []int) []string {
+	m := make([]string, len(loc)/2)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return m
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/aiprovider"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Parsers that can read a quick-add text.
+const (
+	QuickAddParserAI    = "ai"
+	QuickAddParserRules = "rules"
+)
+
+var ErrQuickAddNoProject = errors.New("no project for the task: add a #tag naming one of your projects, pass projectId or set a default project")
+
+// quickAddInstruction is the system prompt of quick-add. It is built in rather than taken from the
+// prompts table, which holds the prompts for images.
+const quickAddInstruction = `You turn a short note into a to-do task. Reply with JSON only, in this shape:
+{"title": "...", "description": "...", "priority": "low|medium|high", "dueDate": "YYYY-MM-DD"}
+The title is a short imperative sentence without the date, priority or #tags. The description adds any
+detail from the note, or is empty. Leave out priority and dueDate unless the note states them.
+Today is %s, a %s, and weeks begin on %s.`
+
+// quickAddSchema is the reply to quickAddInstruction.
+var quickAddSchema = &aiprovider.Schema{
+	Name:        "task",
+	Description: "The task the note describes",
+	Definition:  extractedTaskSchema,
+}
+
+type QuickAddService struct {
+	AIServerRepository *repository.AIServerRepository
+	ProjectRepository  *repository.ProjectRepository
+	TaskService        *TaskService
+	PreferencesService *PreferencesService
+	AIUsageService     *AIUsageService
+}
+
+func NewQuickAddService(aiRepo *repository.AIServerRepository, projectRepo *repository.ProjectRepository, taskService *TaskService,
+	preferencesService *PreferencesService, aiUsageService *AIUsageService) *QuickAddService {
+	return &QuickAddService{
+		AIServerRepository: aiRepo,
+		ProjectRepository:  projectRepo,
+		TaskService:        taskService,
+		PreferencesService: preferencesService,
+		AIUsageService:     aiUsageService,
+	}
+}
+
+// QuickAdd creates a task from a sentence. The AI of the user reads it when configured, otherwise, or
+// when the AI fails, the rule-based parser does. The task goes to the project named by a #tag, else to
+// data.ProjectId, else to the user's default project.
+func (s *QuickAddService) QuickAdd(ctx context.Context, userID uint, data request.QuickAddRequestDto) (*response.QuickAddResponseDto, error) {
+	today := s.PreferencesService.Today(userID)
+	weekStart := s.PreferencesService.WeekStart(userID)
+	parsed := parseQuickAdd(data.Text, today, weekStart)
+
+	projectID, err := s.resolveProject(userID, parsed.ProjectTag, data.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	task := request.CreateTaskRequestDto{
+		Title:       truncateRunes(parsed.Title, maxTaskTitleLength),
+		Description: truncateRunes(strings.TrimSpace(data.Text), maxTaskDescriptionLength),
+		Priority:    parsed.Priority,
+	}
+	if parsed.DueDate != nil {
+		task.DueDate = parsed.DueDate.Format("2006-01-02")
+	}
+	parser := QuickAddParserRules
+
+	if settings, err := s.AIServerRepository.FindByUserID(userID); err == nil {
+		aiTask, err := s.parseWithAI(ctx, settings, data.Text, today, weekStart)
+		if err == nil {
+			task, parser = *aiTask, QuickAddParserAI
+		} else {
+			fmt.Printf("Quick-add AI parsing failed for user %d, using the rules: %v\n", userID, err)
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	created, err := s.TaskService.SaveTask(&task, int(projectID), int(userID))
+	if err != nil {
+		return nil, err
+	}
+	return &response.QuickAddResponseDto{Task: created, Parser: parser}, nil
+}
+
+func (s *QuickAddService) resolveProject(userID uint, tag string, projectID *uint) (uint, error) {
+	if tag != "" {
+		project, err := s.ProjectRepository.FindByNameForUser(int(userID), tag)
+		if err == nil {
+			return project.ID, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+	}
+	if projectID != nil {
+		allowed, err := s.ProjectRepository.CanUse(userID, *projectID)
+		if err != nil {
+			return 0, err
+		}
+		if !allowed {
+			return 0, ErrProjectNotFound
+		}
+		return *projectID, nil
+	}
+	if id := s.PreferencesService.DefaultProjectID(userID); id != nil {
+		return *id, nil
+	}
+	return 0, ErrQuickAddNoProject
+}
+
+func (s *QuickAddService) parseWithAI(ctx context.Context, settings *models.AIServerSettings, text string, today time.Time,
+	weekStart time.Weekday) (*request.CreateTaskRequestDto, error) {
+	provider, err := s.AIUsageService.Provider(settings, models.AIFeatureQuickAdd, nil)
+	if err != nil {
+		return nil, err
+	}
+	var item extractedTask
+	_, err = aiprovider.CompleteJSON(ctx, provider, aiprovider.Request{
+		System:    fmt.Sprintf(quickAddInstruction, today.Format("2006-01-02"), today.Weekday(), weekStart),
+		Prompt:    text,
+		Schema:    quickAddSchema,
+		MaxTokens: 500,
+	}, aiprovider.DefaultAttempts, &item)
+	if err != nil {
+		return nil, err
+	}
+	task, ok := item.toCreateTaskRequest(text)
+	if !ok {
+		return nil, errors.New("AI response has no title or description long enough for a task")
+	}
+	return &task, nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"SimpleToDo/config"
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/mailer"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	settingRegistrationMode    = "registration.mode"
+	settingRegistrationDomains = "registration.allowed_domains"
+
+	InvitationTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrRegistrationNotAllowed = errors.New("registration not allowed")
+	ErrInvitationForbidden    = errors.New("not allowed to send this invitation")
+)
+
+type RegistrationService struct {
+	SettingRepository    *repository.SettingRepository
+	InvitationRepository *repository.InvitationRepository
+	ProjectRepository    *repository.ProjectRepository
+	RoleRepository       *repository.RoleRepository
+	UserRepository       *repository.UserRepository
+	PreferencesService   *PreferencesService
+}
+
+func NewRegistrationService(settingRepo *repository.SettingRepository, invitationRepo *repository.InvitationRepository,
+	projectRepo *repository.ProjectRepository, roleRepo *repository.RoleRepository,
+	userRepo *repository.UserRepository, preferencesService *PreferencesService) *RegistrationService {
+	return &RegistrationService{
+		SettingRepository:    settingRepo,
+		InvitationRepository: invitationRepo,
+		ProjectRepository:    projectRepo,
+		RoleRepository:       roleRepo,
+		UserRepository:       userRepo,
+		PreferencesService:   preferencesService,
+	}
+}
+
+func (s *RegistrationService) GetSettings() (*response.RegistrationSettingsResponseDto, error) {
+	mode, err := s.SettingRepository.Get(settingRegistrationMode, models.RegistrationModeOpen)
+	if err != nil {
+		return nil, err
+	}
+	domains, err := s.SettingRepository.Get(settingRegistrationDomains, "")
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]string, 0)
+	for _, d := range strings.Split(domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			allowed = append(allowed, d)
+		}
+	}
+	return &response.RegistrationSettingsResponseDto{Mode: mode, AllowedDomains: allowed}, nil
+}
+
+func (s *RegistrationService) UpdateSettings(data request.UpdateRegistrationSettingsRequest) (*response.RegistrationSettingsResponseDto, error) {
+	var domains []string
+	for _, d := range data.AllowedDomains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+		if d != "" {
+			domains = append(domains, d)
+		}
+	}
+	if data.Mode == models.RegistrationModeDomains && len(domains) == 0 {
+		return nil, errors.New("allowedDomains is required when mode is domains")
+	}
+
+	if err := s.SettingRepository.Set(settingRegistrationMode, data.Mode); err != nil {
+		return nil, err
+	}
+	if err := s.SettingRepository.Set(settingRegistrationDomains, strings.Join(domains, ",")); err != nil {
+		return nil, err
+	}
+	return s.GetSettings()
+}
+
+// CheckRegistration decides whether email may sign up. A valid invitation addressed to email is accepted
+// in every mode and is returned so the caller can complete it once the user exists.
+func (s *RegistrationService) CheckRegistration(email, inviteToken string) (*models.Invitation, error) {
+	if inviteToken != "" {
+		invitation, err := s.InvitationRepository.FindPendingByToken(inviteToken)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid or expired invitation", ErrRegistrationNotAllowed)
+		}
+		if !strings.EqualFold(strings.TrimSpace(invitation.Email), strings.TrimSpace(email)) {
+			return nil, fmt.Errorf("%w: the invitation was sent to a different email", ErrRegistrationNotAllowed)
+		}
+		return invitation, nil
+	}
+
+	settings, err := s.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	switch settings.Mode {
+	case models.RegistrationModeClosed:
+		return nil, fmt.Errorf("%w: registration is closed", ErrRegistrationNotAllowed)
+	case models.RegistrationModeInvite:
+		return nil, fmt.Errorf("%w: registration requires an invitation", ErrRegistrationNotAllowed)
+	case models.RegistrationModeDomains:
+		_, domain, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+		for _, allowed := range settings.AllowedDomains {
+			if domain == allowed {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: email domain is not allowed", ErrRegistrationNotAllowed)
+	}
+	return nil, nil
+}
+
+// AcceptInvitation consumes the invitation and adds the new user to the invited project, if any.
+func (s *RegistrationService) AcceptInvitation(invitation *models.Invitation, userId uint) error {
+	if err := s.InvitationRepository.MarkAccepted(invitation.ID); err != nil {
+		return err
+	}
+	if invitation.ProjectId != nil {
+		return s.ProjectRepository.AddMember(*invitation.ProjectId, userId)
+	}
+	return nil
+}
+
+// Invite emails an invitation. Users with users:manage can invite to any project or none, project owners
+// can invite to their own projects.
+func (s *RegistrationService) Invite(inviterId uint, inviterRoleId uint, data request.CreateInvitationRequest) (*response.InvitationResponseDto, error) {
+	canManageUsers, err := s.RoleRepository.HasPermission(inviterRoleId, models.PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+	inviter, err := s.UserRepository.FindByID(inviterId)
+	if err != nil {
+		return nil, err
+	}
+
+	projectName := ""
+	if data.ProjectId != nil {
+		project, err := s.ProjectRepository.FindById(int(*data.ProjectId))
+		if err != nil {
+			return nil, err
+		}
+		if project.UserId != inviterId && !canManageUsers {
+			return nil, fmt.Errorf("%w: only the project owner can invite to this project", ErrInvitationForbidden)
+		}
+		projectName = project.Name
+	} else if !canManageUsers {
+		return nil, fmt.Errorf("%w: a projectId you own is required", ErrInvitationForbidden)
+	}
+
+	email := strings.TrimSpace(data.Email)
+	if exists, err := s.UserRepository.ExistsByEmail(email); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, repository.ErrEmailTaken
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.Invitation{
+		Email:       email,
+		ProjectId:   data.ProjectId,
+		InvitedById: inviterId,
+		ExpiresAt:   time.Now().Add(InvitationTTL),
+	}
+	if err := s.InvitationRepository.Create(invitation, token); err != nil {
+		return nil, err
+	}
+
+	m, err := mailer.New()
+	if err != nil {
+		return nil, err
+	}
+	base := config.GetAppEnv().BaseURL
+	link := fmt.Sprintf("%s/auth?invite=%s", base, token)
+
+	// the invitee has no preferences yet, and most likely reads the language of the inviter
+	msg, err := mailer.Render(mailer.Invitation, s.PreferencesService.Locale(inviterId), map[string]string{
+		"InviterName": strings.TrimSpace(inviter.FirstName + " " + inviter.LastName),
+		"ProjectName": projectName,
+		"InviteURL":   link,
+		"Year":        strconv.Itoa(time.Now().Year()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		errSent := m.SendMessage(email, msg)
+		if errSent != nil {
+			fmt.Printf("Error sending invitation email: %v\n", errSent)
+		} else {
+			fmt.Println("Invitation email sent successfully")
+		}
+	}()
+
+	invitation.InvitedBy = *inviter
+	if data.ProjectId != nil {
+		invitation.Project = &models.Project{ID: *data.ProjectId, Name: projectName}
+	}
+	dto := toInvitationDto(invitation)
+	return &dto, nil
+}
+
+func (s *RegistrationService) GetInvitations(pagination response.Pagination) (*response.Pagination, error) {
+	invitationsPaginated, err := s.InvitationRepository.FindAll(pagination)
+	if err != nil {
+		return nil, err
+	}
+	invitations, ok := invitationsPaginated.Items.([]*models.Invitation)
+	if !ok {
+		return nil, errors.New("error converting invitations to invitation entity")
+	}
+
+	var invitationsResponse = make([]response.InvitationResponseDto, 0)
+	for _, invitation := range invitations {
+		invitationsResponse = append(invitationsResponse, toInvitationDto(invitation))
+	}
+	invitationsPaginated.Items = invitationsResponse
+	return invitationsPaginated, nil
+}
+
+func (s *RegistrationService) RevokeInvitation(id uint) error {
+	if _, err := s.InvitationRepository.FindById(id); err != nil {
+		return err
+	}
+	return s.InvitationRepository.Revoke(id)
+}
+
+// PreviewInvitation returns what the registration form needs to pre-fill for a pending invitation.
+func (s *RegistrationService) PreviewInvitation(token string) (*response.InvitationPreviewResponseDto, error) {
+	invitation, err := s.InvitationRepository.FindPendingByToken(token)
+	if err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+	preview := &response.InvitationPreviewResponseDto{Email: invitation.Email, ExpiresAt: invitation.ExpiresAt}
+	if invitation.Project != nil {
+		preview.ProjectName = invitation.Project.Name
+	}
+	return preview, nil
+}
+
+func toInvitationDto(invitation *models.Invitation) response.InvitationResponseDto {
+	dto := response.InvitationResponseDto{
+		Id:         invitation.ID,
+		Email:      invitation.Email,
+		ProjectId:  invitation.ProjectId,
+		InvitedBy:  invitation.InvitedBy.Email,
+		ExpiresAt:  invitation.ExpiresAt,
+		Used:       invitation.Used,
+		AcceptedAt: invitation.AcceptedAt,
+	}
+	if invitation.Project != nil {
+		dto.ProjectName = invitation.Project.Name
+	}
+	return dto
+}
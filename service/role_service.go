@@ -0,0 +1,204 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"errors"
+	"fmt"
+)
+
+const AdminRoleId = 1
+
+var (
+	ErrOwnRoleChange     = errors.New("users cannot change their own role")
+	ErrAdminRoleRequired = errors.New("only administrators can grant or take away the admin role")
+	ErrOwnRoleEdit       = errors.New("users cannot change the permissions of their own role")
+	ErrPermissionNotHeld = errors.New("only administrators can grant permissions they do not hold")
+)
+
+type RoleService struct {
+	RoleRepository *repository.RoleRepository
+	UserRepository *repository.UserRepository
+}
+
+func NewRoleService(roleRepo *repository.RoleRepository, userRepo *repository.UserRepository) *RoleService {
+	return &RoleService{
+		RoleRepository: roleRepo,
+		UserRepository: userRepo,
+	}
+}
+
+func toRoleDto(role *models.Role) response.RoleResponseDto {
+	var permissions = make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		permissions = append(permissions, p.Name)
+	}
+	return response.RoleResponseDto{
+		Id:          role.ID,
+		Name:        role.Name,
+		Value:       role.Value,
+		Permissions: permissions,
+	}
+}
+
+func (s *RoleService) GetAll(pagination response.Pagination) (*response.Pagination, error) {
+	rolesPaginated, err := s.RoleRepository.FindAll(pagination)
+	if err != nil {
+		return nil, err
+	}
+	roles, ok := rolesPaginated.Items.([]*models.Role)
+	if !ok {
+		return nil, errors.New("error converting roles to role entity")
+	}
+
+	var rolesResponse = make([]response.RoleResponseDto, 0)
+	for _, role := range roles {
+		rolesResponse = append(rolesResponse, toRoleDto(role))
+	}
+	rolesPaginated.Items = rolesResponse
+	return rolesPaginated, nil
+}
+
+func (s *RoleService) GetAllPermissions(pagination response.Pagination) (*response.Pagination, error) {
+	permissionsPaginated, err := s.RoleRepository.FindAllPermissions(pagination)
+	if err != nil {
+		return nil, err
+	}
+	permissions, ok := permissionsPaginated.Items.([]*models.Permission)
+	if !ok {
+		return nil, errors.New("error converting permissions to permission entity")
+	}
+
+	var permissionsResponse = make([]response.PermissionResponseDto, 0)
+	for _, p := range permissions {
+		permissionsResponse = append(permissionsResponse, response.PermissionResponseDto{
+			Id:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+		})
+	}
+	permissionsPaginated.Items = permissionsResponse
+	return permissionsPaginated, nil
+}
+
+// Create creates the role on behalf of the caller, who must hold every permission of it unless they are
+// an administrator.
+func (s *RoleService) Create(callerRoleId uint, data request.CreateRoleRequest) (*response.RoleResponseDto, error) {
+	permissions, err := s.resolvePermissions(data.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGrantable(callerRoleId, nil, permissions); err != nil {
+		return nil, err
+	}
+	role := &models.Role{
+		Name:        data.Name,
+		Value:       data.Value,
+		Permissions: permissions,
+	}
+	if err := s.RoleRepository.Save(role); err != nil {
+		return nil, err
+	}
+	roleDto := toRoleDto(role)
+	return &roleDto, nil
+}
+
+// SetPermissions replaces the permissions of the role on behalf of the caller. Unless they are an
+// administrator, the caller cannot change their own role nor add a permission they do not hold, so that
+// roles:manage cannot be used to gain more permissions.
+func (s *RoleService) SetPermissions(callerRoleId, id uint, data request.UpdateRolePermissionsRequest) (*response.RoleResponseDto, error) {
+	if id == AdminRoleId {
+		return nil, errors.New("admin role permissions cannot be changed")
+	}
+	if callerRoleId != AdminRoleId && callerRoleId == id {
+		return nil, ErrOwnRoleEdit
+	}
+	role, err := s.RoleRepository.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.resolvePermissions(data.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGrantable(callerRoleId, role.Permissions, permissions); err != nil {
+		return nil, err
+	}
+	if err := s.RoleRepository.ReplacePermissions(role, permissions); err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+	roleDto := toRoleDto(role)
+	return &roleDto, nil
+}
+
+// AssignToUser gives the user the role on behalf of the caller. Nobody can change their own role, and
+// only administrators can make a user an administrator or change the role of one.
+func (s *RoleService) AssignToUser(callerId, callerRoleId, roleId, userId uint) error {
+	if callerId == userId {
+		return ErrOwnRoleChange
+	}
+	if _, err := s.RoleRepository.FindById(roleId); err != nil {
+		return err
+	}
+	user, err := s.UserRepository.FindByID(userId)
+	if err != nil {
+		return err
+	}
+	if callerRoleId != AdminRoleId && (roleId == AdminRoleId || user.RoleId == AdminRoleId) {
+		return ErrAdminRoleRequired
+	}
+	return s.UserRepository.UpdateRole(userId, roleId)
+}
+
+func (s *RoleService) GetPermissionNames(roleId uint) ([]string, error) {
+	return s.RoleRepository.FindPermissionNamesByRoleId(roleId)
+}
+
+func (s *RoleService) HasPermission(roleId uint, permission string) (bool, error) {
+	return s.RoleRepository.HasPermission(roleId, permission)
+}
+
+// checkGrantable refuses the permissions a role would gain over current that the caller does not hold,
+// unless the caller is an administrator.
+func (s *RoleService) checkGrantable(callerRoleId uint, current, permissions []models.Permission) error {
+	if callerRoleId == AdminRoleId {
+		return nil
+	}
+	held, err := s.RoleRepository.FindPermissionNamesByRoleId(callerRoleId)
+	if err != nil {
+		return err
+	}
+	grantable := make(map[string]bool, len(held)+len(current))
+	for _, name := range held {
+		grantable[name] = true
+	}
+	for _, p := range current {
+		grantable[p.Name] = true
+	}
+	for _, p := range permissions {
+		if !grantable[p.Name] {
+			return fmt.Errorf("%w: %s", ErrPermissionNotHeld, p.Name)
+		}
+	}
+	return nil
+}
+
+func (s *RoleService) resolvePermissions(names []string) ([]models.Permission, error) {
+	permissions, err := s.RoleRepository.FindPermissionsByNames(names)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		known[p.Name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown permission: %s", name)
+		}
+	}
+	return permissions, nil
+}
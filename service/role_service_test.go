@@ -0,0 +1,129 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	testManagerRoleId = 2
+	testEditorRoleId  = 3
+)
+
+// newTestRoleService returns a RoleService on a fresh database with the admin role holding every
+// permission, a manager role holding roles:manage and prompts:manage, and an editor role holding
+// prompts:manage and audit:read.
+func newTestRoleService(t *testing.T) *RoleService {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening the database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.User{}); err != nil {
+		t.Fatalf("migrating the database: %v", err)
+	}
+
+	permissions := map[string]models.Permission{}
+	for i, name := range []string{models.PermissionUsersManage, models.PermissionRolesManage,
+		models.PermissionPromptsManage, models.PermissionAuditRead} {
+		permissions[name] = models.Permission{ID: uint(i + 1), Name: name}
+	}
+	roles := []models.Role{
+		{ID: AdminRoleId, Name: "Admin", Permissions: []models.Permission{permissions[models.PermissionUsersManage],
+			permissions[models.PermissionRolesManage], permissions[models.PermissionPromptsManage], permissions[models.PermissionAuditRead]}},
+		{ID: testManagerRoleId, Name: "Manager", Permissions: []models.Permission{permissions[models.PermissionRolesManage],
+			permissions[models.PermissionPromptsManage]}},
+		{ID: testEditorRoleId, Name: "Editor", Permissions: []models.Permission{permissions[models.PermissionPromptsManage],
+			permissions[models.PermissionAuditRead]}},
+	}
+	if err := db.Create(&roles).Error; err != nil {
+		t.Fatalf("creating the roles: %v", err)
+	}
+	return NewRoleService(repository.NewRoleRepository(db), repository.NewUserRepository(db))
+}
+
+func TestRoleServiceSetPermissions(t *testing.T) {
+	tests := []struct {
+		name         string
+		callerRoleId uint
+		roleId       uint
+		permissions  []string
+		wantErr      error
+	}{
+		{"manager adds a permission to their own role", testManagerRoleId, testManagerRoleId,
+			[]string{models.PermissionRolesManage, models.PermissionPromptsManage, models.PermissionUsersManage}, ErrOwnRoleEdit},
+		{"manager drops a permission of their own role", testManagerRoleId, testManagerRoleId,
+			[]string{models.PermissionRolesManage}, ErrOwnRoleEdit},
+		{"manager adds a permission they do not hold", testManagerRoleId, testEditorRoleId,
+			[]string{models.PermissionPromptsManage, models.PermissionAuditRead, models.PermissionUsersManage}, ErrPermissionNotHeld},
+		{"manager adds a permission they hold", testManagerRoleId, testEditorRoleId,
+			[]string{models.PermissionPromptsManage, models.PermissionAuditRead, models.PermissionRolesManage}, nil},
+		{"manager drops a permission they do not hold", testManagerRoleId, testEditorRoleId,
+			[]string{models.PermissionPromptsManage}, nil},
+		{"admin adds any permission", AdminRoleId, testEditorRoleId,
+			[]string{models.PermissionUsersManage}, nil},
+		{"admin changes the role of a manager", AdminRoleId, testManagerRoleId,
+			[]string{models.PermissionRolesManage, models.PermissionUsersManage}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestRoleService(t)
+			before, _ := s.GetPermissionNames(tt.roleId)
+
+			role, err := s.SetPermissions(tt.callerRoleId, tt.roleId, request.UpdateRolePermissionsRequest{Permissions: tt.permissions})
+			after, _ := s.GetPermissionNames(tt.roleId)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SetPermissions() error = %v, want %v", err, tt.wantErr)
+				}
+				if !reflect.DeepEqual(after, before) {
+					t.Errorf("permissions = %v, want them unchanged %v", after, before)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetPermissions() error = %v", err)
+			}
+			if len(role.Permissions) != len(tt.permissions) || len(after) != len(tt.permissions) {
+				t.Errorf("permissions = %v, stored %v, want %v", role.Permissions, after, tt.permissions)
+			}
+		})
+	}
+}
+
+func TestRoleServiceSetPermissionsOfAdmin(t *testing.T) {
+	s := newTestRoleService(t)
+	if _, err := s.SetPermissions(AdminRoleId, AdminRoleId, request.UpdateRolePermissionsRequest{}); err == nil {
+		t.Fatal("SetPermissions() changed the admin role")
+	}
+}
+
+func TestRoleServiceCreate(t *testing.T) {
+	tests := []struct {
+		name         string
+		callerRoleId uint
+		permissions  []string
+		wantErr      error
+	}{
+		{"manager grants a permission they hold", testManagerRoleId, []string{models.PermissionPromptsManage}, nil},
+		{"manager grants a permission they do not hold", testManagerRoleId,
+			[]string{models.PermissionPromptsManage, models.PermissionUsersManage}, ErrPermissionNotHeld},
+		{"admin grants any permission", AdminRoleId, []string{models.PermissionUsersManage, models.PermissionAuditRead}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestRoleService(t)
+			_, err := s.Create(tt.callerRoleId, request.CreateRoleRequest{Name: "Support", Value: "support", Permissions: tt.permissions})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionRevoked   = errors.New("session has been revoked")
+	ErrAccountSuspended = errors.New("account suspended")
+)
+
+// SessionService decides whether a signature-valid JWT still represents an active session.
+type SessionService struct {
+	UserRepository          *repository.UserRepository
+	ImpersonationRepository *repository.ImpersonationRepository
+}
+
+func NewSessionService(userRepo *repository.UserRepository, impersonationRepo *repository.ImpersonationRepository) *SessionService {
+	return &SessionService{UserRepository: userRepo, ImpersonationRepository: impersonationRepo}
+}
+
+// ValidateSession rejects tokens of suspended users, tokens issued before the user's last revocation and
+// impersonation tokens whose session has been ended. impersonationId is zero for regular tokens. It
+// returns the current email and role of the user, which take precedence over the claims of the token so
+// that a role change applies at once.
+func (s *SessionService) ValidateSession(userId uint, issuedAt time.Time, impersonationId uint) (*models.User, error) {
+	user, err := s.UserRepository.FindSessionState(userId)
+	if err != nil {
+		return nil, ErrSessionRevoked
+	}
+	if user.Suspended {
+		return nil, ErrAccountSuspended
+	}
+	if user.TokensValidAfter != nil && issuedAt.Before(*user.TokensValidAfter) {
+		return nil, ErrSessionRevoked
+	}
+	if impersonationId != 0 {
+		session, err := s.ImpersonationRepository.FindById(impersonationId)
+		if err != nil || session.UserId != userId || session.EndedAt != nil || time.Now().After(session.ExpiresAt) {
+			return nil, ErrSessionRevoked
+		}
+	}
+	return user, nil
+}
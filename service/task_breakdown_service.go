@@ -0,0 +1,186 @@
+package service
+
+import (
+	"SimpleToDo/dto/request"
+	"SimpleToDo/dto/response"
+	"SimpleToDo/models"
+	"SimpleToDo/repository"
+	"SimpleToDo/util/aiprovider"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+)
+
+var (
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrNoStepsSuggested = errors.New("the AI suggested no steps for the task")
+)
+
+// maxBreakdownContextTasks caps how many of the project's other tasks are listed to the model.
+const maxBreakdownContextTasks = 30
+
+// defaultBreakdownPrompt is used while no prompt with the breakdown purpose exists.
+const defaultBreakdownPrompt = `You are a productivity assistant. Break the task you are given into small, concrete steps that
+can each be done in one sitting, in the order they should be done. Do not repeat tasks the project already has.`
+
+// breakdownInstruction is appended to every breakdown prompt so that the reply has the shape of
+// taskListSchema.
+const breakdownInstruction = `Reply with JSON only, in this shape:
+{"tasks": [{"title": "...", "description": "...", "priority": "low|medium|high", "dueDate": "YYYY-MM-DD"}]}
+Add one entry per step, at most 15. Titles have at least 5 characters and descriptions at least 10. Leave out
+priority and dueDate unless the task suggests them, and never give a dueDate after the task's own. Today is %s.`
+
+type TaskBreakdownService struct {
+	AIServerRepository *repository.AIServerRepository
+	PromptRepository   *repository.PromptRepository
+	TaskRepository     *repository.TaskRepository
+	ProjectRepository  *repository.ProjectRepository
+	TaskService        *TaskService
+	PreferencesService *PreferencesService
+	AIUsageService     *AIUsageService
+}
+
+func NewTaskBreakdownService(aiRepo *repository.AIServerRepository, promptRepo *repository.PromptRepository,
+	taskRepo *repository.TaskRepository, projectRepo *repository.ProjectRepository, taskService *TaskService,
+	preferencesService *PreferencesService, aiUsageService *AIUsageService) *TaskBreakdownService {
+	return &TaskBreakdownService{
+		AIServerRepository: aiRepo,
+		PromptRepository:   promptRepo,
+		TaskRepository:     taskRepo,
+		ProjectRepository:  projectRepo,
+		TaskService:        taskService,
+		PreferencesService: preferencesService,
+		AIUsageService:     aiUsageService,
+	}
+}
+
+// Suggest asks the user's AI provider to split the task into smaller steps. The steps are not saved;
+// the user accepts them, possibly edited, with Accept. A non-nil stream receives the JSON reply while it
+// is written.
+func (s *TaskBreakdownService) Suggest(ctx context.Context, userID uint, taskID int, promptID *uint,
+	stream *aiprovider.Stream) ([]request.CreateTaskRequestDto, error) {
+	task, err := s.findTask(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := s.ProjectRepository.FindById(int(task.ProjectId))
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := s.AIServerRepository.FindByUserID(userID)
+	if err != nil {
+		return nil, ErrAINotConfigured
+	}
+	systemPrompt, usedPromptID, err := s.resolvePrompt(promptID)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.AIUsageService.Provider(settings, models.AIFeatureBreakdown, usedPromptID)
+	if err != nil {
+		return nil, err
+	}
+	var reply extractedTaskList
+	_, err = aiprovider.CompleteJSON(ctx, provider, aiprovider.Request{
+		System: systemPrompt + "\n\n" + fmt.Sprintf(breakdownInstruction, s.PreferencesService.Today(userID).Format("2006-01-02")),
+		Prompt: breakdownContext(task, &project),
+		Schema: taskListSchema,
+		Stream: stream,
+	}, aiprovider.DefaultAttempts, &reply)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := reply.toCreateTaskRequests()
+	if len(steps) == 0 {
+		return nil, ErrNoStepsSuggested
+	}
+	return steps, nil
+}
+
+// Accept creates the steps as new tasks in the project of the task that was broken down.
+func (s *TaskBreakdownService) Accept(userID uint, taskID int, steps []request.CreateTaskRequestDto) ([]response.TaskResponseDto, error) {
+	task, err := s.findTask(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return s.TaskService.SaveTasks(steps, int(task.ProjectId), int(userID))
+}
+
+// findTask returns the task if it is in a project the user owns or is a member of. Tasks of other
+// projects are reported as not found.
+func (s *TaskBreakdownService) findTask(userID uint, taskID int) (*models.Task, error) {
+	task, err := s.TaskRepository.FindById(taskID)
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+	allowed, err := s.ProjectRepository.CanUse(userID, task.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrTaskNotFound
+	}
+	return &task, nil
+}
+
+// resolvePrompt returns the system prompt of the requested breakdown prompt, else of the default one,
+// else the built-in prompt, with the ID of the prompt when it came from the prompts table.
+func (s *TaskBreakdownService) resolvePrompt(promptID *uint) (string, *uint, error) {
+	if promptID != nil {
+		prompt, err := s.PromptRepository.FindByIdAndPurpose(*promptID, models.PromptPurposeBreakdown)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, ErrPromptNotFound
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return prompt.SystemPrompt, &prompt.ID, nil
+	}
+	prompt, err := s.PromptRepository.FindDefault(models.PromptPurposeBreakdown)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultBreakdownPrompt, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return prompt.SystemPrompt, &prompt.ID, nil
+}
+
+// breakdownContext describes the task and its project to the model.
+func breakdownContext(task *models.Task, project *models.Project) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project: %s\n", project.Name)
+	if project.Description != "" {
+		fmt.Fprintf(&b, "Project description: %s\n", project.Description)
+	}
+
+	var others []string
+	for _, other := range project.Tasks {
+		if other.ID == task.ID {
+			continue
+		}
+		if len(others) == maxBreakdownContextTasks {
+			break
+		}
+		others = append(others, "- "+other.Title)
+	}
+	if len(others) > 0 {
+		fmt.Fprintf(&b, "Other tasks of the project:\n%s\n", strings.Join(others, "\n"))
+	}
+
+	fmt.Fprintf(&b, "\nTask to break down: %s\n", task.Title)
+	if task.Description != "" {
+		fmt.Fprintf(&b, "Task description: %s\n", task.Description)
+	}
+	if task.Priority != "" {
+		fmt.Fprintf(&b, "Priority: %s\n", task.Priority)
+	}
+	if task.DueDate != nil {
+		fmt.Fprintf(&b, "Due date: %s\n", task.DueDate.Format("2006-01-02"))
+	}
+	return b.String()
+}
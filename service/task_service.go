@@ -6,28 +6,50 @@ import (
 	"SimpleToDo/models"
 	"SimpleToDo/repository"
 	"SimpleToDo/util/mapper"
+	"encoding/json"
 	"errors"
 	"gorm.io/gorm"
+	"time"
+)
+
+// Values of the due filter of task lists. Days are counted in the user's timezone.
+const (
+	TaskDueToday    = "today"
+	TaskDueOverdue  = "overdue"
+	TaskDueUpcoming = "upcoming"
 )
 
 type TaskService struct {
-	TaskRepository   *repository.TaskRepository
-	StatusRepository *repository.StatusRepository
-	TaskMapper       *mapper.TaskMapperImpl
+	TaskRepository     *repository.TaskRepository
+	StatusRepository   *repository.StatusRepository
+	ProjectRepository  *repository.ProjectRepository
+	TaskMapper         *mapper.TaskMapperImpl
+	PreferencesService *PreferencesService
 }
 
 func NewTaskService(taskRepo *repository.TaskRepository, statusRepo *repository.StatusRepository,
-	taskMapper *mapper.TaskMapperImpl) *TaskService {
+	projectRepo *repository.ProjectRepository, taskMapper *mapper.TaskMapperImpl, preferencesService *PreferencesService) *TaskService {
 	return &TaskService{
-		TaskRepository:   taskRepo,
-		StatusRepository: statusRepo,
-		TaskMapper:       taskMapper,
+		TaskRepository:     taskRepo,
+		StatusRepository:   statusRepo,
+		ProjectRepository:  projectRepo,
+		TaskMapper:         taskMapper,
+		PreferencesService: preferencesService,
 	}
 }
 
-func (taskService *TaskService) GetAll(pagination response.Pagination, userId int) (*response.Pagination, error) {
+// DefaultSort returns the order tasks are listed in when the client does not ask for one.
+func (taskService *TaskService) DefaultSort(userId int) string {
+	return taskService.PreferencesService.TaskSortOrder(uint(userId))
+}
+
+func (taskService *TaskService) GetAll(pagination response.Pagination, userId int, due string) (*response.Pagination, error) {
 
-	tasksResponsePaginated, err := taskService.TaskRepository.FindAll(pagination, userId)
+	dueConditions, err := taskService.dueConditions(userId, due)
+	if err != nil {
+		return nil, err
+	}
+	tasksResponsePaginated, err := taskService.TaskRepository.FindAll(pagination, userId, dueConditions)
 	if err != nil {
 		return nil, err
 	}
@@ -51,14 +73,18 @@ func (taskService *TaskService) GetAll(pagination response.Pagination, userId in
 	return tasksResponsePaginated, nil
 }
 
-func (taskService *TaskService) GetAllTaskByProjectId(pagination response.Pagination, projectId int, userId int, taskTitle string, status string) (*response.Pagination, error) {
+func (taskService *TaskService) GetAllTaskByProjectId(pagination response.Pagination, projectId int, userId int, taskTitle string, status string, due string) (*response.Pagination, error) {
 
 	statusModel, err := taskService.StatusRepository.FindByValue(status)
 	if err != nil {
 		return nil, err
 	}
 	statusId := statusModel.ID
-	tasksResponsePaginated, err := taskService.TaskRepository.FindAllByProjectId(pagination, projectId, userId, taskTitle, int(statusId))
+	dueConditions, err := taskService.dueConditions(userId, due)
+	if err != nil {
+		return nil, err
+	}
+	tasksResponsePaginated, err := taskService.TaskRepository.FindAllByProjectId(pagination, projectId, userId, taskTitle, int(statusId), dueConditions)
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +125,11 @@ func (taskService *TaskService) SaveTask(taskToCreate *request.CreateTaskRequest
 		return response.TaskResponseDto{}, err
 	}
 
+	dueDate, err := parseDueDate(taskToCreate.DueDate)
+	if err != nil {
+		return response.TaskResponseDto{}, err
+	}
+
 	taskEntity := models.Task{
 		Model:       gorm.Model{},
 		Title:       taskToCreate.Title,
@@ -109,6 +140,8 @@ func (taskService *TaskService) SaveTask(taskToCreate *request.CreateTaskRequest
 		ProjectId:   uint(projectId),
 		User:        models.User{},
 		Project:     models.Project{},
+		DueDate:     dueDate,
+		Priority:    taskToCreate.Priority,
 	}
 
 	taskResponse, err := taskService.TaskRepository.Save(taskEntity)
@@ -119,6 +152,78 @@ func (taskService *TaskService) SaveTask(taskToCreate *request.CreateTaskRequest
 	return taskService.TaskMapper.ToDto(&taskResponse), nil
 }
 
+// SaveTasks creates all the tasks in the project, or none of them if any fails. The user must own the
+// project or be a member of it.
+func (taskService *TaskService) SaveTasks(tasksToCreate []request.CreateTaskRequestDto, projectId int, userId int) ([]response.TaskResponseDto, error) {
+	return taskService.SaveJobTasks(0, tasksToCreate, projectId, userId)
+}
+
+// SaveJobTasks is SaveTasks for the job with the given id, which records the created tasks in the job;
+// see FindJobTasks.
+func (taskService *TaskService) SaveJobTasks(jobId uint, tasksToCreate []request.CreateTaskRequestDto, projectId int, userId int) ([]response.TaskResponseDto, error) {
+	allowed, err := taskService.ProjectRepository.CanUse(uint(userId), uint(projectId))
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrProjectNotFound
+	}
+
+	statusFetched, err := taskService.StatusRepository.FindById(1)
+	if err != nil {
+		return nil, err
+	}
+
+	taskEntities := make([]models.Task, 0, len(tasksToCreate))
+	for _, taskToCreate := range tasksToCreate {
+		dueDate, err := parseDueDate(taskToCreate.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		taskEntities = append(taskEntities, models.Task{
+			Title:       taskToCreate.Title,
+			Description: taskToCreate.Description,
+			StatusId:    1,
+			Status:      statusFetched,
+			UserId:      uint(userId),
+			ProjectId:   uint(projectId),
+			DueDate:     dueDate,
+			Priority:    taskToCreate.Priority,
+		})
+	}
+
+	saved, err := taskService.TaskRepository.SaveAllForJob(taskEntities, uint(projectId), jobId)
+	if err != nil {
+		return nil, err
+	}
+	return taskService.toDtos(saved), nil
+}
+
+// FindJobTasks returns the tasks a job created with SaveJobTasks, or nil when it created none yet. The
+// tasks deleted since are left out.
+func (taskService *TaskService) FindJobTasks(job *models.Job) ([]response.TaskResponseDto, error) {
+	if job.Checkpoint == "" {
+		return nil, nil
+	}
+	var ids []uint
+	if err := json.Unmarshal([]byte(job.Checkpoint), &ids); err != nil {
+		return nil, err
+	}
+	tasks, err := taskService.TaskRepository.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	return taskService.toDtos(tasks), nil
+}
+
+func (taskService *TaskService) toDtos(tasks []models.Task) []response.TaskResponseDto {
+	tasksResponse := make([]response.TaskResponseDto, 0, len(tasks))
+	for i := range tasks {
+		tasksResponse = append(tasksResponse, taskService.TaskMapper.ToDto(&tasks[i]))
+	}
+	return tasksResponse
+}
+
 func (taskService *TaskService) UpdateTask(taskUpdate *request.UpdateTaskRequestDto, id int) (response.TaskResponseDto, error) {
 
 	statusFetched, err := taskService.StatusRepository.FindByValue(taskUpdate.Status)
@@ -126,11 +231,18 @@ func (taskService *TaskService) UpdateTask(taskUpdate *request.UpdateTaskRequest
 		return response.TaskResponseDto{}, err
 	}
 
+	dueDate, err := parseDueDate(taskUpdate.DueDate)
+	if err != nil {
+		return response.TaskResponseDto{}, err
+	}
+
 	taskEntity := models.Task{
 		Title:       taskUpdate.Title,
 		Description: taskUpdate.Description,
 		StatusId:    statusFetched.ID,
 		Status:      *statusFetched,
+		DueDate:     dueDate,
+		Priority:    taskUpdate.Priority,
 	}
 
 	taskResponse, err := taskService.TaskRepository.Update(taskEntity, id)
@@ -149,3 +261,50 @@ func (taskService *TaskService) DeleteTasks(taskIds []int) error {
 
 	return nil
 }
+
+// dueConditions narrows a task list to the tasks due today, overdue or upcoming for the user. Completed
+// and cancelled tasks are never overdue.
+func (taskService *TaskService) dueConditions(userId int, due string) ([]response.Condition, error) {
+	if due == "" {
+		return nil, nil
+	}
+	today := taskService.PreferencesService.Today(uint(userId))
+	condition := response.Condition{Column: "due_date", Value: today, Modifier: response.And}
+
+	switch due {
+	case TaskDueToday:
+		condition.Operator = response.Equal
+	case TaskDueUpcoming:
+		condition.Operator = response.GreaterThan
+	case TaskDueOverdue:
+		condition.Operator = response.LessThan
+		var closed []uint
+		for _, value := range []string{"completed", "cancelled"} {
+			status, err := taskService.StatusRepository.FindByValue(value)
+			if err != nil {
+				return nil, err
+			}
+			closed = append(closed, status.ID)
+		}
+		return []response.Condition{condition, {
+			Column:   "status_id",
+			Operator: response.NotIn,
+			Value:    closed,
+			Modifier: response.And,
+		}}, nil
+	default:
+		return nil, errors.New("invalid due filter")
+	}
+	return []response.Condition{condition}, nil
+}
+
+func parseDueDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	dueDate, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &dueDate, nil
+}
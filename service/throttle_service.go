@@ -0,0 +1,119 @@
+package service
+
+import (
+	"SimpleToDo/repository"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FailurePolicy describes how repeated authentication failures for one subject are slowed down:
+// the first FreeAttempts failures are free, the following ones wait an exponentially growing delay
+// (capped at MaxBackoff) and reaching LockoutAfter locks the subject for LockoutDuration.
+type FailurePolicy struct {
+	FreeAttempts    int
+	LockoutAfter    int
+	MaxBackoff      time.Duration
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration
+}
+
+var (
+	AccountFailurePolicy = FailurePolicy{
+		FreeAttempts:    3,
+		LockoutAfter:    10,
+		MaxBackoff:      5 * time.Minute,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      24 * time.Hour,
+	}
+	IPFailurePolicy = FailurePolicy{
+		FreeAttempts:    10,
+		LockoutAfter:    50,
+		MaxBackoff:      5 * time.Minute,
+		LockoutDuration: time.Hour,
+		ResetAfter:      24 * time.Hour,
+	}
+)
+
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Reason     string
+}
+
+func (e *ThrottledError) Error() string {
+	return e.Reason
+}
+
+type ThrottleService struct {
+	ThrottleRepository *repository.ThrottleRepository
+}
+
+func NewThrottleService(throttleRepo *repository.ThrottleRepository) *ThrottleService {
+	return &ThrottleService{ThrottleRepository: throttleRepo}
+}
+
+func IPActionSubject(action, ip string) string {
+	return fmt.Sprintf("ip:%s:%s", action, ip)
+}
+
+func AccountActionSubject(action, email string) string {
+	return fmt.Sprintf("account:%s:%s", action, strings.ToLower(strings.TrimSpace(email)))
+}
+
+func IPFailureSubject(ip string) string {
+	return "fail:ip:" + ip
+}
+
+func AccountFailureSubject(email string) string {
+	return "fail:account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// Hit counts one request for subject inside a fixed window and rejects it once limit is exceeded.
+func (s *ThrottleService) Hit(subject string, limit int, window time.Duration) error {
+	now := time.Now()
+	t, err := s.ThrottleRepository.Hit(subject, now, now.Add(-window))
+	if err != nil {
+		return err
+	}
+
+	if t.Hits > limit {
+		return &ThrottledError{
+			RetryAfter: t.WindowStart.Add(window).Sub(now),
+			Reason:     "too many requests",
+		}
+	}
+	return nil
+}
+
+// CheckFailures rejects the attempt while subject is backing off or locked out.
+func (s *ThrottleService) CheckFailures(subject string) error {
+	t, err := s.ThrottleRepository.FindOrInit(subject)
+	if err != nil {
+		return err
+	}
+	if wait := time.Until(t.LockedUntil); wait > 0 {
+		return &ThrottledError{RetryAfter: wait, Reason: "too many failed attempts, try again later"}
+	}
+	return nil
+}
+
+func (s *ThrottleService) RegisterFailure(subject string, policy FailurePolicy) error {
+	now := time.Now()
+	t, err := s.ThrottleRepository.RegisterFailure(subject, now, now.Add(-policy.ResetAfter))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case t.Failures >= policy.LockoutAfter:
+		return s.ThrottleRepository.LockUntil(subject, now.Add(policy.LockoutDuration))
+	case t.Failures > policy.FreeAttempts:
+		backoff := time.Second << min(t.Failures-policy.FreeAttempts-1, 20)
+		return s.ThrottleRepository.LockUntil(subject, now.Add(min(backoff, policy.MaxBackoff)))
+	}
+	return nil
+}
+
+func (s *ThrottleService) Reset(subject string) error {
+	return s.ThrottleRepository.DeleteBySubject(subject)
+}
@@ -5,22 +5,38 @@ import (
 	"SimpleToDo/dto/response"
 	"SimpleToDo/models"
 	"SimpleToDo/repository"
+	"SimpleToDo/util/aiprovider"
 	"SimpleToDo/util/mapper"
 	"errors"
 	"gorm.io/gorm"
+	"strings"
+)
+
+// apiKeyMask replaces the hidden part of API keys returned to clients.
+const apiKeyMask = "********"
+
+var (
+	ErrAPIKeyRequired  = errors.New("API key is required")
+	ErrBaseURLRequired = errors.New("base URL is required for Azure OpenAI")
+	ErrAdminTarget     = errors.New("only administrators can manage administrator accounts")
 )
 
 type UserService struct {
 	UserRepository     *repository.UserRepository
 	AIServerRepository *repository.AIServerRepository
+	ThrottleService    *ThrottleService
 	UserMapper         *mapper.UserMapperImpl
+	AuditService       *AuditService
 }
 
-func NewUserService(userRepo *repository.UserRepository, aiRepo *repository.AIServerRepository, userMapper *mapper.UserMapperImpl) *UserService {
+func NewUserService(userRepo *repository.UserRepository, aiRepo *repository.AIServerRepository,
+	throttleService *ThrottleService, userMapper *mapper.UserMapperImpl, auditService *AuditService) *UserService {
 	return &UserService{
 		UserRepository:     userRepo,
 		AIServerRepository: aiRepo,
+		ThrottleService:    throttleService,
 		UserMapper:         userMapper,
+		AuditService:       auditService,
 	}
 }
 
@@ -66,15 +82,9 @@ func (s *UserService) Update(id uint, data request.UpdateUserRequest) (*response
 	if data.LastName != "" {
 		existing.LastName = data.LastName
 	}
-	if data.Email != "" {
-		existing.Email = data.Email
-	}
 	if data.Phone != "" {
 		existing.Phone = data.Phone
 	}
-	if len(data.Image) > 0 {
-		existing.Image = data.Image
-	}
 
 	userEntity, err := s.UserRepository.Update(existing)
 	if err != nil {
@@ -84,42 +94,148 @@ func (s *UserService) Update(id uint, data request.UpdateUserRequest) (*response
 	return &userDto, err
 }
 
-func (s *UserService) Delete(id uint) error {
-	return s.UserRepository.Delete(id)
+func (s *UserService) Delete(id uint, info RequestInfo) error {
+	user, err := s.UserRepository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	err = s.UserRepository.Delete(id)
+	s.AuditService.RecordResult(info, models.SecurityEventUserDeleted, user, err)
+	return err
+}
+
+// CheckManageable refuses account actions on an administrator unless the caller is an administrator too,
+// so holders of users:manage cannot suspend or take over the accounts above them.
+func (s *UserService) CheckManageable(callerRoleId uint, id uint) error {
+	user, err := s.UserRepository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if user.RoleId == AdminRoleId && callerRoleId != AdminRoleId {
+		return ErrAdminTarget
+	}
+	return nil
+}
+
+// Unlock clears the failed login counter and any lockout on the user's account.
+func (s *UserService) Unlock(id uint, info RequestInfo) error {
+	user, err := s.UserRepository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	err = s.ThrottleService.Reset(AccountFailureSubject(user.Email))
+	s.AuditService.RecordResult(info, models.SecurityEventUserUnlocked, user, err)
+	return err
+}
+
+// SetSuspended blocks or restores the user's access. Suspending also revokes every token issued so far.
+func (s *UserService) SetSuspended(id uint, suspended bool, info RequestInfo) error {
+	user, err := s.UserRepository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	err = s.UserRepository.SetSuspended(id, suspended)
+	eventType := models.SecurityEventUserReactivated
+	if suspended {
+		eventType = models.SecurityEventUserSuspended
+	}
+	s.AuditService.RecordResult(info, eventType, user, err)
+	return err
+}
+
+func (s *UserService) ForceVerify(id uint, info RequestInfo) error {
+	user, err := s.UserRepository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	err = s.UserRepository.MarkVerified(id)
+	s.AuditService.RecordResult(info, models.SecurityEventUserVerified, user, err)
+	return err
 }
 
 func (s *UserService) GetAISettings(userID uint) (*response.AISettingsResponseDto, error) {
 	settings, err := s.AIServerRepository.FindByUserID(userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return &response.AISettingsResponseDto{BaseUrl: "", APIKey: ""}, nil
+			return &response.AISettingsResponseDto{Provider: aiprovider.OpenAI, BaseUrl: "", APIKey: ""}, nil
 		}
 		return nil, err
 	}
 
 	return &response.AISettingsResponseDto{
-		BaseUrl: settings.BaseUrl,
-		APIKey:  settings.APIKey,
-		Model:   settings.Model,
+		Provider:   settings.Provider,
+		BaseUrl:    settings.BaseUrl,
+		APIKey:     MaskAPIKey(settings.APIKey),
+		Model:      settings.Model,
+		APIVersion: settings.APIVersion,
 	}, nil
 }
 
-func (s *UserService) UpdateAISettings(userID uint, data request.UpdateAISettingsRequest) (*response.AISettingsResponseDto, error) {
+// UpdateAISettings replaces the AI settings of the user. An empty API key, or the masked one returned
+// by GetAISettings, keeps the stored key as long as the provider and the base URL stay the same.
+func (s *UserService) UpdateAISettings(userID uint, data request.UpdateAISettingsRequest, info RequestInfo) (*response.AISettingsResponseDto, error) {
+	provider := data.Provider
+	if provider == "" {
+		provider = aiprovider.OpenAI
+	}
+	if provider == aiprovider.Azure && data.BaseUrl == "" {
+		return nil, ErrBaseURLRequired
+	}
+
+	apiKey := data.APIKey
+	if apiKey == "" || strings.HasPrefix(apiKey, apiKeyMask) {
+		current, err := s.AIServerRepository.FindByUserID(userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		switch {
+		// a key is never sent to a provider or server other than the one it was entered for
+		case err != nil || current.Provider != provider || current.BaseUrl != data.BaseUrl:
+			// Ollama servers usually run without authentication
+			if apiKey != "" || provider != aiprovider.Ollama {
+				return nil, ErrAPIKeyRequired
+			}
+			apiKey = ""
+		case apiKey != "" && apiKey != MaskAPIKey(current.APIKey):
+			return nil, ErrAPIKeyRequired
+		default:
+			apiKey = current.APIKey
+		}
+	}
+
 	settings := &models.AIServerSettings{
-		UserID:  userID,
-		BaseUrl: data.BaseUrl,
-		APIKey:  data.APIKey,
-		Model:   data.Model,
+		UserID:     userID,
+		Provider:   provider,
+		BaseUrl:    data.BaseUrl,
+		APIKey:     apiKey,
+		Model:      data.Model,
+		APIVersion: data.APIVersion,
 	}
 
-	err := s.AIServerRepository.Save(settings)
-	if err != nil {
+	if err := s.AIServerRepository.Save(settings); err != nil {
 		return nil, err
 	}
+	// the API key itself never goes to the log
+	user, _ := s.UserRepository.FindByID(userID)
+	s.AuditService.Record(info, models.SecurityEventAISettingsUpdated, models.SecurityOutcomeSuccess, user,
+		"provider: "+settings.Provider+", base url: "+settings.BaseUrl+", model: "+settings.Model)
 
 	return &response.AISettingsResponseDto{
-		BaseUrl: settings.BaseUrl,
-		APIKey:  settings.APIKey,
-		Model:   settings.Model,
+		Provider:   settings.Provider,
+		BaseUrl:    settings.BaseUrl,
+		APIKey:     MaskAPIKey(settings.APIKey),
+		Model:      settings.Model,
+		APIVersion: settings.APIVersion,
 	}, nil
 }
+
+// MaskAPIKey hides all but the last four characters of an API key, enough to tell keys apart.
+func MaskAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	if len(apiKey) <= 8 {
+		return apiKeyMask
+	}
+	return apiKeyMask + apiKey[len(apiKey)-4:]
+}
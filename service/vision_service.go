@@ -5,187 +5,344 @@ import (
 	"SimpleToDo/dto/response"
 	"SimpleToDo/models"
 	"SimpleToDo/repository"
-	"bytes"
+	"SimpleToDo/util/aiprovider"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"gorm.io/gorm"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+var (
+	ErrPromptNotFound   = errors.New("prompt not found")
+	ErrNoTasksExtracted = errors.New("no tasks found in the image")
+)
+
+const (
+	// maxExtractedTasks matches the most tasks that can be created at once.
+	maxExtractedTasks = 50
+	// the lengths request.CreateTaskRequestDto accepts
+	minTaskTitleLength       = 5
+	maxTaskTitleLength       = 100
+	minTaskDescriptionLength = 10
+	maxTaskDescriptionLength = 300
+)
+
+var taskPriorities = []string{"low", "medium", "high"}
+
+// taskListInstruction is appended to every vision prompt so that the model reports all the action items
+// it finds, rather than a single task, in the shape of taskListSchema.
+const taskListInstruction = `Reply with JSON only, in this shape:
+{"tasks": [{"title": "...", "description": "...", "priority": "low|medium|high", "dueDate": "YYYY-MM-DD"}]}
+Add one entry per action item in the image. Titles have at least 5 characters and descriptions at least 10.
+Leave out priority and dueDate unless the image suggests them. Today is %s.`
+
+// taskListSchema is the reply to prompts asking for several tasks, shared by vision and task breakdown.
+var taskListSchema = &aiprovider.Schema{
+	Name:        "task_list",
+	Description: "The tasks found",
+	Definition: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"tasks": map[string]any{
+				"type":  "array",
+				"items": listedTaskSchema,
+			},
+		},
+		"required": []string{"tasks"},
+	},
+}
+
+// extractedTaskSchema is a task as the model reports it; the hints are optional and may be empty.
+var extractedTaskSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"title":       map[string]any{"type": "string", "minLength": minTaskTitleLength},
+		"description": map[string]any{"type": "string"},
+		"priority":    map[string]any{"type": "string", "enum": append([]string{""}, taskPriorities...)},
+		"dueDate":     map[string]any{"type": "string", "pattern": `^(\d{4}-\d{2}-\d{2})?$`},
+	},
+	"required": []string{"title"},
+}
+
+// listedTaskSchema is an entry of taskListSchema. Unlike extractedTaskSchema it needs a description,
+// as there is no note to fall back on, long enough for the tasks to be created as they are.
+var listedTaskSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"title":       map[string]any{"type": "string", "minLength": minTaskTitleLength},
+		"description": map[string]any{"type": "string", "minLength": minTaskDescriptionLength},
+		"priority":    extractedTaskSchema["properties"].(map[string]any)["priority"],
+		"dueDate":     extractedTaskSchema["properties"].(map[string]any)["dueDate"],
+	},
+	"required": []string{"title", "description"},
+}
+
 type VisionService struct {
 	AIServerRepository *repository.AIServerRepository
 	PromptRepository   *repository.PromptRepository
+	ProjectRepository  *repository.ProjectRepository
 	TaskService        *TaskService
+	PreferencesService *PreferencesService
+	JobService         *JobService
+	AIUsageService     *AIUsageService
 }
 
-func NewVisionService(aiRepo *repository.AIServerRepository, promptRepo *repository.PromptRepository, taskService *TaskService) *VisionService {
+func NewVisionService(aiRepo *repository.AIServerRepository, promptRepo *repository.PromptRepository,
+	projectRepo *repository.ProjectRepository, taskService *TaskService, preferencesService *PreferencesService,
+	jobService *JobService, aiUsageService *AIUsageService) *VisionService {
 	return &VisionService{
 		AIServerRepository: aiRepo,
 		PromptRepository:   promptRepo,
+		ProjectRepository:  projectRepo,
 		TaskService:        taskService,
+		PreferencesService: preferencesService,
+		JobService:         jobService,
+		AIUsageService:     aiUsageService,
 	}
 }
 
-type aiChatRequest struct {
-	Model     string      `json:"model"`
-	Messages  []aiMessage `json:"messages"`
-	MaxTokens int         `json:"max_tokens,omitempty"`
+// visionAnalyzeJob is the payload of a models.JobKindVisionAnalyze job.
+type visionAnalyzeJob struct {
+	ProjectId int                         `json:"projectId"`
+	Request   request.AnalyzeImageRequest `json:"request"`
 }
 
-type aiMessage struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"`
+// AnalyzeImage extracts the tasks in the image and, unless data.Preview, creates them in the project. It
+// returns the request.CreateTasksRequestDto to confirm for a preview, else the response.TasksResponseDto
+// of the created tasks.
+func (s *VisionService) AnalyzeImage(ctx context.Context, userID uint, projectID int, data request.AnalyzeImageRequest) (any, error) {
+	return s.analyzeImage(ctx, 0, userID, projectID, data)
 }
 
-type aiContentPart struct {
-	Type     string `json:"type"`
-	Text     string `json:"text,omitempty"`
-	ImageUrl *aiUrl `json:"image_url,omitempty"`
-}
+// analyzeImage is AnalyzeImage, run by the job with the given id unless it is 0.
+func (s *VisionService) analyzeImage(ctx context.Context, jobID, userID uint, projectID int, data request.AnalyzeImageRequest) (any, error) {
+	if err := s.checkProject(userID, projectID); err != nil {
+		return nil, err
+	}
+	tasks, err := s.ExtractTasksFromImage(ctx, userID, data.ImageBase64, data.PromptId)
+	if err != nil {
+		return nil, err
+	}
+	if data.Preview {
+		// the preview is the body to create the tasks with once the user confirmed them
+		return request.CreateTasksRequestDto{Tasks: tasks}, nil
+	}
 
-type aiUrl struct {
-	Url string `json:"url"`
+	created, err := s.TaskService.SaveJobTasks(jobID, tasks, projectID, int(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrProjectNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save tasks: %w", err)
+	}
+	return response.TasksResponseDto{Tasks: created}, nil
 }
 
-type aiChatResponse struct {
-	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	} `json:"choices"`
-	Error *struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-		Code    string `json:"code"`
-	} `json:"error,omitempty"`
+// AnalyzeImageAsync queues AnalyzeImage as a job, after checking what can be checked before the image
+// reaches the AI.
+func (s *VisionService) AnalyzeImageAsync(userID uint, projectID int, data request.AnalyzeImageRequest) (*response.JobResponseDto, error) {
+	if err := s.checkProject(userID, projectID); err != nil {
+		return nil, err
+	}
+	if _, err := s.AIServerRepository.FindByUserID(userID); err != nil {
+		return nil, ErrAINotConfigured
+	}
+	if _, err := s.resolvePrompt(userID, data.PromptId); err != nil {
+		return nil, err
+	}
+	if err := s.AIUsageService.CheckQuota(userID); err != nil {
+		return nil, err
+	}
+	return s.JobService.Enqueue(userID, models.JobKindVisionAnalyze, visionAnalyzeJob{ProjectId: projectID, Request: data})
 }
 
-func (s *VisionService) ExtractTaskFromImage(ctx context.Context, userID uint, projectIdInt uint, base64Image string) (response.TaskResponseDto, error) {
-	if base64Image == "" {
-		return response.TaskResponseDto{}, errors.New("empty image")
+// RunAnalyzeJob is the JobHandler of models.JobKindVisionAnalyze jobs. A job interrupted after it
+// created the tasks returns them rather than analyzing the image again.
+func (s *VisionService) RunAnalyzeJob(ctx context.Context, job *models.Job) (any, error) {
+	created, err := s.TaskService.FindJobTasks(job)
+	if err != nil {
+		return nil, err
+	}
+	if created != nil {
+		return response.TasksResponseDto{Tasks: created}, nil
 	}
 
-	settings, err := s.AIServerRepository.FindByUserID(userID)
-	if err != nil {
-		return response.TaskResponseDto{}, errors.New("AI configuration not found for user")
+	var payload visionAnalyzeJob
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return nil, err
 	}
+	return s.analyzeImage(ctx, job.ID, job.UserID, payload.ProjectId, payload.Request)
+}
 
-	prompts, err := s.PromptRepository.FindAll(response.Pagination{Page: 1, Limit: 1})
-	if err != nil || prompts.Items == nil {
-		return response.TaskResponseDto{}, errors.New("system prompt not configured in database")
+func (s *VisionService) checkProject(userID uint, projectID int) error {
+	allowed, err := s.ProjectRepository.CanUse(userID, uint(projectID))
+	if err != nil {
+		return err
 	}
-	promptList, ok := prompts.Items.([]*models.Prompt)
-	if !ok || len(promptList) == 0 {
-		return response.TaskResponseDto{}, errors.New("system prompt not found")
+	if !allowed {
+		return ErrProjectNotFound
 	}
-	systemPrompt := promptList[0]
+	return nil
+}
 
-	parts := strings.Split(base64Image, ",")
-	if len(parts) > 1 {
-		base64Image = parts[1]
+// resolvePrompt picks the requested prompt, else the user's default prompt, else the default prompt.
+func (s *VisionService) resolvePrompt(userID uint, promptID *uint) (*models.Prompt, error) {
+	if promptID != nil {
+		prompt, err := s.PromptRepository.FindByIdAndPurpose(*promptID, models.PromptPurposeVision)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromptNotFound
+		}
+		return prompt, err
+	}
+	if id := s.PreferencesService.DefaultPromptID(userID); id != nil {
+		prompt, err := s.PromptRepository.FindByIdAndPurpose(*id, models.PromptPurposeVision)
+		if err == nil {
+			return prompt, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	prompt, err := s.PromptRepository.FindDefault(models.PromptPurposeVision)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("system prompt not configured in database")
 	}
-	formattedImage := "data:image/jpeg;base64," + base64Image
+	return prompt, err
+}
 
-	reqBody := aiChatRequest{
-		Model: settings.Model,
-		Messages: []aiMessage{
-			{
-				Role:    "system",
-				Content: systemPrompt.SystemPrompt,
-			},
-			{
-				Role: "user",
-				Content: []aiContentPart{
-					{
-						Type: "text",
-						Text: systemPrompt.Description,
-					},
-					{
-						Type: "image_url",
-						ImageUrl: &aiUrl{
-							Url: formattedImage,
-						},
-					},
-				},
-			},
-		},
+// ExtractTasksFromImage asks the user's AI provider for the action items in the image. The tasks are
+// not saved, so that callers can show them for confirmation first.
+func (s *VisionService) ExtractTasksFromImage(ctx context.Context, userID uint, base64Image string, promptID *uint) ([]request.CreateTaskRequestDto, error) {
+	if base64Image == "" {
+		return nil, errors.New("empty image")
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	settings, err := s.AIServerRepository.FindByUserID(userID)
 	if err != nil {
-		return response.TaskResponseDto{}, err
+		return nil, ErrAINotConfigured
 	}
 
-	baseUrl := strings.TrimRight(settings.BaseUrl, "/")
-	if strings.Contains(baseUrl, "api.openai.com") && !strings.HasSuffix(baseUrl, "/v1") {
-		baseUrl += "/v1"
+	systemPrompt, err := s.resolvePrompt(userID, promptID)
+	if err != nil {
+		return nil, err
 	}
-	baseUrl = strings.TrimSuffix(baseUrl, "/chat/completions")
-	reqURL := fmt.Sprintf("%s/chat/completions", baseUrl)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewBuffer(jsonData))
+	provider, err := s.AIUsageService.Provider(settings, models.AIFeatureVision, &systemPrompt.ID)
 	if err != nil {
-		return response.TaskResponseDto{}, err
+		return nil, err
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+settings.APIKey)
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
+	var reply extractedTaskList
+	_, err = aiprovider.CompleteJSON(ctx, provider, aiprovider.Request{
+		System: systemPrompt.SystemPrompt + "\n\n" + fmt.Sprintf(taskListInstruction, s.PreferencesService.Today(userID).Format("2006-01-02")),
+		Prompt: systemPrompt.Description,
+		Images: []aiprovider.Image{imageFromBase64(base64Image)},
+		Schema: taskListSchema,
+	}, aiprovider.DefaultAttempts, &reply)
 	if err != nil {
-		return response.TaskResponseDto{}, fmt.Errorf("connection error: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
+	tasks := reply.toCreateTaskRequests()
+	if len(tasks) == 0 {
+		return nil, ErrNoTasksExtracted
+	}
+	return tasks, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		var errorResp aiChatResponse
-		_ = json.Unmarshal(bodyBytes, &errorResp)
-		if errorResp.Error != nil {
-			return response.TaskResponseDto{}, fmt.Errorf("AI Error (%s): %s", errorResp.Error.Type, errorResp.Error.Message)
+// imageFromBase64 accepts a bare base64 image or a data URL. The media type of bare images is sniffed,
+// falling back to JPEG.
+func imageFromBase64(value string) aiprovider.Image {
+	image := aiprovider.Image{MediaType: "image/jpeg", Data: value}
+	if header, data, found := strings.Cut(value, ","); found {
+		image.Data = data
+		if mediaType, ok := strings.CutPrefix(header, "data:"); ok {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.HasPrefix(mediaType, "image/") {
+				image.MediaType = mediaType
+				return image
+			}
 		}
-		return response.TaskResponseDto{}, fmt.Errorf("provider status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
-
-	var aiResp aiChatResponse
-	if err := json.Unmarshal(bodyBytes, &aiResp); err != nil {
-		return response.TaskResponseDto{}, errors.New("failed to parse AI response")
+	// 512 bytes of image need at most 684 base64 characters
+	head, _ := base64.StdEncoding.DecodeString(image.Data[:min(len(image.Data), 684)])
+	if mediaType := http.DetectContentType(head); strings.HasPrefix(mediaType, "image/") {
+		image.MediaType = mediaType
 	}
+	return image
+}
 
-	if len(aiResp.Choices) == 0 {
-		return response.TaskResponseDto{}, errors.New("no content received")
-	}
+// extractedTask is one action item as the model reports it; the hints are optional.
+type extractedTask struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Priority    string `json:"priority"`
+	DueDate     string `json:"dueDate"`
+}
 
-	rawContent := aiResp.Choices[0].Message.Content
-	cleanJson := strings.TrimSpace(rawContent)
+// extractedTaskList is the reply in the shape of taskListSchema.
+type extractedTaskList struct {
+	Tasks []extractedTask `json:"tasks"`
+}
 
-	if strings.HasPrefix(cleanJson, "```") {
-		lines := strings.Split(cleanJson, "\n")
-		if len(lines) >= 2 {
-			cleanJson = strings.Join(lines[1:len(lines)-1], "\n")
+// toCreateTaskRequests drops the hints that are not valid and cuts texts to the lengths tasks allow.
+// Items too short to be created are left out.
+func (list extractedTaskList) toCreateTaskRequests() []request.CreateTaskRequestDto {
+	tasks := make([]request.CreateTaskRequestDto, 0, len(list.Tasks))
+	for _, item := range list.Tasks {
+		task, ok := item.toCreateTaskRequest("")
+		if !ok {
+			continue
+		}
+		tasks = append(tasks, task)
+		if len(tasks) == maxExtractedTasks {
+			break
 		}
 	}
-	cleanJson = strings.TrimSpace(cleanJson)
+	return tasks
+}
 
-	var result map[string]string
-	if err := json.Unmarshal([]byte(cleanJson), &result); err != nil {
-		return response.TaskResponseDto{}, errors.New("failed to parse AI response JSON")
+// toCreateTaskRequest keeps the valid hints of the item and cuts its texts to the lengths tasks allow. A
+// description too short for a task is replaced by fallbackDescription or else the title. It reports false
+// when the title or every description is too short, as request.CreateTaskRequestDto would reject it.
+func (item extractedTask) toCreateTaskRequest(fallbackDescription string) (request.CreateTaskRequestDto, bool) {
+	title := strings.TrimSpace(item.Title)
+	if utf8.RuneCountInString(title) < minTaskTitleLength {
+		return request.CreateTaskRequestDto{}, false
+	}
+	description := ""
+	for _, candidate := range []string{item.Description, fallbackDescription, title} {
+		if candidate = strings.TrimSpace(candidate); utf8.RuneCountInString(candidate) >= minTaskDescriptionLength {
+			description = candidate
+			break
+		}
+	}
+	if description == "" {
+		return request.CreateTaskRequestDto{}, false
 	}
-
 	task := request.CreateTaskRequestDto{
-		Title:       result["title"],
-		Description: result["description"],
+		Title:       truncateRunes(title, maxTaskTitleLength),
+		Description: truncateRunes(description, maxTaskDescriptionLength),
 	}
+	if priority := strings.ToLower(strings.TrimSpace(item.Priority)); slices.Contains(taskPriorities, priority) {
+		task.Priority = priority
+	}
+	if _, err := time.Parse("2006-01-02", item.DueDate); err == nil {
+		task.DueDate = item.DueDate
+	}
+	return task, true
+}
 
-	saveTask, err := s.TaskService.SaveTask(&task, int(projectIdInt), int(userID))
-	if err != nil {
-		return response.TaskResponseDto{}, fmt.Errorf("failed to save task: %v", err)
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
 	}
-	return saveTask, nil
+	return strings.TrimSpace(string(runes[:n]))
 }
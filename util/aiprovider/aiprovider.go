@@ -0,0 +1,202 @@
+// Package aiprovider sends prompts, optionally with images, to the AI APIs users can configure:
+// OpenAI-compatible chat completions, Anthropic Messages, Ollama's native chat API and Azure OpenAI
+// deployments. Every provider takes its base URL from the configuration, so it can be pointed at a local
+// fake server. Replies can be streamed as they are generated. CompleteJSON asks for a reply matching a
+// JSON Schema and has the model repair replies that do not.
+package aiprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider names, as stored in the AI settings of a user.
+const (
+	OpenAI    = "openai"
+	Anthropic = "anthropic"
+	Ollama    = "ollama"
+	Azure     = "azure"
+)
+
+// Providers lists the supported provider names.
+var Providers = []string{OpenAI, Anthropic, Ollama, Azure}
+
+// DefaultMaxTokens caps the reply when a request does not set MaxTokens. Anthropic requires a limit.
+const DefaultMaxTokens = 4096
+
+const requestTimeout = 120 * time.Second
+
+// Image is an image sent along with the prompt.
+type Image struct {
+	// MediaType is the image's content type, e.g. image/jpeg.
+	MediaType string
+	// Data is the base64 encoded image, without a data URL prefix.
+	Data string
+}
+
+// Request is a prompt, optionally followed by further turns of the conversation.
+type Request struct {
+	System string
+	Prompt string
+	Images []Image
+	// Turns continue the conversation after the prompt, e.g. a reply and a correction of it.
+	Turns []Turn
+	// Schema asks for a JSON reply matching it. Providers pass it to their API's structured output
+	// support; CompleteJSON validates the reply.
+	Schema    *Schema
+	MaxTokens int
+	// Stream, when set, receives the reply while it is generated.
+	Stream *Stream
+}
+
+// Turn is a message of the conversation from RoleUser or RoleAssistant.
+type Turn struct {
+	Role    string
+	Content string
+}
+
+// Response is the model's reply and the tokens the provider reports as used.
+type Response struct {
+	Content      string
+	InputTokens  int
+	OutputTokens int
+}
+
+// Provider completes a prompt with one AI API.
+type Provider interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// Config selects and configures a provider.
+type Config struct {
+	// Provider is one of Providers; empty selects OpenAI.
+	Provider string
+	// BaseURL of the API; empty uses the provider's public endpoint, except for Azure where it is the
+	// resource endpoint and required.
+	BaseURL string
+	APIKey  string
+	// Model is the model name or, for Azure, the deployment name.
+	Model string
+	// APIVersion is only used by Azure; empty uses DefaultAzureAPIVersion.
+	APIVersion string
+	// HTTPClient defaults to a client with a two minute timeout.
+	HTTPClient *http.Client
+}
+
+// APIError is an error reported by the provider's API.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("AI Error (%s): %s", e.Type, e.Message)
+	}
+	return fmt.Sprintf("%s status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// New returns the provider selected by cfg.
+func New(cfg Config) (Provider, error) {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+	switch cfg.Provider {
+	case OpenAI, "":
+		return &openAIProvider{cfg: cfg, client: client}, nil
+	case Anthropic:
+		return &anthropicProvider{cfg: cfg, client: client}, nil
+	case Ollama:
+		return &ollamaProvider{cfg: cfg, client: client}, nil
+	case Azure:
+		if cfg.BaseURL == "" {
+			return nil, errors.New("azure needs the base URL of the resource")
+		}
+		return &azureProvider{cfg: cfg, client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+}
+
+func maxTokens(req Request) int {
+	if req.MaxTokens > 0 {
+		return req.MaxTokens
+	}
+	return DefaultMaxTokens
+}
+
+func baseURL(configured, fallback string) string {
+	if configured == "" {
+		return fallback
+	}
+	return strings.TrimRight(configured, "/")
+}
+
+// postJSON posts body to url and decodes a 200 reply into out. Other replies are passed to parseError,
+// which returns nil when it does not recognize the provider's error format.
+func postJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body, out any,
+	parseError func(status int, body []byte) *APIError) error {
+	resp, err := send(ctx, client, provider, url, headers, body, parseError)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("connection error: %v", err)
+	}
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		return errors.New("failed to parse AI response")
+	}
+	return nil
+}
+
+// send posts body to url and returns the reply when its status is 200, else the error parsed from it.
+func send(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body any,
+	parseError func(status int, body []byte) *APIError) (*http.Response, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connection error: %v", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("connection error: %v", err)
+	}
+	if apiErr := parseError(resp.StatusCode, bodyBytes); apiErr != nil {
+		return nil, apiErr
+	}
+	return nil, &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: string(bodyBytes)}
+}
+
+// decode reports whether body is JSON that could be decoded into out.
+func decode(body []byte, out any) bool {
+	return json.Unmarshal(body, out) == nil
+}
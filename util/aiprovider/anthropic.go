@@ -0,0 +1,227 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion = "2023-06-01"
+)
+
+// anthropicProvider calls the Anthropic Messages API.
+type anthropicProvider struct {
+	cfg    Config
+	client *http.Client
+}
+
+type messagesRequest struct {
+	Model      string              `json:"model"`
+	System     string              `json:"system,omitempty"`
+	Messages   []messagesMessage   `json:"messages"`
+	MaxTokens  int                 `json:"max_tokens"`
+	Tools      []messagesTool      `json:"tools,omitempty"`
+	ToolChoice *messagesToolChoice `json:"tool_choice,omitempty"`
+	Stream     bool                `json:"stream,omitempty"`
+}
+
+// messagesTool is the tool a structured reply is requested through: the model is made to call it, and
+// the input of the call is the reply.
+type messagesTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type messagesToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type messagesMessage struct {
+	Role    string                `json:"role"`
+	Content []messagesContentPart `json:"content"`
+}
+
+type messagesContentPart struct {
+	Type   string               `json:"type"`
+	Text   string               `json:"text,omitempty"`
+	Source *messagesImageSource `json:"source,omitempty"`
+}
+
+type messagesImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type messagesResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	Usage messagesUsage `json:"usage"`
+}
+
+type messagesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// messagesStreamEvent is an event of a streamed reply. message_start carries the input tokens,
+// content_block_delta the text or, for a tool call, the JSON of its input, and message_delta the output
+// tokens so far.
+type messagesStreamEvent struct {
+	messagesError
+	Type    string `json:"type"`
+	Message struct {
+		Usage messagesUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Usage messagesUsage `json:"usage"`
+}
+
+type messagesError struct {
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *anthropicProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	// images go first, Anthropic recommends placing them before the question about them
+	var content []messagesContentPart
+	for _, image := range req.Images {
+		content = append(content, messagesContentPart{
+			Type:   "image",
+			Source: &messagesImageSource{Type: "base64", MediaType: image.MediaType, Data: image.Data},
+		})
+	}
+	content = append(content, messagesContentPart{Type: "text", Text: req.Prompt})
+
+	messages := []messagesMessage{{Role: "user", Content: content}}
+	for _, turn := range req.Turns {
+		messages = append(messages, messagesMessage{Role: turn.Role, Content: []messagesContentPart{{Type: "text", Text: turn.Content}}})
+	}
+
+	body := messagesRequest{
+		Model:     p.cfg.Model,
+		System:    req.System,
+		Messages:  messages,
+		MaxTokens: maxTokens(req),
+	}
+	if req.Schema != nil {
+		body.Tools = []messagesTool{{Name: req.Schema.Name, Description: req.Schema.Description, InputSchema: req.Schema.Definition}}
+		body.ToolChoice = &messagesToolChoice{Type: "tool", Name: req.Schema.Name}
+	}
+	headers := map[string]string{
+		"x-api-key":         p.cfg.APIKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	url := baseURL(p.cfg.BaseURL, anthropicBaseURL) + "/v1/messages"
+	parseError := func(status int, body []byte) *APIError {
+		var errResp messagesError
+		if decode(body, &errResp) && errResp.Error != nil {
+			return &APIError{Provider: Anthropic, StatusCode: status, Type: errResp.Error.Type, Message: errResp.Error.Message}
+		}
+		return nil
+	}
+	if req.Stream != nil {
+		body.Stream = true
+		return p.stream(ctx, url, headers, body, parseError, req.Stream)
+	}
+
+	var resp messagesResponse
+	if err := postJSON(ctx, p.client, Anthropic, url, headers, body, &resp, parseError); err != nil {
+		return nil, err
+	}
+	return resp.toResponse()
+}
+
+func (p *anthropicProvider) stream(ctx context.Context, url string, headers map[string]string, body messagesRequest,
+	parseError func(status int, body []byte) *APIError, stream *Stream) (*Response, error) {
+	// a structured reply is the input of the tool call, so only that is streamed
+	deltaType := "text_delta"
+	if body.ToolChoice != nil {
+		deltaType = "input_json_delta"
+	}
+
+	result := &Response{}
+	var content strings.Builder
+	var whole messagesResponse
+	streamed, err := postStream(ctx, p.client, Anthropic, url, headers, body, &whole, parseError, func(data []byte) error {
+		var event messagesStreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return errors.New("failed to parse AI response")
+		}
+		switch event.Type {
+		case "error":
+			if event.Error != nil {
+				return &APIError{Provider: Anthropic, StatusCode: http.StatusOK, Type: event.Error.Type, Message: event.Error.Message}
+			}
+			return errors.New("AI stream failed")
+		case "message_start":
+			result.InputTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			result.OutputTokens = event.Usage.OutputTokens
+		case "content_block_delta":
+			text := event.Delta.Text + event.Delta.PartialJSON
+			if event.Delta.Type != deltaType || text == "" {
+				return nil
+			}
+			content.WriteString(text)
+			return stream.Delta(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !streamed {
+		resp, err := whole.toResponse()
+		if err != nil {
+			return nil, err
+		}
+		return streamWhole(stream, resp)
+	}
+	if content.Len() == 0 {
+		return nil, errors.New("no content received")
+	}
+	result.Content = content.String()
+	return result, nil
+}
+
+func (resp *messagesResponse) toResponse() (*Response, error) {
+	var text strings.Builder
+	for _, part := range resp.Content {
+		switch part.Type {
+		case "tool_use":
+			// the structured reply; any text next to it is commentary
+			return &Response{
+				Content:      string(part.Input),
+				InputTokens:  resp.Usage.InputTokens,
+				OutputTokens: resp.Usage.OutputTokens,
+			}, nil
+		case "text":
+			text.WriteString(part.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, errors.New("no content received")
+	}
+	return &Response{
+		Content:      text.String(),
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}, nil
+}
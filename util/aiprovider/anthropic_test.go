@@ -0,0 +1,197 @@
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAnthropicComplete(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"content": [{"type": "text", "text": "Hel"}, {"type": "text", "text": "lo"}],
+			"usage": {"input_tokens": 20, "output_tokens": 6}}`)
+	})
+	provider, _ := New(Config{Provider: Anthropic, BaseURL: server.URL, APIKey: "sk-ant", Model: "claude-test"})
+
+	resp, err := provider.Complete(context.Background(), Request{
+		System: "Be brief",
+		Prompt: "Say hello",
+		Images: []Image{testImage},
+		Turns:  []Turn{{Role: RoleAssistant, Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 20, OutputTokens: 6}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+
+	got := (*requests)[0]
+	if got.Path != "/v1/messages" {
+		t.Errorf("path = %s", got.Path)
+	}
+	if got.Header.Get("x-api-key") != "sk-ant" || got.Header.Get("anthropic-version") != anthropicVersion {
+		t.Errorf("headers = %v", got.Header)
+	}
+	if got.Body["model"] != "claude-test" || got.Body["system"] != "Be brief" || got.Body["max_tokens"] != float64(DefaultMaxTokens) {
+		t.Errorf("model, system and max_tokens = %v, %v, %v", got.Body["model"], got.Body["system"], got.Body["max_tokens"])
+	}
+	// the image goes before the question about it
+	wantMessages := jsonValue(t, `[
+		{"role": "user", "content": [
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "aW1hZ2U="}},
+			{"type": "text", "text": "Say hello"}
+		]},
+		{"role": "assistant", "content": [{"type": "text", "text": "Hi"}]}
+	]`)
+	if !reflect.DeepEqual(got.Body["messages"], wantMessages) {
+		t.Errorf("messages = %v", got.Body["messages"])
+	}
+	if _, ok := got.Body["tools"]; ok {
+		t.Error("a request without a schema has tools")
+	}
+}
+
+func TestAnthropicCompleteWithSchema(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"content": [
+			{"type": "text", "text": "Here are the tasks."},
+			{"type": "tool_use", "name": "task_list", "input": {"tasks": [{"title": "Buy milk"}]}}
+		], "usage": {"input_tokens": 30, "output_tokens": 8}}`)
+	})
+	provider, _ := New(Config{Provider: Anthropic, BaseURL: server.URL, APIKey: "sk-ant", Model: "claude-test"})
+
+	resp, err := provider.Complete(context.Background(), Request{Prompt: "tasks", Schema: testTaskSchema, MaxTokens: 500})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Content != `{"tasks": [{"title": "Buy milk"}]}` || resp.InputTokens != 30 || resp.OutputTokens != 8 {
+		t.Errorf("Complete() = %+v, want the input of the tool call", resp)
+	}
+
+	body := (*requests)[0].Body
+	tools, _ := body["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("tools = %v", body["tools"])
+	}
+	tool := tools[0].(map[string]any)
+	if tool["name"] != "task_list" || tool["input_schema"] == nil {
+		t.Errorf("tool = %v", tool)
+	}
+	if !reflect.DeepEqual(body["tool_choice"], jsonValue(t, `{"type": "tool", "name": "task_list"}`)) {
+		t.Errorf("tool_choice = %v", body["tool_choice"])
+	}
+	if body["max_tokens"] != 500.0 {
+		t.Errorf("max_tokens = %v", body["max_tokens"])
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   APIError
+	}{
+		{"error object", http.StatusTooManyRequests, `{"type": "error", "error": {"type": "rate_limit_error", "message": "Slow down"}}`,
+			APIError{Provider: Anthropic, StatusCode: http.StatusTooManyRequests, Type: "rate_limit_error", Message: "Slow down"}},
+		{"unknown format", http.StatusInternalServerError, `oops`,
+			APIError{Provider: Anthropic, StatusCode: http.StatusInternalServerError, Message: "oops"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+				writeJSON(w, tt.status, tt.body)
+			})
+			provider, _ := New(Config{Provider: Anthropic, BaseURL: server.URL, Model: "m"})
+			_, err := provider.Complete(context.Background(), Request{Prompt: "p"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Complete() error = %v, want an *APIError", err)
+			}
+			if *apiErr != tt.want {
+				t.Errorf("Complete() error = %+v, want %+v", *apiErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *Schema
+		want   []string
+	}{
+		{"text", nil, []string{"Hel", "lo"}},
+		{"tool input", testTaskSchema, []string{`{"tasks": `, `[]}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+				writeEvents(w, "text/event-stream",
+					`{"type": "message_start", "message": {"usage": {"input_tokens": 15, "output_tokens": 1}}}`,
+					`{"type": "content_block_start", "index": 0}`,
+					`{"type": "content_block_delta", "delta": {"type": "text_delta", "text": "Hel"}}`,
+					`{"type": "content_block_delta", "delta": {"type": "input_json_delta", "partial_json": "{\"tasks\": "}}`,
+					`{"type": "ping"}`,
+					`{"type": "content_block_delta", "delta": {"type": "text_delta", "text": "lo"}}`,
+					`{"type": "content_block_delta", "delta": {"type": "input_json_delta", "partial_json": "[]}"}}`,
+					`{"type": "message_delta", "usage": {"output_tokens": 7}}`,
+					`{"type": "message_stop"}`)
+			})
+			provider, _ := New(Config{Provider: Anthropic, BaseURL: server.URL, Model: "m"})
+
+			var deltas []string
+			resp, err := provider.Complete(context.Background(), Request{Prompt: "p", Schema: tt.schema, Stream: collectStream(&deltas)})
+			if err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+			if !reflect.DeepEqual(deltas, tt.want) {
+				t.Errorf("deltas = %q, want %q", deltas, tt.want)
+			}
+			if resp.Content != tt.want[0]+tt.want[1] || resp.InputTokens != 15 || resp.OutputTokens != 7 {
+				t.Errorf("Complete() = %+v", resp)
+			}
+			if (*requests)[0].Body["stream"] != true {
+				t.Error("stream is not set")
+			}
+		})
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeEvents(w, "text/event-stream",
+			`{"type": "message_start", "message": {"usage": {"input_tokens": 15}}}`,
+			`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`)
+	})
+	provider, _ := New(Config{Provider: Anthropic, BaseURL: server.URL, Model: "m"})
+
+	var deltas []string
+	_, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != "overloaded_error" || apiErr.Message != "Overloaded" {
+		t.Fatalf("Complete() error = %v, want the error event", err)
+	}
+}
+
+func TestAnthropicStreamNotStreamed(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"content": [{"type": "text", "text": "Hello"}], "usage": {"input_tokens": 4, "output_tokens": 2}}`)
+	})
+	provider, _ := New(Config{Provider: Anthropic, BaseURL: server.URL, Model: "m"})
+
+	var deltas []string
+	resp, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 4, OutputTokens: 2}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+	if !reflect.DeepEqual(deltas, []string{"Hello"}) {
+		t.Errorf("deltas = %q, want the whole reply once", deltas)
+	}
+}
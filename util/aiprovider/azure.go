@@ -0,0 +1,29 @@
+package aiprovider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when the settings do not name one.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// azureProvider calls a chat completions deployment of an Azure OpenAI resource. The model of the
+// settings is the deployment name.
+type azureProvider struct {
+	cfg    Config
+	client *http.Client
+}
+
+func (p *azureProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	apiVersion := p.cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+	endpoint := baseURL(p.cfg.BaseURL, "") + "/openai/deployments/" + url.PathEscape(p.cfg.Model) +
+		"/chat/completions?api-version=" + url.QueryEscape(apiVersion)
+	headers := map[string]string{"api-key": p.cfg.APIKey}
+	// the deployment decides the model, the one in the body is ignored
+	return completeChat(ctx, p.client, Azure, endpoint, headers, p.cfg.Model, req)
+}
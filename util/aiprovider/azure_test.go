@@ -0,0 +1,84 @@
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAzureComplete(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiVersion  string
+		wantVersion string
+	}{
+		{"default API version", "", DefaultAzureAPIVersion},
+		{"configured API version", "2025-01-01-preview", "2025-01-01-preview"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+				query = r.URL.Query().Get("api-version")
+				writeJSON(w, http.StatusOK, `{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 8, "completion_tokens": 2}}`)
+			})
+			provider, err := New(Config{Provider: Azure, BaseURL: server.URL + "/", APIKey: "azure-key", Model: "my deployment",
+				APIVersion: tt.apiVersion})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			resp, err := provider.Complete(context.Background(), Request{Prompt: "Say hello", Schema: testTaskSchema})
+			if err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+			if *resp != (Response{Content: "Hello", InputTokens: 8, OutputTokens: 2}) {
+				t.Errorf("Complete() = %+v", resp)
+			}
+
+			got := (*requests)[0]
+			if got.Path != "/openai/deployments/my deployment/chat/completions" {
+				t.Errorf("path = %s", got.Path)
+			}
+			if query != tt.wantVersion {
+				t.Errorf("api-version = %q, want %q", query, tt.wantVersion)
+			}
+			if got.Header.Get("api-key") != "azure-key" || got.Header.Get("Authorization") != "" {
+				t.Errorf("headers = %v, want only api-key", got.Header)
+			}
+			if _, ok := got.Body["response_format"]; !ok {
+				t.Error("response_format is not set")
+			}
+		})
+	}
+}
+
+func TestAzureError(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusNotFound, `{"error": {"code": "DeploymentNotFound", "message": "The API deployment for this resource does not exist."}}`)
+	})
+	provider, _ := New(Config{Provider: Azure, BaseURL: server.URL, Model: "missing"})
+
+	_, err := provider.Complete(context.Background(), Request{Prompt: "p"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Complete() error = %v, want an *APIError", err)
+	}
+	want := APIError{Provider: Azure, StatusCode: http.StatusNotFound, Message: "The API deployment for this resource does not exist."}
+	if *apiErr != want {
+		t.Errorf("Complete() error = %+v, want %+v", *apiErr, want)
+	}
+}
+
+func TestAzureNeedsBaseURL(t *testing.T) {
+	if _, err := New(Config{Provider: Azure, Model: "deployment"}); err == nil {
+		t.Fatal("New() succeeded without a base URL")
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := New(Config{Provider: "gemini"}); err == nil {
+		t.Fatal("New() succeeded with an unknown provider")
+	}
+}
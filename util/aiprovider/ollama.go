@@ -0,0 +1,139 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const ollamaBaseURL = "http://localhost:11434"
+
+// ollamaProvider calls the native chat API of an Ollama server, which needs no API key.
+type ollamaProvider struct {
+	cfg    Config
+	client *http.Client
+}
+
+type ollamaChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []ollamaChatMessage `json:"messages"`
+	Stream   bool                `json:"stream"`
+	// Format is a JSON Schema the reply must match.
+	Format  map[string]any `json:"format,omitempty"`
+	Options map[string]any `json:"options,omitempty"`
+}
+
+type ollamaChatMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// ollamaStreamChunk is a line of a streamed reply; the last one, with done set, has the token counts.
+type ollamaStreamChunk struct {
+	ollamaChatResponse
+	ollamaError
+	Done bool `json:"done"`
+}
+
+type ollamaError struct {
+	Error string `json:"error"`
+}
+
+func (p *ollamaProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	user := ollamaChatMessage{Role: "user", Content: req.Prompt}
+	for _, image := range req.Images {
+		user.Images = append(user.Images, image.Data)
+	}
+	var messages []ollamaChatMessage
+	if req.System != "" {
+		messages = append(messages, ollamaChatMessage{Role: "system", Content: req.System})
+	}
+	messages = append(messages, user)
+	for _, turn := range req.Turns {
+		messages = append(messages, ollamaChatMessage{Role: turn.Role, Content: turn.Content})
+	}
+
+	body := ollamaChatRequest{Model: p.cfg.Model, Messages: messages}
+	if req.Schema != nil {
+		body.Format = req.Schema.Definition
+	}
+	if req.MaxTokens > 0 {
+		body.Options = map[string]any{"num_predict": req.MaxTokens}
+	}
+	headers := map[string]string{}
+	if p.cfg.APIKey != "" {
+		// for servers behind an authenticating proxy
+		headers["Authorization"] = "Bearer " + p.cfg.APIKey
+	}
+
+	url := baseURL(p.cfg.BaseURL, ollamaBaseURL) + "/api/chat"
+	parseError := func(status int, body []byte) *APIError {
+		var errResp ollamaError
+		if decode(body, &errResp) && errResp.Error != "" {
+			return &APIError{Provider: Ollama, StatusCode: status, Message: errResp.Error}
+		}
+		return nil
+	}
+	if req.Stream != nil {
+		body.Stream = true
+		return p.stream(ctx, url, headers, body, parseError, req.Stream)
+	}
+
+	var resp ollamaChatResponse
+	if err := postJSON(ctx, p.client, Ollama, url, headers, body, &resp, parseError); err != nil {
+		return nil, err
+	}
+	return resp.toResponse(), nil
+}
+
+func (p *ollamaProvider) stream(ctx context.Context, url string, headers map[string]string, body ollamaChatRequest,
+	parseError func(status int, body []byte) *APIError, stream *Stream) (*Response, error) {
+	result := &Response{}
+	var content strings.Builder
+	var whole ollamaChatResponse
+	streamed, err := postStream(ctx, p.client, Ollama, url, headers, body, &whole, parseError, func(data []byte) error {
+		var chunk ollamaStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return errors.New("failed to parse AI response")
+		}
+		if chunk.Error != "" {
+			return &APIError{Provider: Ollama, StatusCode: http.StatusOK, Message: chunk.Error}
+		}
+		if chunk.Done {
+			result.InputTokens = chunk.PromptEvalCount
+			result.OutputTokens = chunk.EvalCount
+		}
+		if chunk.Message.Content == "" {
+			return nil
+		}
+		content.WriteString(chunk.Message.Content)
+		return stream.Delta(chunk.Message.Content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !streamed {
+		return streamWhole(stream, whole.toResponse())
+	}
+	result.Content = content.String()
+	return result, nil
+}
+
+func (resp *ollamaChatResponse) toResponse() *Response {
+	return &Response{
+		Content:      resp.Message.Content,
+		InputTokens:  resp.PromptEvalCount,
+		OutputTokens: resp.EvalCount,
+	}
+}
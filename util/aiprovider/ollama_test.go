@@ -0,0 +1,165 @@
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestOllamaComplete(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"message": {"role": "assistant", "content": "Hello"}, "done": true,
+			"prompt_eval_count": 25, "eval_count": 5}`)
+	})
+	provider, _ := New(Config{Provider: Ollama, BaseURL: server.URL + "/", Model: "llava"})
+
+	resp, err := provider.Complete(context.Background(), Request{
+		System:    "Be brief",
+		Prompt:    "Say hello",
+		Images:    []Image{testImage},
+		Turns:     []Turn{{Role: RoleAssistant, Content: "Hi"}},
+		Schema:    testTaskSchema,
+		MaxTokens: 200,
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 25, OutputTokens: 5}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+
+	got := (*requests)[0]
+	if got.Path != "/api/chat" {
+		t.Errorf("path = %s", got.Path)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "" {
+		t.Errorf("Authorization = %q without an API key", auth)
+	}
+	if got.Body["model"] != "llava" || got.Body["stream"] != false {
+		t.Errorf("model and stream = %v, %v", got.Body["model"], got.Body["stream"])
+	}
+	wantMessages := jsonValue(t, `[
+		{"role": "system", "content": "Be brief"},
+		{"role": "user", "content": "Say hello", "images": ["aW1hZ2U="]},
+		{"role": "assistant", "content": "Hi"}
+	]`)
+	if !reflect.DeepEqual(got.Body["messages"], wantMessages) {
+		t.Errorf("messages = %v", got.Body["messages"])
+	}
+	if format, _ := got.Body["format"].(map[string]any); format["type"] != "object" {
+		t.Errorf("format = %v, want the schema", got.Body["format"])
+	}
+	if !reflect.DeepEqual(got.Body["options"], jsonValue(t, `{"num_predict": 200}`)) {
+		t.Errorf("options = %v", got.Body["options"])
+	}
+}
+
+func TestOllamaAPIKey(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"message": {"content": "ok"}}`)
+	})
+	provider, _ := New(Config{Provider: Ollama, BaseURL: server.URL, APIKey: "proxy-key", Model: "m"})
+
+	if _, err := provider.Complete(context.Background(), Request{Prompt: "p"}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	got := (*requests)[0]
+	if auth := got.Header.Get("Authorization"); auth != "Bearer proxy-key" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	for _, name := range []string{"format", "options"} {
+		if _, ok := got.Body[name]; ok {
+			t.Errorf("%s is set without a schema or token limit", name)
+		}
+	}
+}
+
+func TestOllamaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   APIError
+	}{
+		{"error message", http.StatusNotFound, `{"error": "model \"llava\" not found, try pulling it first"}`,
+			APIError{Provider: Ollama, StatusCode: http.StatusNotFound, Message: `model "llava" not found, try pulling it first`}},
+		{"unknown format", http.StatusBadGateway, `bad gateway`,
+			APIError{Provider: Ollama, StatusCode: http.StatusBadGateway, Message: "bad gateway"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+				writeJSON(w, tt.status, tt.body)
+			})
+			provider, _ := New(Config{Provider: Ollama, BaseURL: server.URL, Model: "m"})
+			_, err := provider.Complete(context.Background(), Request{Prompt: "p"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Complete() error = %v, want an *APIError", err)
+			}
+			if *apiErr != tt.want {
+				t.Errorf("Complete() error = %+v, want %+v", *apiErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeEvents(w, "application/x-ndjson",
+			`{"message": {"content": "Hel"}, "done": false}`,
+			`{"message": {"content": "lo"}, "done": false}`,
+			`{"message": {"content": ""}, "done": true, "prompt_eval_count": 11, "eval_count": 3}`)
+	})
+	provider, _ := New(Config{Provider: Ollama, BaseURL: server.URL, Model: "m"})
+
+	var deltas []string
+	resp, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 11, OutputTokens: 3}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+	if !reflect.DeepEqual(deltas, []string{"Hel", "lo"}) {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if (*requests)[0].Body["stream"] != true {
+		t.Error("stream is not set")
+	}
+}
+
+func TestOllamaStreamError(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeEvents(w, "application/x-ndjson", `{"message": {"content": "Hel"}}`, `{"error": "out of memory"}`)
+	})
+	provider, _ := New(Config{Provider: Ollama, BaseURL: server.URL, Model: "m"})
+
+	var deltas []string
+	_, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "out of memory" {
+		t.Fatalf("Complete() error = %v, want the error line", err)
+	}
+}
+
+func TestOllamaStreamNotStreamed(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"message": {"content": "Hello"}, "done": true, "prompt_eval_count": 6, "eval_count": 2}`)
+	})
+	provider, _ := New(Config{Provider: Ollama, BaseURL: server.URL, Model: "m"})
+
+	var deltas []string
+	resp, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 6, OutputTokens: 2}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+	if !reflect.DeepEqual(deltas, []string{"Hello"}) {
+		t.Errorf("deltas = %q, want the whole reply once", deltas)
+	}
+}
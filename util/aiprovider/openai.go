@@ -0,0 +1,229 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const openAIBaseURL = "https://api.openai.com/v1"
+
+// openAIProvider speaks the chat completions API of OpenAI and of the many servers compatible with it.
+type openAIProvider struct {
+	cfg    Config
+	client *http.Client
+}
+
+type chatRequest struct {
+	Model          string              `json:"model"`
+	Messages       []chatMessage       `json:"messages"`
+	MaxTokens      int                 `json:"max_tokens,omitempty"`
+	ResponseFormat *chatResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                `json:"stream,omitempty"`
+	StreamOptions  *chatStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type chatStreamOptions struct {
+	// IncludeUsage adds a last chunk with the tokens used.
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *chatJSONSchema `json:"json_schema,omitempty"`
+}
+
+type chatJSONSchema struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema"`
+	// Strict is off: strict mode requires every property, and the schemas have optional ones.
+	Strict bool `json:"strict"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type chatContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageUrl *chatImageUrl `json:"image_url,omitempty"`
+}
+
+type chatImageUrl struct {
+	Url string `json:"url"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage chatUsage `json:"usage"`
+}
+
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// chatStreamChunk is an event of a streamed reply; errors after the reply started come as events too.
+type chatStreamChunk struct {
+	chatError
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+}
+
+type chatError struct {
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error"`
+}
+
+func (p *openAIProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	base := baseURL(p.cfg.BaseURL, openAIBaseURL)
+	// settings saved before the provider was chosen may hold the bare host or the full endpoint
+	base = strings.TrimSuffix(base, "/chat/completions")
+	if strings.Contains(base, "api.openai.com") && !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	headers := map[string]string{"Authorization": "Bearer " + p.cfg.APIKey}
+	return completeChat(ctx, p.client, OpenAI, base+"/chat/completions", headers, p.cfg.Model, req)
+}
+
+// completeChat sends a chat completions request, shared by OpenAI and Azure.
+func completeChat(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, model string,
+	req Request) (*Response, error) {
+	content := []chatContentPart{{Type: "text", Text: req.Prompt}}
+	for _, image := range req.Images {
+		content = append(content, chatContentPart{
+			Type:     "image_url",
+			ImageUrl: &chatImageUrl{Url: "data:" + image.MediaType + ";base64," + image.Data},
+		})
+	}
+	var messages []chatMessage
+	if req.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.System})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: content})
+	for _, turn := range req.Turns {
+		messages = append(messages, chatMessage{Role: turn.Role, Content: turn.Content})
+	}
+
+	body := chatRequest{Model: model, Messages: messages, MaxTokens: req.MaxTokens}
+	if req.Schema != nil {
+		body.ResponseFormat = &chatResponseFormat{Type: "json_schema", JSONSchema: &chatJSONSchema{
+			Name:        req.Schema.Name,
+			Description: req.Schema.Description,
+			Schema:      req.Schema.Definition,
+		}}
+	}
+
+	parseError := func(status int, body []byte) *APIError {
+		var errResp chatError
+		if decode(body, &errResp) && errResp.Error != nil {
+			return &APIError{Provider: provider, StatusCode: status, Type: errResp.Error.Type, Message: errResp.Error.Message}
+		}
+		return nil
+	}
+	resp, err := sendChat(ctx, client, provider, url, headers, body, parseError, req.Stream)
+	if body.ResponseFormat != nil && rejectsResponseFormat(err) {
+		// many OpenAI-compatible servers lack structured output; CompleteJSON validates the reply anyway
+		body.ResponseFormat = nil
+		resp, err = sendChat(ctx, client, provider, url, headers, body, parseError, req.Stream)
+	}
+	return resp, err
+}
+
+func sendChat(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body chatRequest,
+	parseError func(status int, body []byte) *APIError, stream *Stream) (*Response, error) {
+	if stream != nil {
+		body.Stream = true
+		body.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+		return streamChat(ctx, client, provider, url, headers, body, parseError, stream)
+	}
+
+	var resp chatResponse
+	if err := postJSON(ctx, client, provider, url, headers, body, &resp, parseError); err != nil {
+		return nil, err
+	}
+	return resp.toResponse()
+}
+
+// rejectsResponseFormat reports whether err is a server refusing the response_format of a request. The
+// request was refused before any of the reply was streamed, so it can be sent again.
+func rejectsResponseFormat(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "response_format") || strings.Contains(message, "json_schema")
+}
+
+func streamChat(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body chatRequest,
+	parseError func(status int, body []byte) *APIError, stream *Stream) (*Response, error) {
+	result := &Response{}
+	var content strings.Builder
+	var whole chatResponse
+	streamed, err := postStream(ctx, client, provider, url, headers, body, &whole, parseError, func(data []byte) error {
+		var chunk chatStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return errors.New("failed to parse AI response")
+		}
+		if chunk.Error != nil {
+			return &APIError{Provider: provider, StatusCode: http.StatusOK, Type: chunk.Error.Type, Message: chunk.Error.Message}
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := stream.Delta(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+		if chunk.Usage != nil {
+			result.InputTokens = chunk.Usage.PromptTokens
+			result.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !streamed {
+		resp, err := whole.toResponse()
+		if err != nil {
+			return nil, err
+		}
+		return streamWhole(stream, resp)
+	}
+	if content.Len() == 0 {
+		return nil, errors.New("no content received")
+	}
+	result.Content = content.String()
+	return result, nil
+}
+
+func (resp *chatResponse) toResponse() (*Response, error) {
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("no content received")
+	}
+	return &Response{
+		Content:      resp.Choices[0].Message.Content,
+		InputTokens:  resp.Usage.PromptTokens,
+		OutputTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
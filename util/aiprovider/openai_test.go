@@ -0,0 +1,310 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// recordedRequest is a request a test server received.
+type recordedRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]any
+}
+
+// newTestServer answers the requests it receives with handler and records them.
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, body map[string]any)) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("request body is not JSON: %s", raw)
+		}
+		requests = append(requests, recordedRequest{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+		handler(w, r, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+// writeEvents answers with a streamed reply of the media type, one line per event.
+func writeEvents(w http.ResponseWriter, mediaType string, events ...string) {
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	for _, event := range events {
+		if mediaType == "text/event-stream" {
+			event = "data: " + event + "\n"
+		}
+		_, _ = io.WriteString(w, event+"\n")
+	}
+}
+
+// collectStream returns a Stream that appends the deltas it receives to deltas.
+func collectStream(deltas *[]string) *Stream {
+	return &Stream{Delta: func(text string) error {
+		*deltas = append(*deltas, text)
+		return nil
+	}}
+}
+
+// jsonValue decodes a JSON literal, to compare it with a decoded request body.
+func jsonValue(t *testing.T, literal string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(literal), &value); err != nil {
+		t.Fatalf("bad JSON literal %s: %v", literal, err)
+	}
+	return value
+}
+
+var testImage = Image{MediaType: "image/png", Data: "aW1hZ2U="}
+
+func TestOpenAIComplete(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 12, "completion_tokens": 4}}`)
+	})
+	provider, _ := New(Config{Provider: OpenAI, BaseURL: server.URL + "/v1/", APIKey: "sk-test", Model: "gpt-test"})
+
+	resp, err := provider.Complete(context.Background(), Request{
+		System:    "Be brief",
+		Prompt:    "Say hello",
+		Images:    []Image{testImage},
+		Turns:     []Turn{{Role: RoleAssistant, Content: "Hi"}, {Role: RoleUser, Content: "Again"}},
+		Schema:    testTaskSchema,
+		MaxTokens: 100,
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 12, OutputTokens: 4}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+
+	got := (*requests)[0]
+	if got.Path != "/v1/chat/completions" {
+		t.Errorf("path = %s", got.Path)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if got.Body["model"] != "gpt-test" || got.Body["max_tokens"] != 100.0 {
+		t.Errorf("model and max_tokens = %v, %v", got.Body["model"], got.Body["max_tokens"])
+	}
+	wantMessages := jsonValue(t, `[
+		{"role": "system", "content": "Be brief"},
+		{"role": "user", "content": [
+			{"type": "text", "text": "Say hello"},
+			{"type": "image_url", "image_url": {"url": "data:image/png;base64,aW1hZ2U="}}
+		]},
+		{"role": "assistant", "content": "Hi"},
+		{"role": "user", "content": "Again"}
+	]`)
+	if !reflect.DeepEqual(got.Body["messages"], wantMessages) {
+		t.Errorf("messages = %v", got.Body["messages"])
+	}
+	format, _ := got.Body["response_format"].(map[string]any)
+	schema, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || schema["name"] != "task_list" || schema["schema"] == nil {
+		t.Errorf("response_format = %v", got.Body["response_format"])
+	}
+	if _, ok := got.Body["stream"]; ok {
+		t.Error("a request that is not streamed has stream set")
+	}
+}
+
+func TestOpenAIBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+	}{
+		{"versioned", "/v1"},
+		{"full endpoint", "/v1/chat/completions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+				writeJSON(w, http.StatusOK, `{"choices": [{"message": {"content": "ok"}}]}`)
+			})
+			provider, _ := New(Config{BaseURL: server.URL + tt.baseURL, Model: "m"})
+			if _, err := provider.Complete(context.Background(), Request{Prompt: "p"}); err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+			if path := (*requests)[0].Path; path != "/v1/chat/completions" {
+				t.Errorf("path = %s", path)
+			}
+		})
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   APIError
+	}{
+		{"error object", http.StatusUnauthorized, `{"error": {"message": "Incorrect API key", "type": "invalid_request_error", "code": "invalid_api_key"}}`,
+			APIError{Provider: OpenAI, StatusCode: http.StatusUnauthorized, Type: "invalid_request_error", Message: "Incorrect API key"}},
+		{"unknown format", http.StatusBadGateway, `upstream unavailable`,
+			APIError{Provider: OpenAI, StatusCode: http.StatusBadGateway, Message: "upstream unavailable"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+				writeJSON(w, tt.status, tt.body)
+			})
+			provider, _ := New(Config{BaseURL: server.URL + "/v1", Model: "m"})
+			_, err := provider.Complete(context.Background(), Request{Prompt: "p"})
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Complete() error = %v, want an *APIError", err)
+			}
+			if *apiErr != tt.want {
+				t.Errorf("Complete() error = %+v, want %+v", *apiErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAINoChoices(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"choices": []}`)
+	})
+	provider, _ := New(Config{BaseURL: server.URL + "/v1", Model: "m"})
+	if _, err := provider.Complete(context.Background(), Request{Prompt: "p"}); err == nil {
+		t.Fatal("Complete() succeeded without choices")
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeEvents(w, "text/event-stream",
+			`{"choices": [{"delta": {"role": "assistant"}}]}`,
+			`{"choices": [{"delta": {"content": "Hel"}}]}`,
+			`{"choices": [{"delta": {"content": "lo"}}]}`,
+			`{"choices": [], "usage": {"prompt_tokens": 9, "completion_tokens": 2}}`,
+			`[DONE]`)
+	})
+	provider, _ := New(Config{BaseURL: server.URL + "/v1", Model: "m"})
+
+	var deltas []string
+	resp, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 9, OutputTokens: 2}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+	if !reflect.DeepEqual(deltas, []string{"Hel", "lo"}) {
+		t.Errorf("deltas = %q", deltas)
+	}
+	body := (*requests)[0].Body
+	if body["stream"] != true || !reflect.DeepEqual(body["stream_options"], jsonValue(t, `{"include_usage": true}`)) {
+		t.Errorf("stream and stream_options = %v, %v", body["stream"], body["stream_options"])
+	}
+}
+
+func TestOpenAIStreamError(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeEvents(w, "text/event-stream",
+			`{"choices": [{"delta": {"content": "Hel"}}]}`,
+			`{"error": {"message": "overloaded", "type": "server_error"}}`)
+	})
+	provider, _ := New(Config{BaseURL: server.URL + "/v1", Model: "m"})
+
+	var deltas []string
+	_, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != "server_error" || apiErr.Message != "overloaded" {
+		t.Fatalf("Complete() error = %v, want the error event", err)
+	}
+}
+
+func TestOpenAIStreamNotStreamed(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusOK, `{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 3, "completion_tokens": 1}}`)
+	})
+	provider, _ := New(Config{BaseURL: server.URL + "/v1", Model: "m"})
+
+	var deltas []string
+	resp, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: collectStream(&deltas)})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if *resp != (Response{Content: "Hello", InputTokens: 3, OutputTokens: 1}) {
+		t.Errorf("Complete() = %+v", resp)
+	}
+	if !reflect.DeepEqual(deltas, []string{"Hello"}) {
+		t.Errorf("deltas = %q, want the whole reply once", deltas)
+	}
+}
+
+func TestOpenAIStreamDeltaError(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeEvents(w, "text/event-stream", `{"choices": [{"delta": {"content": "Hel"}}]}`, `{"choices": [{"delta": {"content": "lo"}}]}`)
+	})
+	provider, _ := New(Config{BaseURL: server.URL + "/v1", Model: "m"})
+
+	stop := errors.New("client left")
+	calls := 0
+	_, err := provider.Complete(context.Background(), Request{Prompt: "p", Stream: &Stream{Delta: func(string) error {
+		calls++
+		return stop
+	}}})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Complete() error = %v after %d deltas, want the Delta error after 1", err, calls)
+	}
+}
+
+func TestOpenAIRetriesWithoutResponseFormat(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		if _, ok := body["response_format"]; ok {
+			writeJSON(w, http.StatusBadRequest, `{"error": {"message": "response_format json_schema is not supported", "type": "invalid_request_error"}}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"choices": [{"message": {"content": "{\"tasks\": []}"}}], "usage": {"prompt_tokens": 7, "completion_tokens": 3}}`)
+	})
+	provider, _ := New(Config{Provider: OpenAI, BaseURL: server.URL + "/v1", APIKey: "key", Model: "local"})
+
+	resp, err := provider.Complete(context.Background(), Request{Prompt: "tasks", Schema: testTaskSchema})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Content != `{"tasks": []}` || resp.InputTokens != 7 || resp.OutputTokens != 3 {
+		t.Errorf("Complete() = %+v", resp)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("server received %d requests, want 2", len(*requests))
+	}
+	if _, ok := (*requests)[1].Body["response_format"]; ok {
+		t.Error("the retry still has a response_format")
+	}
+}
+
+func TestOpenAIDoesNotRetryOtherErrors(t *testing.T) {
+	server, requests := newTestServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]any) {
+		writeJSON(w, http.StatusBadRequest, `{"error": {"message": "max_tokens is too large", "type": "invalid_request_error"}}`)
+	})
+	provider, _ := New(Config{Provider: OpenAI, BaseURL: server.URL + "/v1", APIKey: "key", Model: "local"})
+
+	if _, err := provider.Complete(context.Background(), Request{Prompt: "tasks", Schema: testTaskSchema}); err == nil {
+		t.Fatal("Complete() succeeded")
+	}
+	if len(*requests) != 1 {
+		t.Errorf("server received %d requests, want 1", len(*requests))
+	}
+}
@@ -0,0 +1,216 @@
+package aiprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Schema describes the JSON object a reply must be. Definition is a JSON Schema whose top level is an
+// object; Validate understands the keywords type, properties, required, additionalProperties, items,
+// minItems, maxItems, enum, minLength, maxLength and pattern.
+type Schema struct {
+	// Name identifies the schema to the provider: letters, digits, _ and -.
+	Name        string
+	Description string
+	Definition  map[string]any
+}
+
+// ValidationError tells where a reply does not match its schema.
+type ValidationError struct {
+	// Path locates the value, e.g. tasks[0].title; empty for the whole reply.
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return "reply: " + e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Validate reports whether data is JSON matching the schema. The error is a *ValidationError unless the
+// schema itself is broken.
+func (s *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Message: "not valid JSON: " + err.Error()}
+	}
+	if decoder.More() {
+		return &ValidationError{Message: "not valid JSON: unexpected data after the value"}
+	}
+	return validateValue("", value, s.Definition)
+}
+
+func validateValue(path string, value any, schema map[string]any) error {
+	if types, ok := schema["type"]; ok && !matchesType(value, types) {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("must be of type %v", types)}
+	}
+	if enum, ok := schema["enum"]; ok && !slices.ContainsFunc(anyList(enum), func(e any) bool { return equalJSON(e, value) }) {
+		values, _ := json.Marshal(enum)
+		return &ValidationError{Path: path, Message: "must be one of " + string(values)}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("property %q is required", name)}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		// sorted so that the same reply always reports the same error
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			propertySchema, known := properties[name].(map[string]any)
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return &ValidationError{Path: path, Message: fmt.Sprintf("property %q is not allowed", name)}
+				}
+				continue
+			}
+			if err := validateValue(joinPath(path, name), v[name], propertySchema); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if limit, ok := number(schema["minItems"]); ok && float64(len(v)) < limit {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must have at least %v items", limit)}
+		}
+		if limit, ok := number(schema["maxItems"]); ok && float64(len(v)) > limit {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must have at most %v items", limit)}
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateValue(path+"["+strconv.Itoa(i)+"]", item, items); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if limit, ok := number(schema["minLength"]); ok && length < limit {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be at least %v characters long", limit)}
+		}
+		if limit, ok := number(schema["maxLength"]); ok && length > limit {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be at most %v characters long", limit)}
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("schema pattern %q: %w", pattern, err)
+			}
+			if !re.MatchString(v) {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("must match %s", pattern)}
+			}
+		}
+	}
+	return nil
+}
+
+func matchesType(value any, types any) bool {
+	names := stringList(types)
+	if name, ok := types.(string); ok {
+		names = []string{name}
+	}
+	for _, name := range names {
+		switch v := value.(type) {
+		case map[string]any:
+			if name == "object" {
+				return true
+			}
+		case []any:
+			if name == "array" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case json.Number:
+			if name == "number" {
+				return true
+			}
+			if _, err := v.Int64(); err == nil && name == "integer" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringList reads a list of strings from a schema, whether it was written in Go ([]string) or
+// decoded from JSON ([]any).
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// anyList reads a list from a schema, whether it was written in Go ([]string) or decoded from JSON ([]any).
+func anyList(value any) []any {
+	if list, ok := value.([]string); ok {
+		items := make([]any, len(list))
+		for i, item := range list {
+			items[i] = item
+		}
+		return items
+	}
+	items, _ := value.([]any)
+	return items
+}
+
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func equalJSON(a, b any) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
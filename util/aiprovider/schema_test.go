@@ -0,0 +1,93 @@
+package aiprovider
+
+import (
+	"errors"
+	"testing"
+)
+
+var testTaskSchema = &Schema{
+	Name: "task_list",
+	Definition: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"tasks": map[string]any{
+				"type":     "array",
+				"minItems": 1,
+				"maxItems": 2,
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"title":    map[string]any{"type": "string", "minLength": 5, "maxLength": 10},
+						"priority": map[string]any{"type": "string", "enum": []string{"", "low", "high"}},
+						"dueDate":  map[string]any{"type": "string", "pattern": `^(\d{4}-\d{2}-\d{2})?$`},
+						"points":   map[string]any{"type": "integer"},
+						"done":     map[string]any{"type": []string{"boolean", "null"}},
+					},
+					"required":             []string{"title"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required": []string{"tasks"},
+	},
+}
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// path of the expected *ValidationError; "-" when the data is valid
+		path string
+	}{
+		{"valid", `{"tasks": [{"title": "Buy milk"}]}`, "-"},
+		{"valid with every property", `{"tasks": [{"title": "Buy milk", "priority": "low", "dueDate": "2025-01-31", "points": 3, "done": null}]}`, "-"},
+		{"unknown top level property", `{"tasks": [{"title": "Buy milk"}], "note": "x"}`, "-"},
+		{"empty enum value", `{"tasks": [{"title": "Buy milk", "priority": ""}]}`, "-"},
+		{"rune length", `{"tasks": [{"title": "Café ☕"}]}`, "-"},
+		{"not JSON", `tasks: buy milk`, ""},
+		{"trailing data", `{"tasks": [{"title": "Buy milk"}]} {}`, ""},
+		{"not an object", `["Buy milk"]`, ""},
+		{"missing required", `{}`, ""},
+		{"wrong type", `{"tasks": {"title": "Buy milk"}}`, "tasks"},
+		{"too few items", `{"tasks": []}`, "tasks"},
+		{"too many items", `{"tasks": [{"title": "Buy milk"}, {"title": "Buy eggs"}, {"title": "Buy bread"}]}`, "tasks"},
+		{"item missing required", `{"tasks": [{"title": "Buy milk"}, {"priority": "low"}]}`, "tasks[1]"},
+		{"additional property", `{"tasks": [{"title": "Buy milk", "owner": "me"}]}`, "tasks[0]"},
+		{"too short", `{"tasks": [{"title": "Milk"}]}`, "tasks[0].title"},
+		{"too long", `{"tasks": [{"title": "Buy milk and eggs"}]}`, "tasks[0].title"},
+		{"not in enum", `{"tasks": [{"title": "Buy milk", "priority": "urgent"}]}`, "tasks[0].priority"},
+		{"pattern mismatch", `{"tasks": [{"title": "Buy milk", "dueDate": "tomorrow"}]}`, "tasks[0].dueDate"},
+		{"not an integer", `{"tasks": [{"title": "Buy milk", "points": 1.5}]}`, "tasks[0].points"},
+		{"none of the types", `{"tasks": [{"title": "Buy milk", "done": "yes"}]}`, "tasks[0].done"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testTaskSchema.Validate([]byte(tt.data))
+			if tt.path == "-" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() = %v, want a *ValidationError", err)
+			}
+			if validationErr.Path != tt.path {
+				t.Errorf("Validate() path = %q, want %q (%v)", validationErr.Path, tt.path, err)
+			}
+		})
+	}
+}
+
+func TestSchemaValidateBrokenPattern(t *testing.T) {
+	schema := &Schema{Definition: map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"code": map[string]any{"type": "string", "pattern": `(`}},
+	}}
+	err := schema.Validate([]byte(`{"code": "x"}`))
+	var validationErr *ValidationError
+	if err == nil || errors.As(err, &validationErr) {
+		t.Fatalf("Validate() = %v, want an error about the schema", err)
+	}
+}
@@ -0,0 +1,90 @@
+package aiprovider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// maxStreamLine caps a line of a streamed reply; chunks are small, but a server that does not stream
+// may send the whole reply as one line.
+const maxStreamLine = 4 << 20
+
+// Stream receives a reply while it is generated. Servers that answer a streamed request with a single
+// reply have Delta called once with all of it.
+type Stream struct {
+	// Delta is called with each piece of the reply as it arrives. An error stops the reply.
+	Delta func(text string) error
+	// Retry, if set, is called by CompleteJSON when it rejected a reply and asks the model for another
+	// one. The text received so far should be discarded.
+	Retry func(reason error) error
+}
+
+// postStream posts body to url and passes the events of a streamed 200 reply to onEvent as they arrive:
+// the data of Server-Sent Events, or the lines of newline-delimited JSON. Servers that ignore the request
+// to stream send a single JSON reply instead; it is decoded into whole and streamed is false. Errors are
+// parsed as by postJSON.
+func postStream(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body, whole any,
+	parseError func(status int, body []byte) *APIError, onEvent func(data []byte) error) (streamed bool, err error) {
+	resp, err := send(ctx, client, provider, url, headers, body, parseError)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" && mediaType != "application/x-ndjson" {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, fmt.Errorf("connection error: %v", err)
+		}
+		if err := json.Unmarshal(bodyBytes, whole); err != nil {
+			return false, errors.New("failed to parse AI response")
+		}
+		return false, nil
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxStreamLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if mediaType == "text/event-stream" {
+			// only data lines matter: the providers repeat the event name in the data
+			data, ok := bytes.CutPrefix(line, []byte("data:"))
+			if !ok {
+				continue
+			}
+			line = bytes.TrimSpace(data)
+			if string(line) == "[DONE]" {
+				break
+			}
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if err := onEvent(line); err != nil {
+			return true, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		}
+		return true, fmt.Errorf("connection error: %v", err)
+	}
+	return true, nil
+}
+
+// streamWhole hands a reply that was not streamed to the stream as a single piece.
+func streamWhole(stream *Stream, resp *Response) (*Response, error) {
+	if err := stream.Delta(resp.Content); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,110 @@
+package aiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultAttempts is how many replies CompleteJSON asks for, the first included, when the caller does
+// not say.
+const DefaultAttempts = 3
+
+// Roles of the turns of a conversation.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// repairPrompt asks the model to correct a reply that did not match the schema.
+const repairPrompt = `Your reply does not match the required format: %s.
+Reply again with only the corrected JSON, without any other text, matching this JSON Schema:
+%s`
+
+// ErrInvalidOutput matches every *InvalidOutputError with errors.Is.
+var ErrInvalidOutput = errors.New("AI response did not match the expected format")
+
+// InvalidOutputError is returned by CompleteJSON when no reply matched the schema.
+type InvalidOutputError struct {
+	Attempts int
+	// Err is the validation error of the last reply.
+	Err error
+}
+
+func (e *InvalidOutputError) Error() string {
+	return fmt.Sprintf("%v after %d attempts: %v", ErrInvalidOutput, e.Attempts, e.Err)
+}
+
+func (e *InvalidOutputError) Is(target error) bool {
+	return target == ErrInvalidOutput
+}
+
+func (e *InvalidOutputError) Unwrap() error {
+	return e.Err
+}
+
+// CompleteJSON completes req, whose Schema must be set, validates the reply against the schema and
+// decodes it into out. A reply that does not match is sent back to the model together with the
+// validation error, for it to repair, until attempts replies were received; a streamed request is told
+// through Stream.Retry. The returned Response has the last reply and the tokens of all attempts. When
+// no reply matched the error is an *InvalidOutputError.
+func CompleteJSON(ctx context.Context, p Provider, req Request, attempts int, out any) (*Response, error) {
+	if req.Schema == nil {
+		return nil, errors.New("a structured completion needs a schema")
+	}
+	if attempts < 1 {
+		attempts = DefaultAttempts
+	}
+	definition, err := json.Marshal(req.Schema.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &Response{}
+	req.Turns = append([]Turn(nil), req.Turns...)
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		resp, err := p.Complete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		total.Content = resp.Content
+		total.InputTokens += resp.InputTokens
+		total.OutputTokens += resp.OutputTokens
+
+		content := trimCodeFence(resp.Content)
+		if lastErr = req.Schema.Validate([]byte(content)); lastErr == nil {
+			total.Content = content
+			if err := json.Unmarshal([]byte(content), out); err != nil {
+				return nil, err
+			}
+			return total, nil
+		}
+		if attempt == attempts {
+			break
+		}
+		if req.Stream != nil && req.Stream.Retry != nil {
+			if err := req.Stream.Retry(lastErr); err != nil {
+				return nil, err
+			}
+		}
+		req.Turns = append(req.Turns,
+			Turn{Role: RoleAssistant, Content: resp.Content},
+			Turn{Role: RoleUser, Content: fmt.Sprintf(repairPrompt, lastErr, definition)})
+	}
+	return total, &InvalidOutputError{Attempts: attempts, Err: lastErr}
+}
+
+// trimCodeFence removes the markdown code fence models like to wrap JSON replies in.
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		lines := strings.Split(content, "\n")
+		if len(lines) >= 2 {
+			content = strings.Join(lines[1:len(lines)-1], "\n")
+		}
+	}
+	return strings.TrimSpace(content)
+}
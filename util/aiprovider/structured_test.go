@@ -0,0 +1,162 @@
+package aiprovider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// scriptedProvider answers each Complete with the next of its replies and keeps the requests.
+type scriptedProvider struct {
+	replies  []string
+	err      error
+	requests []Request
+}
+
+func (p *scriptedProvider) Complete(_ context.Context, req Request) (*Response, error) {
+	p.requests = append(p.requests, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	reply := p.replies[min(len(p.requests), len(p.replies))-1]
+	return &Response{Content: reply, InputTokens: 10, OutputTokens: 5}, nil
+}
+
+type testTaskList struct {
+	Tasks []struct {
+		Title string `json:"title"`
+	} `json:"tasks"`
+}
+
+func TestCompleteJSON(t *testing.T) {
+	valid := `{"tasks": [{"title": "Buy milk"}]}`
+	tests := []struct {
+		name      string
+		replies   []string
+		attempts  int
+		wantCalls int
+		wantValid bool
+	}{
+		{"first reply valid", []string{valid}, 3, 1, true},
+		{"code fence", []string{"```json\n" + valid + "\n```"}, 3, 1, true},
+		{"repaired", []string{`{"tasks": [{"title": "Milk"}]}`, valid}, 3, 2, true},
+		{"repaired on the last attempt", []string{`not JSON`, `{"tasks": []}`, valid}, 3, 3, true},
+		{"never valid", []string{`{"tasks": [{"title": "Milk"}]}`}, 3, 3, false},
+		{"single attempt", []string{`{}`, valid}, 1, 1, false},
+		{"default attempts", []string{`{}`}, 0, DefaultAttempts, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{replies: tt.replies}
+			var out testTaskList
+			resp, err := CompleteJSON(context.Background(), provider, Request{Prompt: "tasks", Schema: testTaskSchema},
+				tt.attempts, &out)
+
+			if len(provider.requests) != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", len(provider.requests), tt.wantCalls)
+			}
+			if resp == nil {
+				t.Fatalf("CompleteJSON() response = nil, err = %v", err)
+			}
+			if resp.InputTokens != 10*tt.wantCalls || resp.OutputTokens != 5*tt.wantCalls {
+				t.Errorf("tokens = %d/%d, want the sum of %d calls", resp.InputTokens, resp.OutputTokens, tt.wantCalls)
+			}
+			if !tt.wantValid {
+				var invalid *InvalidOutputError
+				if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidOutput) {
+					t.Fatalf("CompleteJSON() error = %v, want an *InvalidOutputError", err)
+				}
+				if invalid.Attempts != tt.wantCalls {
+					t.Errorf("Attempts = %d, want %d", invalid.Attempts, tt.wantCalls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompleteJSON() error = %v", err)
+			}
+			if resp.Content != valid {
+				t.Errorf("Content = %q, want %q", resp.Content, valid)
+			}
+			if len(out.Tasks) != 1 || out.Tasks[0].Title != "Buy milk" {
+				t.Errorf("decoded %+v", out)
+			}
+		})
+	}
+}
+
+func TestCompleteJSONRepairTurns(t *testing.T) {
+	invalid := `{"tasks": [{"title": "Milk"}]}`
+	provider := &scriptedProvider{replies: []string{invalid, `{"tasks": [{"title": "Buy milk"}]}`}}
+	first := Turn{Role: RoleUser, Content: "earlier turn"}
+	req := Request{Prompt: "tasks", Schema: testTaskSchema, Turns: []Turn{first}}
+	var out testTaskList
+	if _, err := CompleteJSON(context.Background(), provider, req, 3, &out); err != nil {
+		t.Fatalf("CompleteJSON() error = %v", err)
+	}
+
+	if len(provider.requests[0].Turns) != 1 {
+		t.Fatalf("first request has %d turns, want only the caller's", len(provider.requests[0].Turns))
+	}
+	turns := provider.requests[1].Turns
+	if len(turns) != 3 || turns[0] != first {
+		t.Fatalf("repair request turns = %+v", turns)
+	}
+	if turns[1].Role != RoleAssistant || turns[1].Content != invalid {
+		t.Errorf("turn 1 = %+v, want the rejected reply", turns[1])
+	}
+	if turns[2].Role != RoleUser || !strings.Contains(turns[2].Content, "tasks[0].title") ||
+		!strings.Contains(turns[2].Content, `"minLength":5`) {
+		t.Errorf("turn 2 = %q, want the validation error and the schema", turns[2].Content)
+	}
+	if len(req.Turns) != 1 {
+		t.Errorf("the caller's turns were changed: %+v", req.Turns)
+	}
+}
+
+func TestCompleteJSONStreamRetry(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{`{}`, `{"tasks": [{"title": "Buy milk"}]}`}}
+	var reasons []error
+	stream := &Stream{
+		Delta: func(string) error { return nil },
+		Retry: func(reason error) error {
+			reasons = append(reasons, reason)
+			return nil
+		},
+	}
+	var out testTaskList
+	if _, err := CompleteJSON(context.Background(), provider, Request{Schema: testTaskSchema, Stream: stream}, 3, &out); err != nil {
+		t.Fatalf("CompleteJSON() error = %v", err)
+	}
+	if len(reasons) != 1 {
+		t.Fatalf("Retry called %d times, want 1", len(reasons))
+	}
+	var validationErr *ValidationError
+	if !errors.As(reasons[0], &validationErr) {
+		t.Errorf("Retry reason = %v, want a *ValidationError", reasons[0])
+	}
+}
+
+func TestCompleteJSONProviderError(t *testing.T) {
+	apiErr := &APIError{Provider: OpenAI, StatusCode: 401, Message: "bad key"}
+	provider := &scriptedProvider{err: apiErr}
+	var out testTaskList
+	_, err := CompleteJSON(context.Background(), provider, Request{Schema: testTaskSchema}, 3, &out)
+	if !errors.Is(err, apiErr) {
+		t.Fatalf("CompleteJSON() error = %v, want the provider's", err)
+	}
+	if len(provider.requests) != 1 {
+		t.Errorf("provider called %d times, want 1", len(provider.requests))
+	}
+}
+
+func TestCompleteJSONNeedsSchema(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{`{}`}}
+	var out testTaskList
+	if _, err := CompleteJSON(context.Background(), provider, Request{}, 3, &out); err == nil {
+		t.Fatal("CompleteJSON() without a schema succeeded")
+	}
+	if len(provider.requests) != 0 {
+		t.Errorf("provider called %d times, want 0", len(provider.requests))
+	}
+}
@@ -0,0 +1,73 @@
+// Package breached checks passwords against a local copy of a breached-password list in the
+// "Pwned Passwords" format (uppercase SHA-1 hashes, optionally followed by ":<count>").
+//
+// The list can be either a directory of k-anonymity range files, one per 5-character hash prefix
+// (e.g. "21BD1" or "21BD1.txt", each line holding the remaining 35 characters), or a single file
+// with one full hash per line. Only the range file matching the password's prefix is read, so the
+// directory layout is the one to use for the full list.
+package breached
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const prefixLength = 5
+
+// Contains reports whether password appears in the list stored at path.
+func Contains(path, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() {
+		return containsInRangeDir(path, hash)
+	}
+	return containsInFile(path, hash, "")
+}
+
+func containsInRangeDir(dir, hash string) (bool, error) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		found, err := containsInFile(filepath.Join(dir, name), suffix, prefix)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return found, err
+	}
+	return false, nil
+}
+
+// containsInFile scans path for target. Lines may carry the full hash even in range files, so the
+// prefix is stripped from them when present.
+func containsInFile(path, target, prefix string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.ToUpper(line)
+		if prefix != "" && len(line) == len(prefix)+len(target) {
+			line = strings.TrimPrefix(line, prefix)
+		}
+		if line == target {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
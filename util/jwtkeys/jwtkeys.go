@@ -0,0 +1,385 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	// TokenTTL is the lifetime of issued tokens. Retired keys stay published for this long
+	// so that tokens signed right before a rotation remain verifiable until they expire.
+	TokenTTL = 72 * time.Hour
+
+	manifestFile = "keyring.json"
+)
+
+var ErrNoSigningKey = errors.New("no JWT signing key configured")
+
+// KeyInfo is the public metadata of a key as stored in the keyring manifest.
+type KeyInfo struct {
+	Kid       string     `json:"kid"`
+	Alg       string     `json:"alg"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RetiredAt *time.Time `json:"retiredAt,omitempty"`
+}
+
+type signingKey struct {
+	KeyInfo
+	private crypto.Signer
+}
+
+// Keyring holds the signing keys stored under dir. The newest non-retired key signs new tokens,
+// every key that is not past its retirement window verifies them.
+type Keyring struct {
+	dir     string
+	mu      sync.RWMutex
+	keys    []*signingKey
+	modTime time.Time
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var defaultKeyring *Keyring
+
+func ValidateAlg(alg string) error {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return fmt.Errorf("unsupported JWT algorithm %q (use %s or %s)", alg, AlgRS256, AlgEdDSA)
+	}
+	return nil
+}
+
+// Init loads the keyring used by Sign, Keyfunc and PublicJWKS and fails when it holds no active key.
+func Init(dir string) error {
+	ring, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	if _, err := ring.active(); err != nil {
+		return fmt.Errorf("%w in %s", err, dir)
+	}
+	defaultKeyring = ring
+	return nil
+}
+
+func Default() *Keyring {
+	return defaultKeyring
+}
+
+func Load(dir string) (*Keyring, error) {
+	ring := &Keyring{dir: dir}
+	if err := ring.reload(); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+func (r *Keyring) Dir() string {
+	return r.dir
+}
+
+// Rotate generates a new active key, retires the previous one and prunes keys whose retirement window has passed.
+func (r *Keyring) Rotate(alg string) (string, error) {
+	if err := ValidateAlg(alg); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := r.reloadIfChanged(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	private, err := generateKey(alg)
+	if err != nil {
+		return "", err
+	}
+	kid, err := newKid()
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(r.dir, kid+".pem"), pemBytes, 0o600); err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	var kept []*signingKey
+	for _, k := range r.keys {
+		if k.RetiredAt == nil {
+			k.RetiredAt = &now
+		}
+		if k.RetiredAt.Add(TokenTTL).Before(now) {
+			_ = os.Remove(filepath.Join(r.dir, k.Kid+".pem"))
+			continue
+		}
+		kept = append(kept, k)
+	}
+	kept = append(kept, &signingKey{
+		KeyInfo: KeyInfo{Kid: kid, Alg: alg, CreatedAt: now},
+		private: private,
+	})
+	r.keys = kept
+
+	if err := r.writeManifest(); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+func (r *Keyring) Keys() []KeyInfo {
+	_ = r.reloadIfChanged()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	metas := make([]KeyInfo, 0, len(r.keys))
+	for _, k := range r.keys {
+		metas = append(metas, k.KeyInfo)
+	}
+	return metas
+}
+
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	key, err := r.active()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(signingMethod(key.Alg), claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the verification key from the token's kid header.
+func (r *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+	_ = r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.verifiable() {
+		if k.Kid != kid {
+			continue
+		}
+		if t.Method.Alg() != k.Alg {
+			return nil, errors.New("token algorithm does not match key")
+		}
+		return k.private.Public(), nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+func (r *Keyring) PublicJWKS() JWKSet {
+	_ = r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set := JWKSet{Keys: make([]JWK, 0)}
+	for _, k := range r.verifiable() {
+		jwk := JWK{Kid: k.Kid, Use: "sig", Alg: k.Alg}
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (r *Keyring) active() (*signingKey, error) {
+	_ = r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var newest *signingKey
+	for _, k := range r.keys {
+		if k.RetiredAt != nil {
+			continue
+		}
+		if newest == nil || k.CreatedAt.After(newest.CreatedAt) {
+			newest = k
+		}
+	}
+	if newest == nil {
+		return nil, ErrNoSigningKey
+	}
+	return newest, nil
+}
+
+// verifiable must be called with r.mu held.
+func (r *Keyring) verifiable() []*signingKey {
+	now := time.Now()
+	var keys []*signingKey
+	for _, k := range r.keys {
+		if k.RetiredAt != nil && k.RetiredAt.Add(TokenTTL).Before(now) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// reloadIfChanged picks up rotations made by another process (e.g. the CLI) while the server is running.
+func (r *Keyring) reloadIfChanged() error {
+	info, err := os.Stat(filepath.Join(r.dir, manifestFile))
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	unchanged := info.ModTime().Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	return r.reload()
+}
+
+func (r *Keyring) reload() error {
+	path := filepath.Join(r.dir, manifestFile)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var metas []KeyInfo
+	if err := json.Unmarshal(raw, &metas); err != nil {
+		return fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	var keys []*signingKey
+	for _, meta := range metas {
+		private, err := readPrivateKey(filepath.Join(r.dir, meta.Kid+".pem"))
+		if err != nil {
+			return fmt.Errorf("cannot load JWT key %s: %w", meta.Kid, err)
+		}
+		keys = append(keys, &signingKey{KeyInfo: meta, private: private})
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+	return nil
+}
+
+// writeManifest must be called with r.mu held for writing.
+func (r *Keyring) writeManifest() error {
+	metas := make([]KeyInfo, 0, len(r.keys))
+	for _, k := range r.keys {
+		metas = append(metas, k.KeyInfo)
+	}
+	raw, err := json.MarshalIndent(metas, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, manifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported key type")
+	}
+	return signer, nil
+}
+
+func generateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case AlgRS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	}
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	if alg == AlgRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+func newKid() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(b), nil
+}
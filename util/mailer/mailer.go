@@ -35,12 +35,14 @@ func (m *Mailer) Send(to, subject, text string) error {
 	return d.DialAndSend(msg)
 }
 
-func (m *Mailer) SendWithTemplate(to, subject, textTemplate string) error {
+// SendMessage sends a rendered Message as plain text with an HTML alternative.
+func (m *Mailer) SendMessage(to string, message *Message) error {
 	msg := mail.NewMessage()
 	msg.SetHeader("From", m.from)
 	msg.SetHeader("To", to)
-	msg.SetHeader("Subject", subject)
-	msg.AddAlternative("text/html", textTemplate)
+	msg.SetHeader("Subject", message.Subject)
+	msg.SetBody("text/plain", message.Text)
+	msg.AddAlternative("text/html", message.HTML)
 
 	d := mail.NewDialer(m.host, m.port, m.user, m.pass)
 	return d.DialAndSend(msg)
@@ -0,0 +1,148 @@
+package mailer
+
+import (
+	embedfs "SimpleToDo"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Message types in the localized template catalog.
+const (
+	VerifyEmail       = "verify_email"
+	ResetPassword     = "reset_password"
+	ProjectSummary    = "project_summary"
+	MagicLink         = "magic_link"
+	ChangeEmail       = "change_email"
+	EmailChangeNotice = "email_change_notice"
+	PasswordChanged   = "password_changed"
+	AccountDeletion   = "account_deletion"
+	Invitation        = "invitation"
+)
+
+// DefaultLocale is used when neither the recipient's locale nor its base language has templates.
+const DefaultLocale = "en"
+
+// catalogDir holds one directory per locale with a subjects.json and, for every message type listed
+// in it, a <type>.html and a <type>.txt template.
+const catalogDir = "config/static/templates/i18n"
+
+// Message is a rendered email, ready to send with SendMessage.
+type Message struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type catalogEntry struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+var (
+	catalogOnce sync.Once
+	catalog     map[string]map[string]*catalogEntry
+	catalogErr  error
+)
+
+// Render renders the message type in the best locale available for locale: the exact tag
+// ("pt-BR"), then its base language ("pt"), then DefaultLocale.
+func Render(messageType, locale string, data any) (*Message, error) {
+	catalogOnce.Do(func() {
+		catalog, catalogErr = loadCatalog(embedfs.TemplatesFS, catalogDir)
+	})
+	if catalogErr != nil {
+		return nil, catalogErr
+	}
+
+	var entry *catalogEntry
+	for _, candidate := range localeCandidates(locale) {
+		if entry = catalog[candidate][messageType]; entry != nil {
+			break
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("no email template for %q", messageType)
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := entry.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := entry.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	if err := entry.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	return &Message{Subject: subject.String(), HTML: html.String(), Text: text.String()}, nil
+}
+
+func localeCandidates(locale string) []string {
+	locale = normalizeLocale(locale)
+	var candidates []string
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if base, _, found := strings.Cut(locale, "-"); found {
+			candidates = append(candidates, base)
+		}
+	}
+	return append(candidates, DefaultLocale)
+}
+
+// normalizeLocale lowercases a BCP 47 tag so that "pt_BR", "pt-BR" and "pt-br" find the same directory.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+func loadCatalog(fsys fs.FS, dir string) (map[string]map[string]*catalogEntry, error) {
+	localeDirs, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]*catalogEntry)
+	for _, localeDir := range localeDirs {
+		if !localeDir.IsDir() {
+			continue
+		}
+		locale := normalizeLocale(localeDir.Name())
+		base := path.Join(dir, localeDir.Name())
+
+		raw, err := fs.ReadFile(fsys, path.Join(base, "subjects.json"))
+		if err != nil {
+			return nil, err
+		}
+		var subjects map[string]string
+		if err := json.Unmarshal(raw, &subjects); err != nil {
+			return nil, fmt.Errorf("%s/subjects.json: %w", base, err)
+		}
+
+		entries := make(map[string]*catalogEntry, len(subjects))
+		for messageType, subject := range subjects {
+			entry := &catalogEntry{}
+			if entry.subject, err = texttemplate.New(messageType).Parse(subject); err != nil {
+				return nil, fmt.Errorf("%s/subjects.json: %w", base, err)
+			}
+			if entry.html, err = htmltemplate.ParseFS(fsys, path.Join(base, messageType+".html")); err != nil {
+				return nil, err
+			}
+			if entry.text, err = texttemplate.ParseFS(fsys, path.Join(base, messageType+".txt")); err != nil {
+				return nil, err
+			}
+			entries[messageType] = entry
+		}
+		result[locale] = entries
+	}
+	if _, ok := result[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("email templates for the default locale %q are missing", DefaultLocale)
+	}
+	return result, nil
+}
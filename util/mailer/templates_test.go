@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	embedfs "SimpleToDo"
+	"strings"
+	"testing"
+)
+
+var messageTypes = []string{VerifyEmail, ResetPassword, ProjectSummary, MagicLink, ChangeEmail, EmailChangeNotice,
+	PasswordChanged, AccountDeletion, Invitation}
+
+// testData fills every field any template uses.
+var testData = map[string]string{
+	"Username":     "Ada",
+	"VerifyURL":    "https://todo.example.com/verify",
+	"ResetURL":     "https://todo.example.com/reset",
+	"LoginURL":     "https://todo.example.com/login",
+	"ConfirmURL":   "https://todo.example.com/confirm",
+	"InviteURL":    "https://todo.example.com/invite",
+	"NewEmail":     "ada@example.com",
+	"ChangedAt":    "2025-01-31 10:00",
+	"DeletionDate": "2025-02-14 10:00",
+	"InviterName":  "Grace Hopper",
+	"ProjectName":  "Compiler",
+	"Project":      "Compiler",
+	"GeneratedAt":  "2025-01-31 10:00",
+	"Summary":      "On track.",
+	"Year":         "2025",
+}
+
+func TestCatalogCoversEveryLocale(t *testing.T) {
+	catalog, err := loadCatalog(embedfs.TemplatesFS, catalogDir)
+	if err != nil {
+		t.Fatalf("loadCatalog() error = %v", err)
+	}
+	for locale, entries := range catalog {
+		for _, messageType := range messageTypes {
+			if entries[messageType] == nil {
+				t.Errorf("locale %s has no %s template", locale, messageType)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, locale := range []string{"en", "es", "es-MX", "pt-BR", ""} {
+		for _, messageType := range messageTypes {
+			msg, err := Render(messageType, locale, testData)
+			if err != nil {
+				t.Errorf("Render(%s, %q) error = %v", messageType, locale, err)
+				continue
+			}
+			if msg.Subject == "" || msg.HTML == "" || msg.Text == "" {
+				t.Errorf("Render(%s, %q) has an empty part: %+v", messageType, locale, msg)
+			}
+			for part, content := range map[string]string{"subject": msg.Subject, "html": msg.HTML, "text": msg.Text} {
+				if strings.Contains(content, "<no value>") {
+					t.Errorf("Render(%s, %q) %s uses a field the data lacks", messageType, locale, part)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderFallsBackToBaseLanguage(t *testing.T) {
+	msg, err := Render(MagicLink, "es_MX", testData)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.HasPrefix(msg.Text, "Hola") {
+		t.Errorf("Text = %q, want the Spanish template", msg.Text)
+	}
+}
+
+func TestRenderUnknownType(t *testing.T) {
+	if _, err := Render("newsletter", "en", testData); err == nil {
+		t.Fatal("Render() of an unknown type succeeded")
+	}
+}
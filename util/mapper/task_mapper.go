@@ -17,6 +17,12 @@ func NewTaskMapperImpl() *TaskMapperImpl {
 
 func (t *TaskMapperImpl) ToDto(taskEntity *models.Task) response.TaskResponseDto {
 
+	var dueDate *string
+	if taskEntity.DueDate != nil {
+		formatted := taskEntity.DueDate.Format("2006-01-02")
+		dueDate = &formatted
+	}
+
 	return response.TaskResponseDto{
 		Id:          int(taskEntity.ID),
 		Title:       taskEntity.Title,
@@ -25,6 +31,8 @@ func (t *TaskMapperImpl) ToDto(taskEntity *models.Task) response.TaskResponseDto
 		StatusId:    int(taskEntity.StatusId),
 		UserId:      int(taskEntity.UserId),
 		ProjectId:   int(taskEntity.ProjectId),
+		DueDate:     dueDate,
+		Priority:    taskEntity.Priority,
 		CreatedAt:   taskEntity.CreatedAt,
 		UpdatedAt:   taskEntity.UpdatedAt,
 	}
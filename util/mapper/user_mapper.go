@@ -3,6 +3,7 @@ package mapper
 import (
 	"SimpleToDo/dto/response"
 	"SimpleToDo/models"
+	"fmt"
 )
 
 type UserMapperImpl struct {
@@ -26,8 +27,21 @@ func (t *UserMapperImpl) ToDto(userEntity *models.User) response.UserResponseDto
 		Phone:     userEntity.Phone,
 		Username:  userEntity.Username,
 		BirthDate: userEntity.BirthDate,
-		Image:     userEntity.Image,
+		AvatarUrl: AvatarURL(userEntity.ID, userEntity.AvatarVersion),
 		Address:   userEntity.Address,
 		Role:      userEntity.Role.Name,
+		Verified:  userEntity.Verified,
+		Suspended: userEntity.Suspended,
+
+		DeletionScheduledAt: userEntity.DeletionScheduledAt,
+	}
+}
+
+// AvatarURL is where the user's avatar is served, or "" when there is none. The version changes with
+// the image, so the response can be cached indefinitely.
+func AvatarURL(userID uint, version string) string {
+	if version == "" {
+		return ""
 	}
+	return fmt.Sprintf("/api/v1/avatars/%d?v=%s", userID, version)
 }
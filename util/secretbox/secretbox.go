@@ -0,0 +1,413 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sealedPrefix marks values produced by Seal; anything else is legacy plaintext.
+	sealedPrefix  = "sb1:"
+	masterKeySize = 32
+	manifestFile  = "keyring.json"
+	envKeyPrefix  = "env-"
+	aeadKeyInfo   = "SimpleToDo secretbox AES-256-GCM"
+)
+
+var (
+	ErrNoMasterKey  = errors.New("no master key configured")
+	ErrUnknownKey   = errors.New("value was sealed with an unknown master key")
+	ErrEnvMasterKey = errors.New("the master key is set through SECRETS_MASTER_KEY; change it there and list the old one in SECRETS_PREVIOUS_MASTER_KEYS")
+)
+
+// KeyInfo is the public metadata of a master key.
+type KeyInfo struct {
+	Kid       string    `json:"kid"`
+	CreatedAt time.Time `json:"createdAt"`
+	// FromEnv is set for keys given through the environment rather than stored in the keyring.
+	FromEnv bool `json:"-"`
+}
+
+type storedKey struct {
+	KeyInfo
+	Secret string `json:"secret"`
+}
+
+type masterKey struct {
+	KeyInfo
+	secret string
+	aead   cipher.AEAD
+}
+
+// Keyring holds the master keys that protect secrets stored in the database. The newest key seals
+// new values, every key opens them. A key given in the environment takes precedence over the ones
+// stored under dir, which are then only used for opening.
+type Keyring struct {
+	dir     string
+	env     []*masterKey
+	mu      sync.RWMutex
+	stored  []*masterKey
+	modTime time.Time
+}
+
+var defaultKeyring *Keyring
+
+// Init loads the keyring used by Default, generating a master key under dir when there is none.
+// envKey and previousEnvKeys are base64 encoded 32 byte keys, usually from SECRETS_MASTER_KEY and
+// SECRETS_PREVIOUS_MASTER_KEYS.
+func Init(dir, envKey string, previousEnvKeys []string) error {
+	ring, err := Load(dir, envKey, previousEnvKeys)
+	if err != nil {
+		return err
+	}
+	if _, err := ring.active(); errors.Is(err, ErrNoMasterKey) {
+		if _, err := ring.Rotate(); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	defaultKeyring = ring
+	return nil
+}
+
+func Default() *Keyring {
+	return defaultKeyring
+}
+
+func Load(dir, envKey string, previousEnvKeys []string) (*Keyring, error) {
+	ring := &Keyring{dir: dir}
+	for i, encoded := range append([]string{envKey}, previousEnvKeys...) {
+		encoded = strings.TrimSpace(encoded)
+		if encoded == "" {
+			if i == 0 && len(previousEnvKeys) > 0 {
+				return nil, errors.New("SECRETS_PREVIOUS_MASTER_KEYS requires SECRETS_MASTER_KEY")
+			}
+			continue
+		}
+		key, err := envMasterKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		ring.env = append(ring.env, key)
+	}
+	if err := ring.reload(); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+func (r *Keyring) Dir() string {
+	return r.dir
+}
+
+// GenerateKey returns a new random master key in the format expected by SECRETS_MASTER_KEY.
+func GenerateKey() (string, error) {
+	secret := make([]byte, masterKeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// Rotate stores a new master key in the keyring and makes it the active one. Older keys are kept so
+// existing values can still be opened until they are re-sealed and Prune is called.
+func (r *Keyring) Rotate() (string, error) {
+	if len(r.env) > 0 {
+		return "", ErrEnvMasterKey
+	}
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := r.reloadIfChanged(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	encoded, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	kid, err := newKid()
+	if err != nil {
+		return "", err
+	}
+	key, err := newMasterKey(KeyInfo{Kid: kid, CreatedAt: time.Now().UTC()}, encoded)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := append(r.stored, key)
+	if err := r.writeManifest(stored); err != nil {
+		return "", err
+	}
+	r.stored = stored
+	return kid, nil
+}
+
+// Prune removes every stored key but the active one. Call it only once all values were re-sealed.
+func (r *Keyring) Prune() (int, error) {
+	active, err := r.active()
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kept []*masterKey
+	for _, k := range r.stored {
+		if k == active {
+			kept = append(kept, k)
+		}
+	}
+	removed := len(r.stored) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := r.writeManifest(kept); err != nil {
+		return 0, err
+	}
+	r.stored = kept
+	return removed, nil
+}
+
+func (r *Keyring) Keys() []KeyInfo {
+	_ = r.reloadIfChanged()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var metas []KeyInfo
+	for _, k := range r.all() {
+		metas = append(metas, k.KeyInfo)
+	}
+	return metas
+}
+
+// ActiveKid returns the id of the key Seal currently uses.
+func (r *Keyring) ActiveKid() (string, error) {
+	key, err := r.active()
+	if err != nil {
+		return "", err
+	}
+	return key.Kid, nil
+}
+
+// Seal encrypts plaintext with the active key. context is authenticated but not stored, so a value
+// only opens with the same context; use it to bind the value to the row it belongs to.
+func (r *Keyring) Seal(plaintext, context string) (string, error) {
+	key, err := r.active()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return sealedPrefix + key.Kid + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal with the same context.
+func (r *Keyring) Open(value, context string) (string, error) {
+	kid, payload, ok := parseSealed(value)
+	if !ok {
+		return "", errors.New("value is not sealed")
+	}
+	key, err := r.find(kid)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < key.aead.NonceSize() {
+		return "", errors.New("malformed sealed value")
+	}
+	nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	plaintext, err := key.aead.Open(nil, nonce, ciphertext, []byte(context))
+	if err != nil {
+		return "", fmt.Errorf("cannot open value sealed with key %s: %w", kid, err)
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed reports whether value was produced by Seal, as opposed to plaintext stored before
+// encryption was introduced.
+func IsSealed(value string) bool {
+	_, _, ok := parseSealed(value)
+	return ok
+}
+
+// SealedWith returns the id of the key value was sealed with, or "" for plaintext.
+func SealedWith(value string) string {
+	kid, _, _ := parseSealed(value)
+	return kid
+}
+
+func parseSealed(value string) (kid, payload string, ok bool) {
+	rest, found := strings.CutPrefix(value, sealedPrefix)
+	if !found {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+func (r *Keyring) active() (*masterKey, error) {
+	if len(r.env) > 0 {
+		return r.env[0], nil
+	}
+	_ = r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.stored) == 0 {
+		return nil, ErrNoMasterKey
+	}
+	return r.stored[len(r.stored)-1], nil
+}
+
+func (r *Keyring) find(kid string) (*masterKey, error) {
+	for _, k := range r.env {
+		if k.Kid == kid {
+			return k, nil
+		}
+	}
+	_ = r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.stored {
+		if k.Kid == kid {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %s", ErrUnknownKey, kid)
+}
+
+// all must be called with r.mu held.
+func (r *Keyring) all() []*masterKey {
+	return append(append([]*masterKey{}, r.env...), r.stored...)
+}
+
+// reloadIfChanged picks up rotations made by another process (e.g. the CLI) while the server is running.
+func (r *Keyring) reloadIfChanged() error {
+	info, err := os.Stat(filepath.Join(r.dir, manifestFile))
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	unchanged := info.ModTime().Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	return r.reload()
+}
+
+func (r *Keyring) reload() error {
+	path := filepath.Join(r.dir, manifestFile)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var stored []storedKey
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	var keys []*masterKey
+	for _, k := range stored {
+		key, err := newMasterKey(k.KeyInfo, k.Secret)
+		if err != nil {
+			return fmt.Errorf("cannot load master key %s: %w", k.Kid, err)
+		}
+		keys = append(keys, key)
+	}
+
+	r.mu.Lock()
+	r.stored = keys
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+	return nil
+}
+
+// writeManifest must be called with r.mu held for writing.
+func (r *Keyring) writeManifest(keys []*masterKey) error {
+	stored := make([]storedKey, len(keys))
+	for i, k := range keys {
+		stored[i] = storedKey{KeyInfo: k.KeyInfo, Secret: k.secret}
+	}
+	raw, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, manifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return nil
+}
+
+func envMasterKey(encoded string) (*masterKey, error) {
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(secret) != masterKeySize {
+		return nil, fmt.Errorf("invalid master key: must be %d bytes, base64 encoded", masterKeySize)
+	}
+	// the id must be stable across restarts without being stored, so it is derived from the key
+	digest := sha256.Sum256(append([]byte("kid:"), secret...))
+	info := KeyInfo{Kid: envKeyPrefix + hex.EncodeToString(digest[:8]), FromEnv: true}
+	return newMasterKey(info, encoded)
+}
+
+func newMasterKey(info KeyInfo, encoded string) (*masterKey, error) {
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(secret) != masterKeySize {
+		return nil, fmt.Errorf("invalid master key: must be %d bytes, base64 encoded", masterKeySize)
+	}
+	derived, err := hkdf.Key(sha256.New, secret, nil, aeadKeyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &masterKey{KeyInfo: info, secret: encoded, aead: aead}, nil
+}
+
+func newKid() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(b), nil
+}